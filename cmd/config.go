@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"syl-md2ppt/internal/app"
+	"syl-md2ppt/internal/config"
+)
+
+func newConfigCmd(stdout, stderr io.Writer, flags *buildFlags) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:           "config",
+		Short:         "查看、检查配置",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	var show app.ConfigOptions
+	showCmd := &cobra.Command{
+		Use:           "show [卡片文件]",
+		Short:         "显示实际生效的配置（各层合并、补上默认值之后）和它的来源",
+		Long:          "显示实际生效的配置（各层合并、补上默认值之后）和它的来源。\n给了卡片文件时叠加卡片所在目录的目录配置；--explain 列出每个配置项由哪一层设置。",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := show
+			opts.ConfigPath = flags.configArg
+			if len(args) == 1 {
+				opts.Card = args[0]
+			}
+			view, err := app.ShowConfig(opts)
+			if err != nil {
+				return err
+			}
+			for _, w := range view.Warnings {
+				fmt.Fprintln(stderr, w)
+			}
+			fmt.Fprintf(stdout, "# 来源：%s\n", view.Source)
+			layers := make([]string, len(view.Layers))
+			for i, l := range view.Layers {
+				layers[i] = l.String()
+			}
+			fmt.Fprintf(stdout, "# 合并顺序：%s\n", strings.Join(layers, " → "))
+			if opts.Explain {
+				printExplain(stdout, view.Explain)
+				return nil
+			}
+			enc := yaml.NewEncoder(stdout)
+			enc.SetIndent(2)
+			if err := enc.Encode(view.Config); err != nil {
+				return err
+			}
+			return enc.Close()
+		},
+	}
+	showCmd.Flags().BoolVar(&show.Explain, "explain", false, "列出每个配置项的取值和设置它的层")
+	showCmd.Flags().StringVar(&show.TemplatePath, "template", "", "和 build 的 --template 一样，作为命令行这一层")
+	configCmd.AddCommand(showCmd)
+	configCmd.AddCommand(&cobra.Command{
+		Use:           "validate [file]",
+		Short:         "严格检查配置文件：不认识的配置项、类型不对或超出范围的值都带行号列出",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("读取当前目录失败：%w", err)
+			}
+			pathArg := flags.configArg
+			if len(args) == 1 {
+				pathArg = args[0]
+			}
+			source, err := config.Locate(pathArg, cwd)
+			if err != nil {
+				return err
+			}
+			if source == config.EmbeddedSource {
+				fmt.Fprintln(stdout, "没有找到配置文件，用的是内置默认配置")
+				return nil
+			}
+			raw, err := os.ReadFile(source)
+			if err != nil {
+				return fmt.Errorf("读取配置文件失败（%s）：%w", source, err)
+			}
+			problems := config.Validate(raw)
+			if len(problems) == 0 {
+				fmt.Fprintf(stdout, "配置没问题：%s\n", source)
+				return nil
+			}
+			fmt.Fprintf(stderr, "配置文件有 %d 处问题（%s）：\n", len(problems), source)
+			for _, p := range problems {
+				fmt.Fprintf(stderr, "  %s\n", p)
+			}
+			return errAlreadyPrinted
+		},
+	})
+	configCmd.AddCommand(&cobra.Command{
+		Use:           "schema",
+		Short:         "输出配置文件的 JSON Schema，供编辑器补全和检查",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := config.Schema()
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(stdout, "%s\n", schema)
+			return err
+		},
+	})
+	return configCmd
+}
+
+// printExplain 每行一个配置项，来源对齐写在后面。
+func printExplain(w io.Writer, items []config.Explanation) {
+	width := 0
+	for _, e := range items {
+		width = max(width, runewidth(e.Key+": "+e.Value))
+	}
+	for _, e := range items {
+		line := e.Key + ": " + e.Value
+		origin := e.Origin.String()
+		for _, o := range e.Also {
+			origin += "；同 " + o.String()
+		}
+		fmt.Fprintf(w, "%s%s  # %s\n", line, strings.Repeat(" ", width-runewidth(line)), origin)
+	}
+}
+
+// runewidth 按等宽终端估算显示宽度，中日韩字符算两格。
+func runewidth(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x1100 && (r <= 0x115F || r >= 0x2E80 && r <= 0xA4CF || r >= 0xAC00 && r <= 0xD7A3 || r >= 0xF900 && r <= 0xFAFF || r >= 0xFE30 && r <= 0xFE4F || r >= 0xFF00 && r <= 0xFF60 || r >= 0xFFE0 && r <= 0xFFE6) {
+			n += 2
+			continue
+		}
+		n++
+	}
+	return n
+}
@@ -5,11 +5,30 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"syl-md2ppt/internal/diag"
 )
 
 var unknownCmdPattern = regexp.MustCompile(`unknown command "([^"]+)" for "([^"]+)"`)
 var errAlreadyPrinted = errors.New("错误信息已输出")
 
+// 退出码：严格模式下因告警失败时用 ExitStrict，和其他失败（ExitFailure）区分开。
+const (
+	ExitFailure = 1
+	ExitStrict  = 2
+)
+
+// ExitCode 返回 err 对应的进程退出码。
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, diag.ErrStrict):
+		return ExitStrict
+	}
+	return ExitFailure
+}
+
 func FriendlyError(err error) string {
 	if err == nil {
 		return ""
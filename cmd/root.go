@@ -1,20 +1,37 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"syl-md2ppt/internal/app"
+	"syl-md2ppt/internal/diag"
+	"syl-md2ppt/internal/discovery"
 )
 
 type buildFlags struct {
-	outputArg string
-	configArg string
+	outputArg  string
+	configArg  string
+	lang       string
+	splitLangs bool
+	template   string
+	format     string
+	strict     bool
+	watch      bool
+	noCache    bool
+	jobs       int
+	reproduce  bool
+	overwrite  string
+	backup     bool
 }
 
 const dataSourceRequirementsHelp = `
@@ -38,7 +55,9 @@ SPI/
 func Execute() error {
 	root := NewRootCmd(time.Now, rand.Reader, os.Stdout, os.Stderr)
 	root.SetArgs(normalizeArgs(os.Args[1:]))
-	return root.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return root.ExecuteContext(ctx)
 }
 
 func NewRootCmd(nowFn func() time.Time, randSrc io.Reader, stdout io.Writer, stderr io.Writer) *cobra.Command {
@@ -57,6 +76,7 @@ func NewRootCmd(nowFn func() time.Time, randSrc io.Reader, stdout io.Writer, std
 	root.SetErr(stderr)
 	root.CompletionOptions.HiddenDefaultCmd = true
 	bindBuildFlags(root, flags)
+	bindDeckFlags(root, flags)
 	root.PersistentFlags().BoolVarP(&showVersion, "version", "v", false, "显示版本信息")
 
 	buildCmd := &cobra.Command{
@@ -67,6 +87,7 @@ func NewRootCmd(nowFn func() time.Time, randSrc io.Reader, stdout io.Writer, std
 		SilenceErrors: true,
 		RunE:          runBuild(nowFn, randSrc, stdout, stderr, flags, true, &showVersion),
 	}
+	bindDeckFlags(buildCmd, flags)
 	root.AddCommand(buildCmd)
 
 	checkCmd := &cobra.Command{
@@ -89,12 +110,29 @@ func NewRootCmd(nowFn func() time.Time, randSrc io.Reader, stdout io.Writer, std
 		},
 	}
 	root.AddCommand(versionCmd)
+	root.AddCommand(newCacheCmd(stdout))
+	root.AddCommand(newInitCmd(stdout))
+	root.AddCommand(newConfigCmd(stdout, stderr, flags))
 	return root
 }
 
 func bindBuildFlags(cmd *cobra.Command, flags *buildFlags) {
 	cmd.PersistentFlags().StringVar(&flags.outputArg, "output", "", "输出文件路径或输出目录")
 	cmd.PersistentFlags().StringVar(&flags.configArg, "config", "", "YAML 配置文件路径")
+	cmd.PersistentFlags().StringVar(&flags.format, "format", app.FormatText, "输出格式：text、json 或 yaml")
+	cmd.PersistentFlags().BoolVar(&flags.strict, "strict", false, "严格模式：告警按错误处理（范围见配置 strict.codes），退出码为 2")
+}
+
+func bindDeckFlags(cmd *cobra.Command, flags *buildFlags) {
+	cmd.Flags().StringVar(&flags.lang, "lang", "", "只输出一种语言（如 EN），整页单栏排版")
+	cmd.Flags().BoolVar(&flags.splitLangs, "split-langs", false, "每种语言各输出一个 pptx")
+	cmd.Flags().StringVar(&flags.template, "template", "", "作为母版的 .pptx 文件（覆盖配置里的 template.path）")
+	cmd.Flags().BoolVar(&flags.watch, "watch", false, "监视数据源和配置文件，改动后自动重新生成（Ctrl+C 退出）")
+	cmd.Flags().BoolVar(&flags.noCache, "no-cache", false, "不使用排版缓存，所有卡片重新排版")
+	cmd.Flags().IntVar(&flags.jobs, "jobs", 0, "同时排版的卡片数，0 表示按 CPU 核数")
+	cmd.Flags().BoolVar(&flags.reproduce, "reproducible", false, "可复现模式：同样的输入生成字节完全相同的文件（时间取 SOURCE_DATE_EPOCH）")
+	cmd.Flags().StringVar(&flags.overwrite, "overwrite", app.OverwriteAlways, "输出文件已存在时：always 覆盖，never 换个带编号的文件名，ask 先询问")
+	cmd.Flags().BoolVar(&flags.backup, "backup", false, "覆盖前把原来的文件保留为 <文件名>.bak")
 }
 
 func runBuild(nowFn func() time.Time, randSrc io.Reader, stdout io.Writer, stderr io.Writer, flags *buildFlags, subcommand bool, showVersion *bool) func(*cobra.Command, []string) error {
@@ -119,20 +157,61 @@ func runBuild(nowFn func() time.Time, randSrc io.Reader, stdout io.Writer, stder
 		if len(args) > 1 {
 			return fmt.Errorf("参数有点多了，只需要一个数据源目录")
 		}
+		format, err := app.ParseFormat(flags.format)
+		if err != nil {
+			return err
+		}
+		if flags.jobs < 0 {
+			return fmt.Errorf("--jobs 不能是负数，收到的是 %d", flags.jobs)
+		}
+		overwrite, err := app.ParseOverwrite(flags.overwrite)
+		if err != nil {
+			return err
+		}
+		toStdout := flags.outputArg == "-"
+		if toStdout && format != app.FormatText {
+			return fmt.Errorf("--output - 时标准输出留给 PPT，不能再用 --format %s", format)
+		}
 
 		cwd, err := os.Getwd()
 		if err != nil {
 			return fmt.Errorf("读取当前目录失败：%w", err)
 		}
 
-		res, err := app.Run(app.Options{
-			SourceDir:  args[0],
-			OutputArg:  flags.outputArg,
-			ConfigPath: flags.configArg,
-			CWD:        cwd,
-			Now:        nowFn(),
-			Rand:       randSrc,
-		})
+		opts := app.Options{
+			SourceDir:    args[0],
+			OutputArg:    flags.outputArg,
+			ConfigPath:   flags.configArg,
+			CWD:          cwd,
+			Now:          nowFn(),
+			Rand:         randSrc,
+			Lang:         flags.lang,
+			SplitLangs:   flags.splitLangs,
+			TemplatePath: flags.template,
+			Strict:       flags.strict,
+			NoCache:      flags.noCache,
+			Jobs:         flags.jobs,
+			Stdout:       stdout,
+			Reproducible: flags.reproduce,
+			Overwrite:    overwrite,
+			Confirm:      confirmOverwrite(cmd.InOrStdin(), stderr),
+			Backup:       flags.backup,
+		}
+		if flags.watch {
+			if format != app.FormatText {
+				return fmt.Errorf("--watch 只支持文本输出，不能和 --format %s 一起用", format)
+			}
+			return watchBuild(cmd.Context(), opts, stdout, stderr)
+		}
+		res, err := app.Run(opts)
+		if format != app.FormatText {
+			return writeReport(stdout, format, app.Report{Command: "build", Build: &res}, err)
+		}
+		if errors.Is(err, diag.ErrStrict) {
+			for _, w := range res.Warnings {
+				fmt.Fprintln(stderr, w)
+			}
+		}
 		if err != nil {
 			return err
 		}
@@ -140,7 +219,21 @@ func runBuild(nowFn func() time.Time, randSrc io.Reader, stdout io.Writer, stder
 		for _, w := range res.Warnings {
 			fmt.Fprintln(stderr, w)
 		}
-		fmt.Fprintf(stdout, "搞定啦，PPT 已生成：%s\n", res.OutputPath)
+		// PPT 写到标准输出时，提示信息改到标准错误。
+		info := stdout
+		if toStdout {
+			info = stderr
+		}
+		for _, out := range res.Outputs {
+			if out.Path == "-" {
+				fmt.Fprintln(info, "搞定啦，PPT 已写到标准输出")
+				continue
+			}
+			fmt.Fprintf(info, "搞定啦，PPT 已生成：%s\n", out.Path)
+		}
+		if res.Cache != nil {
+			fmt.Fprintf(info, "排版缓存：命中 %d 张卡片，重新排版 %d 张\n", res.Cache.Hits, res.Cache.Misses)
+		}
 		_ = subcommand
 		return nil
 	}
@@ -159,6 +252,10 @@ func runCheck(stdout io.Writer, stderr io.Writer, flags *buildFlags, showVersion
 		if len(args) > 1 {
 			return fmt.Errorf("参数有点多了，只需要一个数据源目录")
 		}
+		format, err := app.ParseFormat(flags.format)
+		if err != nil {
+			return err
+		}
 
 		cwd, err := os.Getwd()
 		if err != nil {
@@ -168,7 +265,16 @@ func runCheck(stdout io.Writer, stderr io.Writer, flags *buildFlags, showVersion
 			SourceDir:  args[0],
 			ConfigPath: flags.configArg,
 			CWD:        cwd,
+			Strict:     flags.strict,
 		})
+		if format != app.FormatText {
+			return writeReport(stdout, format, app.Report{Command: "check", Check: &res}, err)
+		}
+		if errors.Is(err, diag.ErrStrict) {
+			for _, w := range res.Warnings {
+				fmt.Fprintln(stderr, w)
+			}
+		}
 		if err != nil {
 			return err
 		}
@@ -176,25 +282,138 @@ func runCheck(stdout io.Writer, stderr io.Writer, flags *buildFlags, showVersion
 			fmt.Fprintln(stderr, w)
 		}
 		for _, it := range res.Items {
-			fmt.Fprintf(stdout, "[%03d] - %s\n", it.No, it.ENPath)
-			fmt.Fprintf(stdout, "[%03d] - %s\n", it.No, it.CNPath)
+			for _, path := range it.Paths {
+				fmt.Fprintf(stdout, "[%03d] - %s\n", it.No, path)
+			}
+		}
+		unit := "对双语文件"
+		if len(res.Languages) != 2 {
+			unit = "组多语言文件"
 		}
 		if res.HasConflict {
-			fmt.Fprintf(stdout, "检查完成：共识别 %d 对双语文件，可生成 %d 页 PPT；发现 %d 组冲突，请先人工确认\n", res.PairCount, res.PairCount, res.ConflictCount)
+			fmt.Fprintf(stdout, "检查完成：共识别 %d %s，可生成 %d 页 PPT；发现 %d 组冲突，请先人工确认\n", res.PairCount, unit, res.SlideCount, res.ConflictCount)
 			return nil
 		}
-		fmt.Fprintf(stdout, "检查通过：共识别 %d 对双语文件，可生成 %d 页 PPT\n", res.PairCount, res.PairCount)
+		fmt.Fprintf(stdout, "检查通过：共识别 %d %s，可生成 %d 页 PPT\n", res.PairCount, unit, res.SlideCount)
 		return nil
 	}
 }
 
+func newCacheCmd(stdout io.Writer) *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:           "cache",
+		Short:         "管理排版缓存",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:           "clean",
+		Short:         "删除全部排版缓存",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := app.CleanCache("")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "排版缓存已清理：%s\n", dir)
+			return nil
+		},
+	})
+	return cacheCmd
+}
+
+func newInitCmd(stdout io.Writer) *cobra.Command {
+	opts := app.InitOptions{}
+	initCmd := &cobra.Command{
+		Use:           "init <dir>",
+		Short:         "生成示例数据源（EN/、CN/ 和一张正反面卡片）和带注释的 syl-md2ppt.yaml",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Dir = args[0]
+			res, err := app.Init(opts)
+			if err != nil {
+				return err
+			}
+			for _, f := range res.Files {
+				fmt.Fprintf(stdout, "已创建：%s\n", f)
+			}
+			fmt.Fprintf(stdout, "下一步：cd %s && syl-md2ppt build .\n", res.Dir)
+			return nil
+		},
+	}
+	initCmd.Flags().BoolVar(&opts.Force, "force", false, "覆盖已存在的文件")
+	initCmd.Flags().BoolVar(&opts.Ignore, "ignore", false, "同时生成 "+discovery.IgnoreFile+"（生成时跳过的文件）")
+	return initCmd
+}
+
+// watchBuild 持续监视并重新生成，只打印和上一次相比有变化的告警。
+func watchBuild(ctx context.Context, opts app.Options, stdout, stderr io.Writer) error {
+	fmt.Fprintln(stdout, "开始监视改动，按 Ctrl+C 退出")
+	return app.Watch(ctx, opts, app.WatchOptions{}, func(ev app.WatchEvent) {
+		for _, w := range ev.Resolved {
+			fmt.Fprintln(stderr, "已解决："+w)
+		}
+		for _, w := range ev.Added {
+			fmt.Fprintln(stderr, w)
+		}
+		if ev.Err != nil {
+			fmt.Fprintln(stderr, FriendlyError(ev.Err))
+			return
+		}
+		for _, out := range ev.Result.Outputs {
+			fmt.Fprintf(stdout, "%s 已更新：%s\n", time.Now().Format("15:04:05"), out.Path)
+		}
+	})
+}
+
+// writeReport 输出机器可读的结果。出错时报告里只有 error 字段和诊断明细，退出码仍然非 0；
+// 严格模式的失败保留完整结果。
+func writeReport(w io.Writer, format string, r app.Report, runErr error) error {
+	if runErr != nil && !errors.Is(runErr, diag.ErrStrict) {
+		r = app.Report{Command: r.Command}
+	}
+	if runErr != nil {
+		r.Error, r.Diagnostics = runErr.Error(), diag.Diagnostics(runErr)
+	}
+	if err := app.WriteReport(w, format, r); err != nil {
+		return err
+	}
+	if runErr != nil {
+		// 保留原来的错误，严格模式的失败仍然用 ExitStrict 退出。
+		return errors.Join(errAlreadyPrinted, runErr)
+	}
+	return nil
+}
+
+// confirmOverwrite 在标准错误上提问，从标准输入读回答；只有 y/yes 算同意，读不到输入按不覆盖处理。
+func confirmOverwrite(in io.Reader, stderr io.Writer) func(string) (bool, error) {
+	r := bufio.NewReader(in)
+	return func(path string) (bool, error) {
+		fmt.Fprintf(stderr, "%s 已存在，要覆盖吗？[y/N] ", path)
+		line, err := r.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(stderr)
+			return false, nil
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return true, nil
+		}
+		return false, nil
+	}
+}
+
 func normalizeArgs(args []string) []string {
 	if len(args) == 0 {
 		return args
 	}
 	first := args[0]
 	switch first {
-	case "build", "check", "help", "completion", "version":
+	case "build", "check", "cache", "init", "config", "help", "completion", "version":
 		return args
 	}
 	if first == "-h" || first == "--help" || first == "-v" || first == "--version" {
@@ -206,17 +425,25 @@ func normalizeArgs(args []string) []string {
 	return append([]string{"build"}, args...)
 }
 
+func flagTakesValue(arg string) bool {
+	switch arg {
+	case "--output", "--config", "--lang", "--template", "--format", "--jobs", "--overwrite":
+		return true
+	}
+	return false
+}
+
 func containsPositionalSource(args []string) bool {
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 		if arg == "--" {
 			return i+1 < len(args)
 		}
-		if arg == "--output" || arg == "--config" {
+		if flagTakesValue(arg) {
 			i++
 			continue
 		}
-		if strings.HasPrefix(arg, "--output=") || strings.HasPrefix(arg, "--config=") {
+		if strings.HasPrefix(arg, "--") && strings.Contains(arg, "=") {
 			continue
 		}
 		if strings.HasPrefix(arg, "-") {
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"archive/zip"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
@@ -10,6 +12,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"syl-md2ppt/internal/diag"
 )
 
 var ansiRegexp = regexp.MustCompile(`\x1b\[[0-9;]*m`)
@@ -73,8 +77,13 @@ func TestNormalizeArgs(t *testing.T) {
 	}{
 		{name: "direct run", in: []string{"./SPI", "--output", "./out"}, want: []string{"build", "./SPI", "--output", "./out"}},
 		{name: "flag first", in: []string{"--output", "./out", "./SPI"}, want: []string{"build", "--output", "./out", "./SPI"}},
+		{name: "lang flag first", in: []string{"--lang", "EN", "./SPI"}, want: []string{"build", "--lang", "EN", "./SPI"}},
+		{name: "template flag first", in: []string{"--template", "brand.pptx", "./SPI"}, want: []string{"build", "--template", "brand.pptx", "./SPI"}},
+		{name: "jobs flag first", in: []string{"--jobs", "4", "./SPI"}, want: []string{"build", "--jobs", "4", "./SPI"}},
 		{name: "build command", in: []string{"build", "./SPI"}, want: []string{"build", "./SPI"}},
 		{name: "check command", in: []string{"check", "./SPI"}, want: []string{"check", "./SPI"}},
+		{name: "init command", in: []string{"init", "./SPI"}, want: []string{"init", "./SPI"}},
+		{name: "config command", in: []string{"config", "show", "--config", "a.yaml"}, want: []string{"config", "show", "--config", "a.yaml"}},
 		{name: "help flag", in: []string{"--help"}, want: []string{"--help"}},
 	}
 
@@ -204,6 +213,134 @@ func TestCheckShowsConflictSummary(t *testing.T) {
 	}
 }
 
+func TestCheckFormatJSON(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	enDir := filepath.Join(source, "EN", "D")
+	cnDir := filepath.Join(source, "CN", "D")
+	for _, dir := range []string{enDir, cnDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	for _, name := range []string{"1-012-X.md", "1-012-Y.md", "1-013-front.md"} {
+		for _, dir := range []string{enDir, cnDir} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("text"), 0o644); err != nil {
+				t.Fatalf("write %s: %v", name, err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(enDir, "notes.md"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	root := NewRootCmd(time.Now, bytes.NewBufferString("ABCDEF"), stdout, stderr)
+	root.SetArgs([]string{"check", source, "--format", "json"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if stderr.Len() != 0 {
+		t.Fatalf("json mode should keep stderr empty, got: %q", stderr.String())
+	}
+	var report struct {
+		Schema string `json:"schema"`
+		Check  struct {
+			PairCount   int      `json:"pair_count"`
+			Conflicts   []string `json:"conflicts"`
+			Diagnostics []struct {
+				Code  string   `json:"code"`
+				Paths []string `json:"paths"`
+				Group string   `json:"group"`
+			} `json:"diagnostics"`
+			Pairs []struct {
+				No       int      `json:"no"`
+				Paths    []string `json:"paths"`
+				Conflict bool     `json:"conflict"`
+			} `json:"pairs"`
+			ConfigSource string `json:"config_source"`
+		} `json:"check"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("output should be JSON: %v\n%s", err, stdout.String())
+	}
+	if report.Schema != "syl-md2ppt/report/v1" {
+		t.Fatalf("unexpected schema %q", report.Schema)
+	}
+	c := report.Check
+	if c.PairCount != 3 || len(c.Pairs) != 3 || len(c.Pairs[0].Paths) != 2 || c.ConfigSource == "" {
+		t.Fatalf("unexpected check report: %s", stdout.String())
+	}
+	if !reflect.DeepEqual(c.Conflicts, []string{"D/1-12"}) || !c.Pairs[0].Conflict || c.Pairs[2].Conflict {
+		t.Fatalf("conflict group should be reported: %s", stdout.String())
+	}
+	groups := map[string]string{}
+	paths := map[string]int{}
+	for _, d := range c.Diagnostics {
+		groups[d.Code] = d.Group
+		paths[d.Code] = len(d.Paths)
+	}
+	if groups["conflict"] != "D/1-12" || paths["conflict"] != 4 || paths["unmatched_filename"] != 1 {
+		t.Fatalf("expected conflict and unmatched_filename warnings: %s", stdout.String())
+	}
+}
+
+func TestBuildOutputToStdout(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	for _, lang := range []string{"EN", "CN"} {
+		dir := filepath.Join(source, lang, "D")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "1-002-Front.md"), []byte(lang+" text"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", lang, err)
+		}
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	root := NewRootCmd(time.Now, bytes.NewBufferString("ABCDEF"), stdout, stderr)
+	root.SetArgs([]string{"build", source, "--output", "-", "--no-cache"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(stdout.Bytes()), int64(stdout.Len())); err != nil {
+		t.Fatalf("stdout should hold only the pptx: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "标准输出") {
+		t.Fatalf("status should go to stderr, got: %q", stderr.String())
+	}
+
+	root = NewRootCmd(time.Now, bytes.NewBufferString("ABCDEF"), stdout, stderr)
+	root.SetArgs([]string{"build", source, "--output", "-", "--format", "json"})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "--format") {
+		t.Fatalf("--output - and --format json should conflict, got: %v", err)
+	}
+}
+
+func TestBuildFormatYAMLReportsErrors(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	root := NewRootCmd(time.Now, bytes.NewBufferString("ABCDEF"), stdout, stderr)
+	root.SetArgs([]string{"build", filepath.Join(t.TempDir(), "missing"), "--format", "yaml"})
+	err := root.Execute()
+	if err == nil || FriendlyError(err) != "" {
+		t.Fatalf("expected a silent non-nil error, got: %v", err)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "schema: syl-md2ppt/report/v1") || !strings.Contains(out, "command: build") || !strings.Contains(out, "error: ") {
+		t.Fatalf("unexpected yaml report: %q", out)
+	}
+
+	root = NewRootCmd(time.Now, bytes.NewBufferString("ABCDEF"), stdout, stderr)
+	root.SetArgs([]string{"check", "x", "--format", "xml"})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "--format") {
+		t.Fatalf("unknown format should be rejected, got: %v", err)
+	}
+}
+
 func TestVersionCommand(t *testing.T) {
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
@@ -245,3 +382,80 @@ func TestVersionFlagWithoutSource(t *testing.T) {
 		t.Fatalf("unexpected version output: %q", got)
 	}
 }
+
+func TestExitCodeSeparatesStrictFailures(t *testing.T) {
+	strictErr := &diag.Error{Err: diag.ErrStrict, Message: "strict"}
+	if ExitCode(nil) != 0 || ExitCode(errors.New("boom")) != ExitFailure || ExitCode(strictErr) != ExitStrict {
+		t.Fatalf("unexpected exit codes")
+	}
+	if ExitStrict == ExitFailure {
+		t.Fatalf("strict failures need their own exit code")
+	}
+}
+
+func TestStrictFailureKeepsExitCodeInReports(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "SPI")
+	for _, dir := range []string{"EN", "CN"} {
+		if err := os.MkdirAll(filepath.Join(source, dir, "D"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(source, dir, "D", "1-013-front.md"), []byte("text"), 0o644); err != nil {
+			t.Fatalf("write card: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(source, "EN", "D", "notes.md"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		stdout := &bytes.Buffer{}
+		root := NewRootCmd(time.Now, bytes.NewBufferString("ABCDEF"), stdout, &bytes.Buffer{})
+		root.SetArgs([]string{"check", source, "--strict", "--format", format})
+		err := root.Execute()
+		if ExitCode(err) != ExitStrict || FriendlyError(err) != "" {
+			t.Fatalf("%s: strict failure should exit %d silently, got %d (%v)", format, ExitStrict, ExitCode(err), err)
+		}
+		if !strings.Contains(stdout.String(), "unmatched_filename") {
+			t.Fatalf("%s: report should list the warning: %s", format, stdout.String())
+		}
+	}
+}
+
+func TestConfigValidateAndShow(t *testing.T) {
+	tmp := t.TempDir()
+	bad := filepath.Join(tmp, "bad.yaml")
+	if err := os.WriteFile(bad, []byte("layout:\n  columns:\n    left_ratio: 1.5\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	root := NewRootCmd(time.Now, nil, stdout, stderr)
+	root.SetArgs([]string{"config", "validate", bad})
+	if err := root.Execute(); !errors.Is(err, errAlreadyPrinted) {
+		t.Fatalf("invalid config should fail, got: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "第 3 行：layout.columns.left_ratio") {
+		t.Fatalf("problem should name the line and key, got: %q", stderr.String())
+	}
+
+	stdout.Reset()
+	root = NewRootCmd(time.Now, nil, stdout, stderr)
+	root.SetArgs([]string{"config", "show", "--config", bad})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("config show returned error: %v", err)
+	}
+	if out := stdout.String(); !strings.Contains(out, "# 来源："+bad) || !strings.Contains(out, "left_ratio: 0.5") {
+		t.Fatalf("config show should print the source and effective values, got: %q", out)
+	}
+
+	stdout.Reset()
+	root = NewRootCmd(time.Now, nil, stdout, stderr)
+	root.SetArgs([]string{"config", "show", "--explain", "--config", bad})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("config show --explain returned error: %v", err)
+	}
+	if out := stdout.String(); !regexp.MustCompile(`layout\.columns\.gap: 0\.2 +# 内置默认`).MatchString(out) || !strings.Contains(out, "# 项目配置 "+bad+":3") {
+		t.Fatalf("--explain should name the layer of each key, got: %q", out)
+	}
+}
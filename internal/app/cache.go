@@ -0,0 +1,181 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"syl-md2ppt/internal/cache"
+	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
+	"syl-md2ppt/internal/pptx"
+	"syl-md2ppt/internal/render"
+)
+
+// cacheFormat 在缓存条目的结构有不兼容的改动时加一，旧条目自然失效。
+const cacheFormat = 2
+
+// cacheMaxAge 是条目多久没命中就在生成后被清掉。
+const cacheMaxAge = 30 * 24 * time.Hour
+
+// CacheStats 是一次生成里按卡片统计的缓存命中情况。
+type CacheStats struct {
+	Hits   int `json:"hits" yaml:"hits"`
+	Misses int `json:"misses" yaml:"misses"`
+}
+
+// cardEntry 是一张卡片在某种语言组合下的排版结果。Diagnostics 里的页码从卡片第一页算起。
+type cardEntry struct {
+	Slides      []render.Slide
+	Parts       []pptx.SlidePart
+	Diagnostics []diag.Diagnostic
+	// Images 是卡片引用的图片和内容哈希（文件不存在时为空），有一张变了就不算命中。
+	Images map[string]string
+}
+
+// renderCache 用卡片原文和配置的哈希找排版结果。base 是配置、字体和程序本身的哈希，
+// 任何一项变了所有卡片都重新排版。stats 只由 renderSlides 按顺序累加。
+type renderCache struct {
+	store *cache.Store
+	base  []byte
+	cfg   *config.Config
+	deck  pptx.Deck
+	stats CacheStats
+}
+
+// newRenderCache 按选项打开缓存；关掉缓存或找不到缓存目录时返回 nil，照常排版。
+func newRenderCache(opts Options, cfg *config.Config) *renderCache {
+	if opts.NoCache {
+		return nil
+	}
+	dir := opts.CacheDir
+	if dir == "" {
+		d, err := cache.DefaultDir()
+		if err != nil {
+			return nil
+		}
+		dir = d
+	}
+	cfgJSON, err := json.Marshal(cfg)
+	if err != nil {
+		return nil
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "format %d\nprogram %s\n", cacheFormat, programStamp())
+	writeField(h, cfgJSON)
+	files := cfg.Layout.Typography.FontFiles
+	for _, path := range []string{files.Regular, files.Bold, files.Italic, files.BoldItalic} {
+		writeField(h, []byte(path+"\x00"+fileHash(path)))
+	}
+	return &renderCache{store: cache.Open(dir), base: h.Sum(nil), cfg: cfg, deck: newDeck(cfg, nil)}
+}
+
+// programStamp 标识当前的程序：版本、提交，以及可执行文件的大小和修改时间（开发时重新编译也会变）。
+// 变了以后旧条目再也不会命中，由 prune 按时间清掉。
+func programStamp() string {
+	stamp := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		stamp = info.Main.Version
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+				stamp += " " + s.Value
+			}
+		}
+	}
+	if exe, err := os.Executable(); err == nil {
+		if st, err := os.Stat(exe); err == nil {
+			stamp += fmt.Sprintf(" %d %d", st.Size(), st.ModTime().UnixNano())
+		}
+	}
+	return stamp
+}
+
+// key 算卡片的缓存键；cfg 是卡片叠加目录配置后的配置，和整份 PPT 的不同时也算进去。
+func (c *renderCache) key(cfg *config.Config, sources []render.Source) string {
+	h := sha256.New()
+	h.Write(c.base)
+	if cfg != c.cfg {
+		cfgJSON, _ := json.Marshal(cfg)
+		writeField(h, cfgJSON)
+	}
+	for _, src := range sources {
+		for _, field := range []string{src.Lang, src.Tag, src.Path, src.Raw} {
+			writeField(h, []byte(field))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get 取出缓存的排版结果，引用的图片有变化时不算命中。可以并发调用。
+func (c *renderCache) get(key string) (cardEntry, bool) {
+	var entry cardEntry
+	if !c.store.Get(key, &entry) {
+		return cardEntry{}, false
+	}
+	for path, sum := range entry.Images {
+		if fileHash(path) != sum {
+			return cardEntry{}, false
+		}
+	}
+	return entry, true
+}
+
+// put 生成各页的 XML 后存进缓存。缓存只是加速，出错时不影响这次生成。可以并发调用。
+func (c *renderCache) put(key string, sources []render.Source, entry *cardEntry) {
+	parts := make([]pptx.SlidePart, 0, len(entry.Slides))
+	for _, s := range entry.Slides {
+		part, err := pptx.RenderSlide(c.deck, s)
+		if err != nil {
+			return
+		}
+		parts = append(parts, part)
+	}
+	entry.Parts = parts
+	entry.Images = make(map[string]string)
+	for _, src := range sources {
+		for _, path := range render.ImagePaths(src) {
+			entry.Images[path] = fileHash(path)
+		}
+	}
+	_ = c.store.Put(key, entry)
+}
+
+// prune 清掉长期没命中的条目。和 put 一样出错时不影响这次生成。
+func (c *renderCache) prune() {
+	_ = c.store.Prune(cacheMaxAge)
+}
+
+// writeField 先写长度再写内容，免得相邻字段拼在一起时产生歧义。
+func writeField(h hash.Hash, b []byte) {
+	fmt.Fprintf(h, "%d:", len(b))
+	h.Write(b)
+}
+
+func fileHash(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CleanCache 删除缓存目录，返回删掉的目录；dir 为空时用默认目录。
+func CleanCache(dir string) (string, error) {
+	if dir == "" {
+		d, err := cache.DefaultDir()
+		if err != nil {
+			return "", err
+		}
+		dir = d
+	}
+	return dir, cache.Open(dir).Clean()
+}
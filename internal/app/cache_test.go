@@ -0,0 +1,136 @@
+package app
+
+import (
+	"archive/zip"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePNG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create png: %v", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+}
+
+func TestRun_CacheSkipsUnchangedCards(t *testing.T) {
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+	enDir := filepath.Join(source, "EN", "D")
+	cnDir := filepath.Join(source, "CN", "D")
+	writePNG(t, filepath.Join(enDir, "chart.png"), 40, 20)
+	if err := os.WriteFile(filepath.Join(enDir, "1-003-Front.md"), []byte("See [docs](https://example.com)\n\n![Chart](chart.png)"), 0o644); err != nil {
+		t.Fatalf("write en: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cnDir, "1-003-Front.md"), []byte("见 [文档](https://example.com)"), 0o644); err != nil {
+		t.Fatalf("write cn: %v", err)
+	}
+	cacheDir := filepath.Join(tmp, "cache")
+	run := func(name string, noCache bool) Result {
+		t.Helper()
+		res, err := Run(Options{
+			SourceDir: source,
+			OutputArg: filepath.Join(tmp, name),
+			CWD:       tmp,
+			NoCache:   noCache,
+			CacheDir:  cacheDir,
+		})
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+		return res
+	}
+
+	first := run("first.pptx", false)
+	if first.Cache == nil || first.Cache.Hits != 0 || first.Cache.Misses != 2 {
+		t.Fatalf("first run should miss every card, got %+v", first.Cache)
+	}
+	second := run("second.pptx", false)
+	if second.Cache.Hits != 2 || second.Cache.Misses != 0 {
+		t.Fatalf("second run should hit every card, got %+v", second.Cache)
+	}
+	fresh := run("fresh.pptx", true)
+	if fresh.Cache != nil {
+		t.Fatalf("--no-cache should not report cache stats")
+	}
+	for _, part := range []string{"ppt/slides/slide2.xml", "ppt/slides/_rels/slide2.xml.rels"} {
+		if got, want := readPart(t, second.OutputPath, part), readPart(t, fresh.OutputPath, part); got != want {
+			t.Fatalf("cached %s differs from a fresh render:\n%s\n---\n%s", part, got, want)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(cnDir, "1-002-Front.md"), []byte("改过的中文正面"), 0o644); err != nil {
+		t.Fatalf("rewrite cn: %v", err)
+	}
+	if res := run("third.pptx", false); res.Cache.Hits != 1 || res.Cache.Misses != 1 {
+		t.Fatalf("only the edited card should be re-rendered, got %+v", res.Cache)
+	}
+	writePNG(t, filepath.Join(enDir, "chart.png"), 80, 20)
+	if res := run("fourth.pptx", false); res.Cache.Hits != 1 || res.Cache.Misses != 1 {
+		t.Fatalf("changing an image should invalidate its card, got %+v", res.Cache)
+	}
+
+	if _, err := CleanCache(cacheDir); err != nil {
+		t.Fatalf("CleanCache: %v", err)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Fatalf("cache dir should be removed, stat err: %v", err)
+	}
+	if res := run("fifth.pptx", false); res.Cache.Misses != 2 {
+		t.Fatalf("cleaned cache should miss every card, got %+v", res.Cache)
+	}
+}
+
+func TestRun_CachePrunesStaleEntries(t *testing.T) {
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+	cacheDir := filepath.Join(tmp, "cache")
+	// 真实的键是十六进制，用 zz 分片免得和这次生成的条目撞在一起。
+	stale := filepath.Join(cacheDir, "zz", "zz01.gob")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(stale, []byte("old"), 0o644); err != nil {
+		t.Fatalf("write stale entry: %v", err)
+	}
+	old := time.Now().Add(-2 * cacheMaxAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	if _, err := Run(Options{SourceDir: source, OutputArg: filepath.Join(tmp, "out.pptx"), CWD: tmp, CacheDir: cacheDir}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("stale entry should be pruned, stat err: %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(stale)); !os.IsNotExist(err) {
+		t.Fatalf("empty shard should be removed, stat err: %v", err)
+	}
+	res, err := Run(Options{SourceDir: source, OutputArg: filepath.Join(tmp, "again.pptx"), CWD: tmp, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if res.Cache.Misses != 0 {
+		t.Fatalf("fresh entries should survive pruning, got %+v", res.Cache)
+	}
+}
+
+func readPart(t *testing.T, path, name string) string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer zr.Close()
+	return readSlideXML(t, &zr.Reader, name)
+}
@@ -4,27 +4,38 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
 
 	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
 	"syl-md2ppt/internal/discovery"
 )
 
 type CheckResult struct {
-	PairCount     int
-	WarningCount  int
-	ConflictCount int
-	HasConflict   bool
-	Warnings      []string
-	Items         []CheckItem
-	ConfigSource  string
+	Languages []string `json:"languages" yaml:"languages"`
+	PairCount int      `json:"pair_count" yaml:"pair_count"`
+	// SlideCount 是 build 会生成的页数，拆页时比 PairCount 多。
+	SlideCount    int  `json:"slide_count" yaml:"slide_count"`
+	WarningCount  int  `json:"warning_count" yaml:"warning_count"`
+	ConflictCount int  `json:"conflict_count" yaml:"conflict_count"`
+	HasConflict   bool `json:"has_conflict" yaml:"has_conflict"`
+	// Warnings 是命令行显示的告警文字，Diagnostics 是同样内容的结构化版本。
+	Warnings    []string          `json:"-" yaml:"-"`
+	Diagnostics []diag.Diagnostic `json:"diagnostics" yaml:"diagnostics"`
+	// Conflicts 是有多个候选、需要人工确认的数字键。
+	Conflicts    []string    `json:"conflicts" yaml:"conflicts"`
+	Items        []CheckItem `json:"pairs" yaml:"pairs"`
+	ConfigSource string      `json:"config_source" yaml:"config_source"`
 }
 
 type CheckItem struct {
-	No     int
-	ENPath string
-	CNPath string
+	No       int      `json:"no" yaml:"no"`
+	Paths    []string `json:"paths" yaml:"paths"`
+	Group    string   `json:"group" yaml:"group"`
+	Conflict bool     `json:"conflict,omitempty" yaml:"conflict,omitempty"`
 }
 
 func Check(opts Options) (CheckResult, error) {
@@ -41,10 +52,11 @@ func Check(opts Options) (CheckResult, error) {
 		cwd = wd
 	}
 
-	cfg, cfgSrc, err := config.Load(opts.ConfigPath, cwd)
+	loaded, err := loadConfig(Options{ConfigPath: opts.ConfigPath}, cwd)
 	if err != nil {
 		return CheckResult{}, err
 	}
+	cfg, cfgSrc := loaded.Config, loaded.Source
 
 	pairs, warnings, err := discovery.Discover(opts.SourceDir, cfg, discovery.DiscoverOptions{
 		FailOnConflict: false,
@@ -53,40 +65,90 @@ func Check(opts Options) (CheckResult, error) {
 		return CheckResult{}, err
 	}
 	if len(pairs) == 0 {
-		return CheckResult{}, fmt.Errorf("没找到可用的双语 Markdown 文件，请检查 EN/CN 目录和文件名中的数字")
+		return CheckResult{}, fmt.Errorf("%w，请检查 %s 目录和文件名中的数字", diag.ErrNoSources, languageDirs(cfg))
 	}
 
-	warnings = dedupeStrings(warnings)
+	// 目录配置写错时生成会失败，检查时一并报出来。
+	cardCfgs, err := cardConfigs(loaded, opts.SourceDir, pairs)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	slideCount, err := countSlides(cfg, cardCfgs, pairs, opts.Jobs)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	warnings = dedupeDiagnostics(append(configWarnings(loaded), warnings...))
 	conflictCount := countConflictWarnings(warnings)
 	items := make([]CheckItem, 0, len(pairs))
+	conflicts := make([]string, 0)
 	for i, p := range pairs {
+		if p.Conflict && (len(conflicts) == 0 || conflicts[len(conflicts)-1] != p.Group) {
+			conflicts = append(conflicts, p.Group)
+		}
+		paths := make([]string, len(p.Paths))
+		for j, path := range p.Paths {
+			paths[j] = toAbsPath(path, cwd)
+		}
 		items = append(items, CheckItem{
-			No:     i + 1,
-			ENPath: toAbsPath(p.ENPath, cwd),
-			CNPath: toAbsPath(p.CNPath, cwd),
+			No:       i + 1,
+			Paths:    paths,
+			Group:    p.Group,
+			Conflict: p.Conflict,
 		})
 	}
 	sort.Slice(items, func(i, j int) bool {
-		if items[i].No != items[j].No {
-			return items[i].No < items[j].No
-		}
-		return items[i].ENPath < items[j].ENPath
+		return items[i].No < items[j].No
 	})
+	langs := make([]string, 0, len(cfg.Languages))
+	for _, l := range cfg.Languages {
+		langs = append(langs, l.Name)
+	}
+	var strictErr error
+	if opts.Strict {
+		strictErr = applyStrict(warnings, cfg.Strict.Codes)
+	}
 	return CheckResult{
+		Languages:     langs,
 		PairCount:     len(pairs),
+		SlideCount:    slideCount,
 		WarningCount:  len(warnings),
 		ConflictCount: conflictCount,
 		HasConflict:   conflictCount > 0,
-		Warnings:      warnings,
+		Warnings:      warningTexts(warnings),
+		Diagnostics:   warnings,
+		Conflicts:     conflicts,
 		Items:         items,
 		ConfigSource:  cfgSrc,
-	}, nil
+	}, strictErr
+}
+
+// countSlides 算出 build 会生成的页数。只有拆页时一对文件才可能排成多页，这时按 build 的排版真正排一遍。
+func countSlides(cfg *config.Config, cardCfgs []*config.Config, pairs []discovery.Pair, jobs int) (int, error) {
+	if !slices.ContainsFunc(cardCfgs, func(c *config.Config) bool { return c.Layout.Overflow == config.OverflowSplit }) {
+		return len(pairs), nil
+	}
+	selections, err := selectLanguages(cfg, Options{})
+	if err != nil {
+		return 0, err
+	}
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	count := 0
+	for _, card := range renderCards(cardCfgs, pairs, selections[0], nil, jobs) {
+		if card.err != nil {
+			return 0, card.err
+		}
+		count += len(card.entry.Slides)
+	}
+	return count, nil
 }
 
-func countConflictWarnings(in []string) int {
+func countConflictWarnings(in []diag.Diagnostic) int {
 	count := 0
-	for _, s := range in {
-		if strings.HasPrefix(s, "冲突组：") {
+	for _, w := range in {
+		if w.Code == diag.CodeConflict {
 			count++
 		}
 	}
@@ -1,9 +1,13 @@
 package app
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"syl-md2ppt/internal/diag"
 )
 
 func TestCheck_OK(t *testing.T) {
@@ -43,11 +47,67 @@ func TestCheck_OK(t *testing.T) {
 	if res.Items[0].No != 1 || res.Items[1].No != 2 {
 		t.Fatalf("expected sequential page numbers 1,2 got %d,%d", res.Items[0].No, res.Items[1].No)
 	}
-	if res.Items[0].ENPath != filepath.Join(enDir, "deck-1-1-002-front.md") {
-		t.Fatalf("unexpected EN path: %s", res.Items[0].ENPath)
+	if res.Items[0].Paths[0] != filepath.Join(enDir, "deck-1-1-002-front.md") {
+		t.Fatalf("unexpected EN path: %s", res.Items[0].Paths[0])
+	}
+	if res.Items[1].Paths[0] != filepath.Join(enDir, "deck-1-1-002-back.md") {
+		t.Fatalf("unexpected EN back path: %s", res.Items[1].Paths[0])
+	}
+}
+
+func TestCheck_CountsContinuationSlides(t *testing.T) {
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+	long := strings.Repeat("english paragraph with a handful of words\n", 200)
+	if err := os.WriteFile(filepath.Join(source, "EN", "D", "1-002-Front.md"), []byte(long), 0o644); err != nil {
+		t.Fatalf("write en: %v", err)
+	}
+	cfgPath := filepath.Join(tmp, "split.yaml")
+	if err := os.WriteFile(cfgPath, []byte("layout:\n  overflow: split\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	res, err := Check(Options{SourceDir: source, CWD: tmp, ConfigPath: cfgPath})
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	built, err := Run(Options{SourceDir: source, CWD: tmp, ConfigPath: cfgPath, OutputArg: filepath.Join(tmp, "out.pptx"), NoCache: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if res.PairCount != 1 || res.SlideCount < 2 || res.SlideCount != built.SlideCount {
+		t.Fatalf("check should count continuation slides like build: pairs %d, check %d, build %d", res.PairCount, res.SlideCount, built.SlideCount)
+	}
+}
+
+func TestCheck_WarnsWhenFontWidthsAreEstimated(t *testing.T) {
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+	cfgPath := filepath.Join(tmp, "font.yaml")
+	if err := os.WriteFile(cfgPath, []byte("layout:\n  typography:\n    font_family: Noto Sans\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	res, err := Check(Options{SourceDir: source, CWD: tmp, ConfigPath: cfgPath})
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	var found bool
+	for _, d := range res.Diagnostics {
+		found = found || d.Code == diag.CodeFontFallback && strings.Contains(d.Message, "Noto Sans")
 	}
-	if res.Items[1].ENPath != filepath.Join(enDir, "deck-1-1-002-back.md") {
-		t.Fatalf("unexpected EN back path: %s", res.Items[1].ENPath)
+	if !found {
+		t.Fatalf("expected a font_fallback warning, got %+v", res.Diagnostics)
+	}
+
+	res, err = Check(Options{SourceDir: source, CWD: tmp})
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	for _, d := range res.Diagnostics {
+		if d.Code == diag.CodeFontFallback {
+			t.Fatalf("Calibri has built-in widths and should not warn: %+v", d)
+		}
 	}
 }
 
@@ -86,3 +146,17 @@ func TestCheck_ConflictDetected(t *testing.T) {
 		t.Fatalf("expected one conflict group, got %d", res.ConflictCount)
 	}
 }
+
+func TestCheck_EmptySource(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	for _, dir := range []string{"EN", "CN"} {
+		if err := os.MkdirAll(filepath.Join(source, dir), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	_, err := Check(Options{SourceDir: source, CWD: tmp})
+	if !errors.Is(err, diag.ErrNoSources) {
+		t.Fatalf("expected diag.ErrNoSources, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
+	"syl-md2ppt/internal/discovery"
+)
+
+// ConfigOptions 是 config show 的参数。
+type ConfigOptions struct {
+	ConfigPath   string
+	TemplatePath string
+	CWD          string
+	// Card 是某张卡片的文件，给了时叠加它所在目录的目录配置。
+	Card    string
+	Explain bool
+}
+
+// ConfigView 是 config show 的内容：实际生效的配置、合并了哪些层，Explain 时还有每个配置项的来源。
+type ConfigView struct {
+	Source   string
+	Layers   []config.Origin
+	Config   *config.Config
+	Explain  []config.Explanation
+	Warnings []diag.Diagnostic
+}
+
+// ShowConfig 合并配置；给了卡片时从卡片往上找到数据源目录（含某个语言目录的那一级），
+// 按配对结果叠加这张卡片各语言文件所在目录的目录配置。
+func ShowConfig(opts ConfigOptions) (ConfigView, error) {
+	cwd := opts.CWD
+	if cwd == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return ConfigView{}, fmt.Errorf("读取当前目录失败：%w", err)
+		}
+		cwd = wd
+	}
+	loaded, err := loadConfig(Options{ConfigPath: opts.ConfigPath, TemplatePath: opts.TemplatePath}, cwd)
+	if err != nil {
+		return ConfigView{}, err
+	}
+	view := ConfigView{Source: loaded.Source, Layers: loaded.Layers, Config: loaded.Config, Warnings: configWarnings(loaded)}
+	var source string
+	var paths []string
+	if opts.Card != "" {
+		source, paths, err = findCard(loaded.Config, toAbsPath(opts.Card, cwd))
+		if err != nil {
+			return ConfigView{}, err
+		}
+		if view.Config, err = loaded.ForCard(source, paths); err != nil {
+			return ConfigView{}, err
+		}
+	}
+	if opts.Explain {
+		if view.Explain, err = loaded.Explain(source, paths); err != nil {
+			return ConfigView{}, err
+		}
+	}
+	return view, nil
+}
+
+func findCard(cfg *config.Config, card string) (string, []string, error) {
+	if _, err := os.Stat(card); err != nil {
+		return "", nil, fmt.Errorf("读取卡片失败：%w", err)
+	}
+	source := ""
+	for d := filepath.Dir(card); d != filepath.Dir(d); d = filepath.Dir(d) {
+		if slices.ContainsFunc(cfg.Languages, func(l config.LanguageConfig) bool { return l.Dir == filepath.Base(d) }) {
+			source = filepath.Dir(d)
+			break
+		}
+	}
+	if source == "" {
+		return "", nil, fmt.Errorf("%s 不在任何语言目录（%s）下面", card, languageDirs(cfg))
+	}
+	pairs, _, err := discovery.Discover(source, cfg, discovery.DiscoverOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	for _, p := range pairs {
+		if slices.Contains(p.Paths, card) {
+			return source, p.Paths, nil
+		}
+	}
+	return "", nil, fmt.Errorf("%s 没有配成对，不会生成页面", card)
+}
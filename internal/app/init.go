@@ -0,0 +1,102 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/discovery"
+)
+
+// InitOptions 是 init 的参数。
+type InitOptions struct {
+	Dir string
+	// Force 时覆盖已存在的文件，否则有一个文件已存在就什么都不写。
+	Force bool
+	// Ignore 时同时生成 .md2pptignore。
+	Ignore bool
+}
+
+// InitResult 列出 init 写出的文件。
+type InitResult struct {
+	Dir   string   `json:"dir" yaml:"dir"`
+	Files []string `json:"files" yaml:"files"`
+}
+
+var sampleCards = map[string]map[string]string{
+	"EN": {
+		"0-001-Front.md": "★ Welcome to **syl-md2ppt**\n\nEach card is a Markdown file; EN and CN cards with the same number share one slide.\n\n● Inline formulas: $a^2 + b^2 = c^2$\n● Inline code: `syl-md2ppt build .`\n\n<!-- notes -->\nText after the separator goes to the speaker notes.\n",
+		"0-001-Back.md":  "▲ The back side follows the front side.\n\n| Term | Meaning |\n|---|---|\n| Front | first slide of a card |\n| Back | second slide of a card |\n",
+	},
+	"CN": {
+		"0-001-Front.md": "★ 欢迎使用 **syl-md2ppt**\n\n每张卡片是一个 Markdown 文件，编号相同的 EN、CN 卡片排在同一页。\n\n● 行内公式：$a^2 + b^2 = c^2$\n● 行内代码：`syl-md2ppt build .`\n\n<!-- notes -->\n分隔符之后的内容会写进演讲者备注。\n",
+		"0-001-Back.md":  "▲ 反面排在正面之后。\n\n| 术语 | 含义 |\n|---|---|\n| Front | 卡片的第一页 |\n| Back | 卡片的第二页 |\n",
+	},
+}
+
+const sampleIgnore = `# syl-md2ppt 生成时跳过的文件，每行一个通配符，# 开头为注释。
+# 不含 / 的规则按文件名或目录名匹配，含 / 的按 EN/、CN/ 下的相对路径匹配，以 / 结尾的只匹配目录。
+drafts/
+*.draft.md
+README.md
+`
+
+// Init 在 opts.Dir 下生成示例数据源（各语言目录和一张正反面卡片）和带注释的配置文件。
+func Init(opts InitOptions) (InitResult, error) {
+	if strings.TrimSpace(opts.Dir) == "" {
+		return InitResult{}, fmt.Errorf("还没给要初始化的目录")
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return InitResult{}, fmt.Errorf("读取目录失败：%w", err)
+	}
+	if st, err := os.Stat(dir); err == nil && !st.IsDir() {
+		return InitResult{}, fmt.Errorf("%s 已存在但不是目录", dir)
+	}
+
+	files := map[string][]byte{"syl-md2ppt.yaml": config.CommentedDefault()}
+	for lang, cards := range sampleCards {
+		for name, body := range cards {
+			files[filepath.Join(lang, "00_Intro", name)] = []byte(body)
+		}
+	}
+	if opts.Ignore {
+		files[discovery.IgnoreFile] = []byte(sampleIgnore)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if !opts.Force {
+		var existing []string
+		for _, name := range names {
+			if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+				existing = append(existing, filepath.Join(dir, name))
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return InitResult{}, fmt.Errorf("检查文件失败：%w", err)
+			}
+		}
+		if len(existing) > 0 {
+			return InitResult{}, fmt.Errorf("这些文件已存在，没有改动（加 --force 覆盖）：%s", strings.Join(existing, "、"))
+		}
+	}
+
+	res := InitResult{Dir: dir}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return res, fmt.Errorf("创建目录失败：%w", err)
+		}
+		if err := os.WriteFile(path, files[name], 0o644); err != nil {
+			return res, fmt.Errorf("写入 %s 失败：%w", path, err)
+		}
+		res.Files = append(res.Files, path)
+	}
+	return res, nil
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInit_ScaffoldsBuildableSource(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "deck")
+	res, err := Init(InitOptions{Dir: dir, Ignore: true})
+	if err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	if len(res.Files) != 6 {
+		t.Fatalf("expected config, ignore file and 4 cards, got %v", res.Files)
+	}
+
+	built, err := Run(Options{SourceDir: dir, OutputArg: "out.pptx", ConfigPath: filepath.Join(dir, "syl-md2ppt.yaml"), CWD: dir, NoCache: true})
+	if err != nil {
+		t.Fatalf("building the scaffold returned error: %v", err)
+	}
+	if built.SlideCount != 2 || len(built.Warnings) != 0 {
+		t.Fatalf("scaffold should build 2 slides without warnings, got %d %v", built.SlideCount, built.Warnings)
+	}
+
+	card := filepath.Join(dir, "EN", "00_Intro", "0-001-Front.md")
+	if err := os.WriteFile(card, []byte("mine"), 0o644); err != nil {
+		t.Fatalf("edit card: %v", err)
+	}
+	if _, err := Init(InitOptions{Dir: dir}); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("existing files should not be overwritten without --force, got %v", err)
+	}
+	if data, _ := os.ReadFile(card); string(data) != "mine" {
+		t.Fatalf("refused init must not touch existing files")
+	}
+	if _, err := Init(InitOptions{Dir: dir, Force: true}); err != nil {
+		t.Fatalf("Init with Force returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(card); string(data) == "mine" {
+		t.Fatalf("Force should overwrite the sample card")
+	}
+}
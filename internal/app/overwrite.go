@@ -0,0 +1,100 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --overwrite 的取值：输出文件已存在时怎么办。
+const (
+	OverwriteAlways = "always"
+	OverwriteNever  = "never"
+	OverwriteAsk    = "ask"
+)
+
+// ParseOverwrite 校验 --overwrite 的取值，空值按 always 处理。
+func ParseOverwrite(v string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(v)); p {
+	case "", OverwriteAlways:
+		return OverwriteAlways, nil
+	case OverwriteNever, OverwriteAsk:
+		return p, nil
+	}
+	return "", fmt.Errorf("--overwrite 只支持 never、ask、always，收到的是 %s", v)
+}
+
+// freePath 返回一个还不存在的文件名：deck.pptx 已存在时依次试 deck-1.pptx、deck-2.pptx……
+func freePath(path string) string {
+	if !fileExists(path) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if !fileExists(candidate) {
+			return candidate
+		}
+	}
+}
+
+// confirmOverwrite 在 ask 策略下询问是否覆盖已存在的文件；没给询问方式时按不覆盖处理。
+func confirmOverwrite(opts Options, path string) error {
+	if opts.Overwrite != OverwriteAsk || !fileExists(path) {
+		return nil
+	}
+	if opts.Confirm != nil {
+		ok, err := opts.Confirm(path)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s 已存在，没有覆盖", path)
+}
+
+// backupFile 把要被替换的旧文件保留为 <文件名>.bak。优先用硬链接，旧文件在新文件写好之前一直可用。
+func backupFile(path string) error {
+	if !fileExists(path) {
+		return nil
+	}
+	bak := path + ".bak"
+	if err := os.Remove(bak); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("备份旧文件失败：%w", err)
+	}
+	if err := os.Link(path, bak); err == nil {
+		return nil
+	}
+	if err := copyFile(path, bak); err != nil {
+		return fmt.Errorf("备份旧文件失败：%w", err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_OverwritePolicies(t *testing.T) {
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+	target := filepath.Join(tmp, "deck.pptx")
+	if err := os.WriteFile(target, []byte("old deck"), 0o644); err != nil {
+		t.Fatalf("write old deck: %v", err)
+	}
+	run := func(opts Options) (Result, error) {
+		opts.SourceDir, opts.OutputArg, opts.CWD, opts.NoCache = source, target, tmp, true
+		return Run(opts)
+	}
+
+	res, err := run(Options{Overwrite: OverwriteNever})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if want := filepath.Join(tmp, "deck-1.pptx"); res.OutputPath != want {
+		t.Fatalf("never should pick a numbered name, want %s got %s", want, res.OutputPath)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "old deck" {
+		t.Fatalf("never must leave the existing deck alone")
+	}
+
+	asked := ""
+	_, err = run(Options{Overwrite: OverwriteAsk, Confirm: func(path string) (bool, error) {
+		asked = path
+		return false, nil
+	}})
+	if err == nil || !strings.Contains(err.Error(), "没有覆盖") || asked != target {
+		t.Fatalf("declining should stop the build, asked %q, got %v", asked, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "old deck" {
+		t.Fatalf("declined deck should stay untouched")
+	}
+
+	_, err = run(Options{Overwrite: OverwriteAsk, Backup: true, Confirm: func(string) (bool, error) { return true, nil }})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(target + ".bak"); string(data) != "old deck" {
+		t.Fatalf("backup should keep the previous deck, got %q", data)
+	}
+	if data, _ := os.ReadFile(target); !strings.HasPrefix(string(data), "PK") {
+		t.Fatalf("target should hold the new deck")
+	}
+	matches, _ := filepath.Glob(filepath.Join(tmp, ".deck.pptx.*"))
+	if len(matches) != 0 {
+		t.Fatalf("temporary files should not be left behind: %v", matches)
+	}
+}
+
+func TestParseOverwrite(t *testing.T) {
+	for in, want := range map[string]string{"": OverwriteAlways, "Never": OverwriteNever, "ask": OverwriteAsk} {
+		if got, err := ParseOverwrite(in); err != nil || got != want {
+			t.Fatalf("ParseOverwrite(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseOverwrite("sometimes"); err == nil {
+		t.Fatalf("unknown policy should be rejected")
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"syl-md2ppt/internal/diag"
+)
+
+// ReportSchema 是 --format json/yaml 输出的格式版本；字段有不兼容的改动时升级版本号。
+const ReportSchema = "syl-md2ppt/report/v1"
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Report 是 check、build 的机器可读输出。Check 和 Build 只有一个有值；
+// 出错时只有 Error，以及错误带的诊断明细（如缺了哪些文件）。
+type Report struct {
+	Schema      string            `json:"schema" yaml:"schema"`
+	Command     string            `json:"command" yaml:"command"`
+	Error       string            `json:"error,omitempty" yaml:"error,omitempty"`
+	Diagnostics []diag.Diagnostic `json:"diagnostics,omitempty" yaml:"diagnostics,omitempty"`
+	Check       *CheckResult      `json:"check,omitempty" yaml:"check,omitempty"`
+	Build       *Result           `json:"build,omitempty" yaml:"build,omitempty"`
+}
+
+// SlideInfo 记录一页的排版结果：来源文件、字号、各语言的分栏数和是否截断。
+type SlideInfo struct {
+	No        int           `json:"no" yaml:"no"`
+	Paths     []string      `json:"paths" yaml:"paths"`
+	FontSize  int           `json:"font_size" yaml:"font_size"`
+	Columns   []SlideColumn `json:"columns" yaml:"columns"`
+	Truncated bool          `json:"truncated" yaml:"truncated"`
+	Page      int           `json:"page,omitempty" yaml:"page,omitempty"`
+	PageCount int           `json:"page_count,omitempty" yaml:"page_count,omitempty"`
+}
+
+type SlideColumn struct {
+	Lang  string `json:"lang" yaml:"lang"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+// ParseFormat 校验 --format 的取值，空值按 text 处理。
+func ParseFormat(v string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(v)); f {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON, FormatYAML:
+		return f, nil
+	}
+	return "", fmt.Errorf("--format 只支持 text、json、yaml，收到的是 %s", v)
+}
+
+// WriteReport 按 json 或 yaml 输出 r，自动填上格式版本。
+func WriteReport(w io.Writer, format string, r Report) error {
+	r.Schema = ReportSchema
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(r)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("不支持的输出格式：%s", format)
+}
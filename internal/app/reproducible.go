@@ -0,0 +1,45 @@
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reproducibleEpoch 是没设 SOURCE_DATE_EPOCH 时可复现模式使用的时间，也是 zip 能表示的最早时间。
+var reproducibleEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// buildClock 返回生成默认文件名用的时间和随机数源，以及写进 PPT 的时间（零值表示当前时间）。
+// 可复现模式下（--reproducible 或设置了 SOURCE_DATE_EPOCH）时间固定，
+// 文件名的随机部分改由数据源目录推出，同样的输入每次得到同样的文件。
+func buildClock(opts Options, cwd string) (time.Time, io.Reader, time.Time, error) {
+	epoch, hasEpoch := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if opts.Reproducible || hasEpoch {
+		stamp := reproducibleEpoch
+		if epoch = strings.TrimSpace(epoch); epoch != "" {
+			sec, err := strconv.ParseInt(epoch, 10, 64)
+			if err != nil {
+				return time.Time{}, nil, time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH 应该是 Unix 时间戳（秒），收到的是 %q", epoch)
+			}
+			stamp = time.Unix(sec, 0).UTC()
+		}
+		sum := sha256.Sum256([]byte(filepath.Clean(toAbsPath(opts.SourceDir, cwd))))
+		return stamp, bytes.NewReader(sum[:]), stamp, nil
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	rnd := opts.Rand
+	if rnd == nil {
+		rnd = rand.Reader
+	}
+	return now, rnd, time.Time{}, nil
+}
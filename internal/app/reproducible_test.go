@@ -0,0 +1,77 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_ReproducibleIsByteIdentical(t *testing.T) {
+	tmp := t.TempDir()
+	source := writeManyCards(t, tmp, 6)
+	outDir := filepath.Join(tmp, "out")
+	run := func(now time.Time, rand string, noCache bool) (string, []byte) {
+		t.Helper()
+		res, err := Run(Options{
+			SourceDir:    source,
+			OutputArg:    outDir,
+			CWD:          tmp,
+			Now:          now,
+			Rand:         bytes.NewBufferString(rand),
+			Reproducible: true,
+			NoCache:      noCache,
+			CacheDir:     filepath.Join(tmp, "cache"),
+		})
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+		data, err := os.ReadFile(res.OutputPath)
+		if err != nil {
+			t.Fatalf("read output: %v", err)
+		}
+		return res.OutputPath, data
+	}
+	firstPath, first := run(time.Date(2026, 2, 20, 19, 0, 0, 0, time.UTC), "ABCDEF", true)
+	secondPath, second := run(time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC), "QWERTY", false)
+	if firstPath != secondPath {
+		t.Fatalf("default name should be stable, got %s and %s", firstPath, secondPath)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("reproducible builds should be byte-identical")
+	}
+}
+
+func TestRun_SourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1767225600")
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+	res, err := Run(Options{SourceDir: source, OutputArg: filepath.Join(tmp, "out"), CWD: tmp, NoCache: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if filepath.Base(res.OutputPath)[:15] != "20260101_000000" {
+		t.Fatalf("default name should use SOURCE_DATE_EPOCH, got %s", res.OutputPath)
+	}
+	zr, err := zip.OpenReader(res.OutputPath)
+	if err != nil {
+		t.Fatalf("open output: %v", err)
+	}
+	defer zr.Close()
+	if core := readSlideXML(t, &zr.Reader, "docProps/core.xml"); !strings.Contains(core, "2026-01-01T00:00:00Z") {
+		t.Fatalf("core properties should use SOURCE_DATE_EPOCH: %s", core)
+	}
+	for _, f := range zr.File {
+		if !f.Modified.Equal(time.Unix(1767225600, 0)) {
+			t.Fatalf("%s has modification time %v", f.Name, f.Modified)
+		}
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err := Run(Options{SourceDir: source, OutputArg: filepath.Join(tmp, "out"), CWD: tmp}); err == nil || !strings.Contains(err.Error(), "SOURCE_DATE_EPOCH") {
+		t.Fatalf("a malformed SOURCE_DATE_EPOCH should be rejected, got %v", err)
+	}
+}
@@ -1,14 +1,18 @@
 package app
 
 import (
-	"crypto/rand"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
 	"syl-md2ppt/internal/discovery"
 	"syl-md2ppt/internal/output"
 	"syl-md2ppt/internal/pptx"
@@ -22,14 +26,49 @@ type Options struct {
 	CWD        string
 	Now        time.Time
 	Rand       io.Reader
+	// Lang 只输出某一种语言（按 languages 的 name 匹配），整页单栏排版。
+	Lang string
+	// SplitLangs 为每种语言各输出一个 pptx。
+	SplitLangs bool
+	// TemplatePath 覆盖配置里的 template.path，相对路径以 CWD 为准。
+	TemplatePath string
+	// Strict 把告警当成错误（只限 strict.codes 列出的代码，没列时全部），这时不生成文件。
+	Strict bool
+	// NoCache 不读也不写排版缓存；CacheDir 为空时用用户缓存目录下的 syl-md2ppt。
+	NoCache  bool
+	CacheDir string
+	// Jobs 是同时排版的卡片数，0 表示按 CPU 核数。
+	Jobs int
+	// Stdout 是 --output - 时写 PPT 的地方，为空时用 os.Stdout。
+	Stdout io.Writer
+	// Reproducible 让同样的输入每次生成字节完全相同的文件，见 buildClock。
+	Reproducible bool
+	// Overwrite 是输出文件已存在时的策略（OverwriteAlways/Never/Ask），为空时按 always；
+	// ask 时用 Confirm 询问。Backup 在替换前把旧文件保留为 .bak。
+	Overwrite string
+	Confirm   func(path string) (bool, error)
+	Backup    bool
 }
 
 type Result struct {
-	OutputPath   string
-	SlideCount   int
-	WarningCount int
-	Warnings     []string
-	ConfigSource string
+	OutputPath   string `json:"output_path" yaml:"output_path"`
+	SlideCount   int    `json:"slide_count" yaml:"slide_count"`
+	WarningCount int    `json:"warning_count" yaml:"warning_count"`
+	// Warnings 是命令行显示的告警文字，Diagnostics 是同样内容的结构化版本。
+	Warnings     []string          `json:"-" yaml:"-"`
+	Diagnostics  []diag.Diagnostic `json:"diagnostics" yaml:"diagnostics"`
+	ConfigSource string            `json:"config_source" yaml:"config_source"`
+	Outputs      []OutputFile      `json:"outputs" yaml:"outputs"`
+	// Cache 是排版缓存的命中情况，关掉缓存时为空。
+	Cache *CacheStats `json:"cache,omitempty" yaml:"cache,omitempty"`
+}
+
+// OutputFile 描述一次运行写出的一个 pptx。Lang 为空表示多语言合排。
+type OutputFile struct {
+	Lang       string      `json:"lang,omitempty" yaml:"lang,omitempty"`
+	Path       string      `json:"path" yaml:"path"`
+	SlideCount int         `json:"slide_count" yaml:"slide_count"`
+	Slides     []SlideInfo `json:"slides" yaml:"slides"`
 }
 
 func Run(opts Options) (Result, error) {
@@ -46,24 +85,39 @@ func Run(opts Options) (Result, error) {
 		cwd = wd
 	}
 
-	now := opts.Now
-	if now.IsZero() {
-		now = time.Now()
+	now, rnd, stamp, err := buildClock(opts, cwd)
+	if err != nil {
+		return Result{}, err
+	}
+
+	loaded, err := loadConfig(opts, cwd)
+	if err != nil {
+		return Result{}, err
+	}
+	cfg, cfgSrc := loaded.Config, loaded.Source
+
+	if _, err := render.LoadMetrics(cfg); err != nil {
+		return Result{}, err
 	}
-	rnd := opts.Rand
-	if rnd == nil {
-		rnd = rand.Reader
+	if err := pptx.CheckTemplate(cfg.Template.Path, cfg.Template.Layout); err != nil {
+		return Result{}, err
 	}
 
-	cfg, cfgSrc, err := config.Load(opts.ConfigPath, cwd)
+	selections, err := selectLanguages(cfg, opts)
 	if err != nil {
 		return Result{}, err
 	}
 
-	outPath, err := output.ResolveOutputPath(opts.OutputArg, cwd, now, rnd)
+	nameCfg := cfg.Output.DefaultName
+	names, err := output.NewNameData(opts.OutputArg, nameCfg, toAbsPath(opts.SourceDir, cwd), now, rnd)
 	if err != nil {
 		return Result{}, err
 	}
+	// 模板里有 {lang} 时每种语言的文件名已经不同，不用再加后缀。
+	langInName := output.UsesDefaultName(opts.OutputArg) && strings.Contains(nameCfg.Template, "{lang}")
+	if opts.OutputArg == output.Stdout && len(selections) > 1 {
+		return Result{}, fmt.Errorf("--output - 只能输出一个文件，不能和 --split-langs 一起用")
+	}
 
 	pairs, discoverWarn, err := discovery.Discover(opts.SourceDir, cfg, discovery.DiscoverOptions{
 		FailOnConflict: true,
@@ -72,61 +126,313 @@ func Run(opts Options) (Result, error) {
 		return Result{}, err
 	}
 	if len(pairs) == 0 {
-		return Result{}, fmt.Errorf("没找到可用的双语 Markdown 文件，请检查 EN/CN 目录和命名规则")
+		return Result{}, fmt.Errorf("%w，请检查 %s 目录和命名规则", diag.ErrNoSources, languageDirs(cfg))
+	}
+
+	cardCfgs, err := cardConfigs(loaded, opts.SourceDir, pairs)
+	if err != nil {
+		return Result{}, err
 	}
 
-	slides := make([]render.Slide, 0, len(pairs))
-	warnings := make([]string, 0)
-	warnings = append(warnings, dedupeStrings(discoverWarn)...)
-	for i, pair := range pairs {
-		enRaw, err := os.ReadFile(pair.ENPath)
+	warnings := dedupeDiagnostics(append(configWarnings(loaded), discoverWarn...))
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	rc := newRenderCache(opts, cfg)
+	outputs := make([]OutputFile, 0, len(selections))
+	decks := make([]pptx.Deck, 0, len(selections))
+	for _, sel := range selections {
+		deck, infos, ws, err := renderSlides(cfg, cardCfgs, pairs, sel, rc, jobs)
 		if err != nil {
-			return Result{}, fmt.Errorf("读取英文文件失败（%s）：%w", pair.ENPath, err)
+			return Result{}, err
 		}
-		cnRaw, err := os.ReadFile(pair.CNPath)
+		warnings = append(warnings, ws...)
+
+		lang := ""
+		if len(sel) == 1 && len(cfg.Languages) > 1 {
+			lang = cfg.Languages[sel[0]].Name
+		}
+		names.Lang, names.Count = selectionName(cfg, sel), len(deck.Slides)
+		path, err := output.ResolveOutputPath(opts.OutputArg, cwd, nameCfg, names)
 		if err != nil {
-			return Result{}, fmt.Errorf("读取中文文件失败（%s）：%w", pair.CNPath, err)
+			return Result{}, err
+		}
+		if opts.SplitLangs && !langInName {
+			path = withLangSuffix(path, lang)
 		}
-		slide, ws := render.BuildSlide(string(enRaw), string(cnRaw), cfg)
-		for _, w := range ws {
-			warnPath := pair.ENPath
-			if w.Code == "truncate_cn" {
-				warnPath = pair.CNPath
+		if opts.Overwrite == OverwriteNever && path != output.Stdout {
+			path = freePath(path)
+		}
+		outputs = append(outputs, OutputFile{Lang: lang, Path: path, SlideCount: len(deck.Slides), Slides: infos})
+		deck.Modified = stamp
+		decks = append(decks, deck)
+	}
+
+	res := Result{
+		OutputPath:   outputs[0].Path,
+		SlideCount:   outputs[0].SlideCount,
+		WarningCount: len(warnings),
+		Diagnostics:  warnings,
+		ConfigSource: cfgSrc,
+		Outputs:      outputs,
+	}
+	if rc != nil {
+		res.Cache = &rc.stats
+		rc.prune()
+	}
+	var strictErr error
+	if opts.Strict {
+		strictErr = applyStrict(warnings, cfg.Strict.Codes)
+	}
+	res.Warnings = warningTexts(warnings)
+	if strictErr != nil {
+		return res, strictErr
+	}
+	for i, out := range outputs {
+		if out.Path == output.Stdout {
+			if err := pptx.WriteDeck(stdoutOf(opts), decks[i]); err != nil {
+				return Result{}, err
 			}
-			warnings = append(warnings, fmt.Sprintf("%s - %s 内容有点多，部分截断", formatSlideNo(i+1), warnPath))
+			continue
+		}
+		if err := confirmOverwrite(opts, out.Path); err != nil {
+			return Result{}, err
+		}
+		if opts.Backup {
+			if err := backupFile(out.Path); err != nil {
+				return Result{}, err
+			}
+		}
+		if err := pptx.Write(out.Path, decks[i]); err != nil {
+			return Result{}, err
 		}
-		slides = append(slides, slide)
 	}
+	return res, nil
+}
+
+// selectionName 是文件名模板里的 {lang}：各语言名用 - 连起来。
+func selectionName(cfg *config.Config, sel []int) string {
+	names := make([]string, len(sel))
+	for i, li := range sel {
+		names[i] = cfg.Languages[li].Name
+	}
+	return strings.Join(names, "-")
+}
+
+func stdoutOf(opts Options) io.Writer {
+	if opts.Stdout != nil {
+		return opts.Stdout
+	}
+	return os.Stdout
+}
 
-	deck := pptx.Deck{
-		SlideWidthIn:  cfg.Layout.Slide.Width,
-		SlideHeightIn: cfg.Layout.Slide.Height,
-		LeftRatio:     cfg.Layout.Columns.LeftRatio,
-		GapIn:         cfg.Layout.Columns.Gap,
-		PaddingIn:     cfg.Layout.Columns.Padding,
-		FontFamily:    cfg.Layout.Typography.FontFamily,
+// selectLanguages 返回每个输出文件要排版的语言下标。
+func selectLanguages(cfg *config.Config, opts Options) ([][]int, error) {
+	lang := strings.TrimSpace(opts.Lang)
+	if lang != "" && opts.SplitLangs {
+		return nil, fmt.Errorf("--lang 和 --split-langs 只能二选一")
+	}
+	if opts.SplitLangs {
+		out := make([][]int, len(cfg.Languages))
+		for i := range cfg.Languages {
+			out[i] = []int{i}
+		}
+		return out, nil
+	}
+	if lang != "" {
+		for i, l := range cfg.Languages {
+			if strings.EqualFold(l.Name, lang) {
+				return [][]int{{i}}, nil
+			}
+		}
+		return nil, fmt.Errorf("没找到语言 %s，可选：%s", lang, languageNames(cfg))
+	}
+	all := make([]int, len(cfg.Languages))
+	for i := range all {
+		all[i] = i
+	}
+	return [][]int{all}, nil
+}
+
+// renderSlides 排版所有卡片；rc 不为 nil 时没改过的卡片直接用缓存，连同生成好的页面 XML。
+// 卡片由 jobs 个 goroutine 并行读取、排版，结果仍按配对顺序拼接，页码和告警顺序与串行时一致。
+// 每张卡片按 cardCfgs 里叠加了目录配置的配置排版，整份 PPT 的版面、样式取 cfg。
+func renderSlides(cfg *config.Config, cardCfgs []*config.Config, pairs []discovery.Pair, langIdx []int, rc *renderCache, jobs int) (pptx.Deck, []SlideInfo, []diag.Diagnostic, error) {
+	cards := renderCards(cardCfgs, pairs, langIdx, rc, jobs)
+	deck := newDeck(cfg, make([]render.Slide, 0, len(pairs)))
+	infos := make([]SlideInfo, 0, len(pairs))
+	warnings := make([]diag.Diagnostic, 0)
+	for n, pair := range pairs {
+		card := cards[n]
+		if card.err != nil {
+			return pptx.Deck{}, nil, nil, card.err
+		}
+		if rc != nil && card.hit {
+			rc.stats.Hits++
+		} else if rc != nil {
+			rc.stats.Misses++
+		}
+		for _, w := range card.entry.Diagnostics {
+			// render 给的页码从卡片的第一页算起，0 表示整张卡片。
+			w.Slide = len(deck.Slides) + max(w.Slide, 1)
+			w.Paths = []string{pair.Paths[langIdx[w.Column]]}
+			w.Group = pair.Group
+			w.Message = renderWarningText(w)
+			warnings = append(warnings, w)
+		}
+		paths := make([]string, len(langIdx))
+		for i, li := range langIdx {
+			paths[i] = pair.Paths[li]
+		}
+		for i, s := range card.entry.Slides {
+			infos = append(infos, slideInfo(len(deck.Slides)+1, paths, s))
+			deck.Slides = append(deck.Slides, s)
+			part := pptx.SlidePart{}
+			if i < len(card.entry.Parts) {
+				part = card.entry.Parts[i]
+			}
+			deck.Prerendered = append(deck.Prerendered, part)
+		}
+	}
+	return deck, infos, warnings, nil
+}
+
+type renderedCard struct {
+	entry cardEntry
+	hit   bool
+	err   error
+}
+
+// renderCards 用最多 jobs 个 goroutine 读取、排版各张卡片，结果按 pairs 的下标存放。
+func renderCards(cardCfgs []*config.Config, pairs []discovery.Pair, langIdx []int, rc *renderCache, jobs int) []renderedCard {
+	cards := make([]renderedCard, len(pairs))
+	jobs = min(max(jobs, 1), len(pairs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range next {
+				cards[n] = renderCard(cardCfgs[n], pairs[n], langIdx, rc)
+			}
+		}()
+	}
+	for n := range pairs {
+		next <- n
+	}
+	close(next)
+	wg.Wait()
+	return cards
+}
+
+func renderCard(cfg *config.Config, pair discovery.Pair, langIdx []int, rc *renderCache) renderedCard {
+	sources := make([]render.Source, len(langIdx))
+	for i, li := range langIdx {
+		lang := cfg.Languages[li]
+		raw, err := os.ReadFile(pair.Paths[li])
+		if err != nil {
+			return renderedCard{err: fmt.Errorf("读取 %s 文件失败（%s）：%w", lang.Name, pair.Paths[li], err)}
+		}
+		sources[i] = render.Source{Lang: lang.Name, Tag: lang.Lang, Raw: string(raw), Path: pair.Paths[li]}
+	}
+	var card renderedCard
+	key := ""
+	if rc != nil {
+		key = rc.key(cfg, sources)
+		card.entry, card.hit = rc.get(key)
+	}
+	if !card.hit {
+		card.entry.Slides, card.entry.Diagnostics = render.BuildSlide(sources, cfg)
+		if rc != nil {
+			rc.put(key, sources, &card.entry)
+		}
+	}
+	return card
+}
+
+func slideInfo(no int, paths []string, s render.Slide) SlideInfo {
+	cols := make([]SlideColumn, len(s.Columns))
+	for i, c := range s.Columns {
+		cols[i] = SlideColumn{Lang: c.Lang, Count: max(1, c.NumCol)}
+	}
+	info := SlideInfo{No: no, Paths: paths, FontSize: s.FontSize, Columns: cols, Truncated: s.HasTruncationBadge}
+	if s.PageCount > 1 {
+		info.Page, info.PageCount = s.Page, s.PageCount
+	}
+	return info
+}
+
+// warningTexts 返回命令行显示的告警；严格模式下已经升级成错误的不在其中。
+func warningTexts(in []diag.Diagnostic) []string {
+	out := make([]string, 0, len(in))
+	for _, w := range in {
+		if w.Severity != diag.SeverityError {
+			out = append(out, w.String())
+		}
+	}
+	return out
+}
+
+// loadConfig 按层合并配置，--template 作为命令行这一层；用了模板时页面尺寸默认跟模板一致。
+func loadConfig(opts Options, cwd string) (*config.Loaded, error) {
+	lo := config.LoadOptions{ConfigPath: opts.ConfigPath, CWD: cwd, TemplateSize: pptx.TemplateSlideSize}
+	if strings.TrimSpace(opts.TemplatePath) != "" {
+		lo.Flags = map[string]config.Flag{"template.path": {Name: "--template", Value: opts.TemplatePath}}
+	}
+	return config.Resolve(lo)
+}
+
+// cardConfigs 取各张卡片叠加目录配置后的配置，没有目录配置的卡片就是整份 PPT 的配置。
+func cardConfigs(loaded *config.Loaded, sourceDir string, pairs []discovery.Pair) ([]*config.Config, error) {
+	out := make([]*config.Config, len(pairs))
+	for i, p := range pairs {
+		c, err := loaded.ForCard(sourceDir, p.Paths)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+func newDeck(cfg *config.Config, slides []render.Slide) pptx.Deck {
+	return pptx.Deck{
+		SlideWidthIn:   cfg.Layout.Slide.Width,
+		SlideHeightIn:  cfg.Layout.Slide.Height,
+		LeftRatio:      cfg.Layout.Columns.LeftRatio,
+		GapIn:          cfg.Layout.Columns.Gap,
+		PaddingIn:      cfg.Layout.Columns.Padding,
+		FontFamily:     cfg.Layout.Typography.FontFamily,
+		TemplatePath:   cfg.Template.Path,
+		TemplateLayout: cfg.Template.Layout,
 		Styles: pptx.StylePalette{
-			BaseColor:    "1F2937",
-			StarColor:    sanitizeHex(cfg.Styles.Markers.Star.Color, "8A6D1D"),
-			DotColor:     sanitizeHex(cfg.Styles.Markers.Dot.Color, "1F2937"),
-			WarnColor:    sanitizeHex(cfg.Styles.Markers.Warn.Color, "9A3412"),
-			FormulaColor: sanitizeHex(cfg.Styles.InlineFormula.Color, "111827"),
-			FormulaFill:  sanitizeHex(cfg.Styles.InlineFormula.Highlight, "FFF176"),
+			BaseColor:        "1F2937",
+			StarColor:        sanitizeHex(cfg.Styles.Markers.Star.Color, "8A6D1D"),
+			DotColor:         sanitizeHex(cfg.Styles.Markers.Dot.Color, "1F2937"),
+			WarnColor:        sanitizeHex(cfg.Styles.Markers.Warn.Color, "9A3412"),
+			FormulaColor:     sanitizeHex(cfg.Styles.InlineFormula.Color, "111827"),
+			FormulaFill:      sanitizeHex(cfg.Styles.InlineFormula.Highlight, "FFF176"),
+			CodeFont:         cfg.Styles.InlineCode.Font,
+			CodeColor:        sanitizeHex(cfg.Styles.InlineCode.Color, "9F1239"),
+			CodeFill:         sanitizeHex(cfg.Styles.InlineCode.Highlight, "F3F4F6"),
+			TableHeaderFill:  sanitizeHex(cfg.Styles.Table.HeaderFill, "1F2937"),
+			TableHeaderColor: sanitizeHex(cfg.Styles.Table.HeaderColor, "FFFFFF"),
+			TableBandFill:    sanitizeHex(cfg.Styles.Table.BandFill, ""),
+			TableBorderColor: sanitizeHex(cfg.Styles.Table.BorderColor, "D1D5DB"),
 		},
 		Slides: slides,
 	}
+}
 
-	if err := pptx.Write(outPath, deck); err != nil {
-		return Result{}, err
+// withLangSuffix 把语言名插到扩展名前：deck.pptx -> deck_EN.pptx。
+func withLangSuffix(path, lang string) string {
+	if lang == "" {
+		return path
 	}
-
-	return Result{
-		OutputPath:   outPath,
-		SlideCount:   len(slides),
-		WarningCount: len(warnings),
-		Warnings:     warnings,
-		ConfigSource: cfgSrc,
-	}, nil
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + lang + ext
 }
 
 func sanitizeHex(v string, fallback string) string {
@@ -146,22 +452,47 @@ func sanitizeHex(v string, fallback string) string {
 	return v
 }
 
-func dedupeStrings(in []string) []string {
-	if len(in) == 0 {
-		return nil
-	}
+// configWarnings 是读配置时的告警，加上字体没有字宽表、只能估算的提醒。
+func configWarnings(loaded *config.Loaded) []diag.Diagnostic {
+	return append(slices.Clone(loaded.Warnings), render.FontWarnings(loaded.Config)...)
+}
+
+func dedupeDiagnostics(in []diag.Diagnostic) []diag.Diagnostic {
 	seen := make(map[string]struct{}, len(in))
-	out := make([]string, 0, len(in))
-	for _, s := range in {
-		if _, ok := seen[s]; ok {
+	out := make([]diag.Diagnostic, 0, len(in))
+	for _, d := range in {
+		key := d.Code + "\x00" + d.Message
+		if _, ok := seen[key]; ok {
 			continue
 		}
-		seen[s] = struct{}{}
-		out = append(out, s)
+		seen[key] = struct{}{}
+		out = append(out, d)
 	}
 	return out
 }
 
-func formatSlideNo(n int) string {
-	return fmt.Sprintf("[%3d]", n)
+func languageDirs(cfg *config.Config) string {
+	dirs := make([]string, 0, len(cfg.Languages))
+	for _, l := range cfg.Languages {
+		dirs = append(dirs, l.Dir)
+	}
+	return strings.Join(dirs, "/")
+}
+
+func languageNames(cfg *config.Config) string {
+	names := make([]string, 0, len(cfg.Languages))
+	for _, l := range cfg.Languages {
+		names = append(names, l.Name)
+	}
+	return strings.Join(names, "/")
+}
+
+func renderWarningText(w diag.Diagnostic) string {
+	switch w.Kind() {
+	case diag.KindOverflow:
+		return "内容有点多，超出页面"
+	case diag.KindTruncate:
+		return "内容有点多，部分截断"
+	}
+	return w.Message
 }
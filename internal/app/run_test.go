@@ -3,14 +3,20 @@ package app
 import (
 	"archive/zip"
 	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	"syl-md2ppt/internal/diag"
 )
 
 func TestRun_EndToEnd(t *testing.T) {
+	isolateUserDirs(t)
 	tmp := t.TempDir()
 	source := filepath.Join(tmp, "SPI")
 	enDir := filepath.Join(source, "EN", "D")
@@ -71,6 +77,7 @@ func TestRun_EndToEnd(t *testing.T) {
 }
 
 func TestRun_WarningContainsSlideNumber(t *testing.T) {
+	isolateUserDirs(t)
 	tmp := t.TempDir()
 	source := filepath.Join(tmp, "SPI")
 	enDir := filepath.Join(source, "EN", "D")
@@ -126,6 +133,13 @@ func TestRun_WarningContainsSlideNumber(t *testing.T) {
 	if !strings.HasSuffix(res.Warnings[0], "内容有点多，部分截断") {
 		t.Fatalf("warning should use concise truncate message, got: %s", res.Warnings[0])
 	}
+	if d := res.Diagnostics[0]; d.Slide != 1 || len(d.Paths) != 1 || d.Paths[0] != enAbs || d.Kind() != diag.KindTruncate || d.Severity != diag.SeverityWarning {
+		t.Fatalf("structured warning should carry code, slide and path, got: %#v", d)
+	}
+	slides := res.Outputs[0].Slides
+	if len(slides) != 1 || !slides[0].Truncated || slides[0].FontSize <= 0 || len(slides[0].Columns) != 2 || slides[0].Paths[1] != cnAbs {
+		t.Fatalf("unexpected slide info: %#v", slides)
+	}
 }
 
 func TestRun_FailsOnConflictGroup(t *testing.T) {
@@ -165,4 +179,347 @@ func TestRun_FailsOnConflictGroup(t *testing.T) {
 	if !strings.Contains(err.Error(), "配对冲突") {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if !errors.Is(err, diag.ErrConflict) {
+		t.Fatalf("conflict error should match diag.ErrConflict: %v", err)
+	}
+}
+
+func TestRun_SplitModeNumbersContinuationSlides(t *testing.T) {
+	isolateUserDirs(t)
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	enDir := filepath.Join(source, "EN", "D")
+	cnDir := filepath.Join(source, "CN", "D")
+	if err := os.MkdirAll(enDir, 0o755); err != nil {
+		t.Fatalf("mkdir en: %v", err)
+	}
+	if err := os.MkdirAll(cnDir, 0o755); err != nil {
+		t.Fatalf("mkdir cn: %v", err)
+	}
+	heavyEN := strings.Repeat("long english text\n", 400)
+	heavyCN := strings.Repeat("很长的中文内容\n", 400)
+	if err := os.WriteFile(filepath.Join(enDir, "1-002-Front.md"), []byte(heavyEN), 0o644); err != nil {
+		t.Fatalf("write en1: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cnDir, "1-002-Front.md"), []byte(heavyCN), 0o644); err != nil {
+		t.Fatalf("write cn1: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(enDir, "1-002-Back.md"), []byte("short"), 0o644); err != nil {
+		t.Fatalf("write en2: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cnDir, "1-002-Back.md"), []byte("短"), 0o644); err != nil {
+		t.Fatalf("write cn2: %v", err)
+	}
+	cfgPath := filepath.Join(tmp, "split.yaml")
+	if err := os.WriteFile(cfgPath, []byte("layout:\n  overflow: split\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	res, err := Run(Options{
+		SourceDir:  source,
+		OutputArg:  filepath.Join(tmp, "out"),
+		ConfigPath: cfgPath,
+		CWD:        tmp,
+		Now:        time.Date(2026, 2, 20, 19, 0, 0, 0, time.UTC),
+		Rand:       bytes.NewBufferString("ABCDEF"),
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if res.SlideCount < 3 {
+		t.Fatalf("expected front card to span several slides, got %d slides", res.SlideCount)
+	}
+	for _, w := range res.Warnings {
+		if strings.Contains(w, "截断") {
+			t.Fatalf("split mode should not truncate, got warning: %s", w)
+		}
+	}
+}
+
+// isolateUserDirs 把用户缓存目录、用户配置和 HOME 指到临时目录，Run 用默认缓存时不会写进开发者自己的目录。
+func isolateUserDirs(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, env := range []string{"HOME", "XDG_CACHE_HOME", "XDG_CONFIG_HOME"} {
+		t.Setenv(env, dir)
+	}
+}
+
+func writeBilingualSource(tb testing.TB, tmp string) string {
+	tb.Helper()
+	source := filepath.Join(tmp, "SPI")
+	writeCard(tb, source, "1-002-Front.md", "English front", "中文正面")
+	return source
+}
+
+// writeCard 在数据源的 EN/D 和 CN/D 下写一对同名卡片。
+func writeCard(tb testing.TB, source, name, en, cn string) {
+	tb.Helper()
+	for lang, text := range map[string]string{"EN": en, "CN": cn} {
+		dir := filepath.Join(source, lang, "D")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			tb.Fatalf("mkdir %s: %v", lang, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			tb.Fatalf("write %s: %v", lang, err)
+		}
+	}
+}
+
+func TestRun_SingleLanguageUsesFullWidth(t *testing.T) {
+	isolateUserDirs(t)
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+
+	res, err := Run(Options{
+		SourceDir: source,
+		OutputArg: filepath.Join(tmp, "en.pptx"),
+		CWD:       tmp,
+		Now:       time.Date(2026, 2, 20, 19, 0, 0, 0, time.UTC),
+		Rand:      bytes.NewBufferString("ABCDEF"),
+		Lang:      "en",
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(res.Outputs) != 1 || res.Outputs[0].Lang != "EN" {
+		t.Fatalf("expected one EN output, got %#v", res.Outputs)
+	}
+
+	zr, err := zip.OpenReader(res.OutputPath)
+	if err != nil {
+		t.Fatalf("open output pptx: %v", err)
+	}
+	defer zr.Close()
+	slide := readSlideXML(t, &zr.Reader, "ppt/slides/slide1.xml")
+	if !strings.Contains(slide, "English front") || strings.Contains(slide, "中文正面") {
+		t.Fatalf("expected English-only slide, got: %s", slide)
+	}
+	if strings.Count(slide, "<p:sp>") != 1 {
+		t.Fatalf("expected a single text box, got: %s", slide)
+	}
+}
+
+func TestRun_UnknownLanguage(t *testing.T) {
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+
+	_, err := Run(Options{
+		SourceDir: source,
+		CWD:       tmp,
+		Now:       time.Date(2026, 2, 20, 19, 0, 0, 0, time.UTC),
+		Rand:      bytes.NewBufferString("ABCDEF"),
+		Lang:      "FR",
+	})
+	if err == nil || !strings.Contains(err.Error(), "没找到语言 FR") {
+		t.Fatalf("expected unknown language error, got: %v", err)
+	}
+}
+
+func TestRun_SplitLangsWritesOneDeckPerLanguage(t *testing.T) {
+	isolateUserDirs(t)
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+
+	res, err := Run(Options{
+		SourceDir:  source,
+		OutputArg:  filepath.Join(tmp, "deck.pptx"),
+		CWD:        tmp,
+		Now:        time.Date(2026, 2, 20, 19, 0, 0, 0, time.UTC),
+		Rand:       bytes.NewBufferString("ABCDEF"),
+		SplitLangs: true,
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(res.Outputs) != 2 {
+		t.Fatalf("expected two outputs, got %#v", res.Outputs)
+	}
+	for _, want := range []string{"deck_EN.pptx", "deck_CN.pptx"} {
+		if _, err := os.Stat(filepath.Join(tmp, want)); err != nil {
+			t.Fatalf("expected %s to be written: %v", want, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "deck.pptx")); !os.IsNotExist(err) {
+		t.Fatalf("combined deck should not be written in split mode")
+	}
+}
+
+func TestRun_NameTemplateWithLang(t *testing.T) {
+	isolateUserDirs(t)
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+	cfgPath := filepath.Join(tmp, "name.yaml")
+	if err := os.WriteFile(cfgPath, []byte("output:\n  default_name:\n    template: \"{source}_{lang}_{count}\"\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	res, err := Run(Options{SourceDir: source, OutputArg: filepath.Join(tmp, "out"), ConfigPath: cfgPath, CWD: tmp, SplitLangs: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	for i, want := range []string{"SPI_EN_1.pptx", "SPI_CN_1.pptx"} {
+		if got := filepath.Base(res.Outputs[i].Path); got != want {
+			t.Fatalf("output %d: want %s, got %s", i, want, got)
+		}
+	}
+}
+
+func readSlideXML(t *testing.T, zr *zip.Reader, name string) string {
+	t.Helper()
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		defer rc.Close()
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(rc); err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return buf.String()
+	}
+	t.Fatalf("%s not found", name)
+	return ""
+}
+
+func TestRun_StrictFailsOnMatchingWarnings(t *testing.T) {
+	isolateUserDirs(t)
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	enDir := filepath.Join(source, "EN", "D")
+	cnDir := filepath.Join(source, "CN", "D")
+	for _, dir := range []string{enDir, cnDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(enDir, "1-002-Front.md"): strings.Repeat("long english text ", 500),
+		filepath.Join(cnDir, "1-002-Front.md"): "短",
+		filepath.Join(enDir, "readme.md"):      "no number",
+	}
+	for path, body := range files {
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	run := func(cfg string) (Result, string, error) {
+		cfgPath := filepath.Join(tmp, "strict.yaml")
+		if err := os.WriteFile(cfgPath, []byte("filename:\n  ignore_unmatched: true\n"+cfg), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		out := filepath.Join(tmp, "deck.pptx")
+		_ = os.Remove(out)
+		res, err := Run(Options{SourceDir: source, OutputArg: out, ConfigPath: cfgPath, CWD: tmp, Strict: true})
+		return res, out, err
+	}
+
+	res, out, err := run("strict:\n  codes: [\"truncate_*\"]\n")
+	if !errors.Is(err, diag.ErrStrict) {
+		t.Fatalf("expected strict error, got %v", err)
+	}
+	if _, statErr := os.Stat(out); !os.IsNotExist(statErr) {
+		t.Fatalf("strict failure should not write the deck")
+	}
+	failed := diag.Diagnostics(err)
+	if len(failed) != 1 || failed[0].Code != "truncate_en" || !strings.Contains(err.Error(), "truncate_en（1 条）") {
+		t.Fatalf("expected one grouped truncate_en failure, got %v", err)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0], "readme.md") {
+		t.Fatalf("unmatched warning should stay a warning, got %v", res.Warnings)
+	}
+
+	if _, _, err := run("strict:\n  codes: [conflict]\n"); err != nil {
+		t.Fatalf("warnings outside strict.codes should not fail, got %v", err)
+	}
+	if _, out, err := run(""); !errors.Is(err, diag.ErrStrict) || len(diag.Diagnostics(err)) != 2 {
+		t.Fatalf("empty strict.codes should fail on every warning, got %v", err)
+	} else if _, statErr := os.Stat(out); !os.IsNotExist(statErr) {
+		t.Fatalf("strict failure should not write the deck")
+	}
+}
+
+// writeManyCards 在 writeBilingualSource 的基础上再写 n 对长短不一的卡片，部分会截断、有格式告警。
+func writeManyCards(tb testing.TB, dir string, n int) string {
+	tb.Helper()
+	source := writeBilingualSource(tb, dir)
+	for i := 1; i <= n; i++ {
+		en := strings.Repeat("**Term** explains a *long* sentence with `code` and $x^2$.\n", 5+(i*7)%60)
+		cn := strings.Repeat("这是一段比较长的中文说明，带 **重点**。\n", 5+(i*11)%60)
+		if i%5 == 0 {
+			en += "unbalanced **bold\n"
+		}
+		writeCard(tb, source, fmt.Sprintf("0-%03d-Front.md", i), en, cn)
+	}
+	return source
+}
+
+func TestRun_ParallelJobsKeepOrder(t *testing.T) {
+	tmp := t.TempDir()
+	source := writeManyCards(t, tmp, 24)
+	run := func(name string, jobs int) Result {
+		t.Helper()
+		res, err := Run(Options{SourceDir: source, OutputArg: filepath.Join(tmp, name), CWD: tmp, Jobs: jobs, NoCache: true})
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+		return res
+	}
+	serial := run("serial.pptx", 1)
+	parallel := run("parallel.pptx", 8)
+	if len(serial.Warnings) == 0 {
+		t.Fatalf("fixture should produce warnings")
+	}
+	if !reflect.DeepEqual(serial.Diagnostics, parallel.Diagnostics) {
+		t.Fatalf("warning order differs:\n%v\n---\n%v", serial.Warnings, parallel.Warnings)
+	}
+	if !reflect.DeepEqual(serial.Outputs[0].Slides, parallel.Outputs[0].Slides) {
+		t.Fatalf("slide order differs")
+	}
+}
+
+func BenchmarkRun(b *testing.B) {
+	tmp := b.TempDir()
+	source := writeManyCards(b, tmp, 48)
+	for _, jobs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := Run(Options{SourceDir: source, OutputArg: filepath.Join(tmp, "bench.pptx"), CWD: tmp, Jobs: jobs, NoCache: true})
+				if err != nil {
+					b.Fatalf("Run returned error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_DirectoryConfigAppliesToCardsBeneath(t *testing.T) {
+	isolateUserDirs(t)
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	for _, dir := range []string{"A", "B"} {
+		for _, lang := range []string{"EN", "CN"} {
+			if err := os.MkdirAll(filepath.Join(source, lang, dir), 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(source, lang, dir, "0-001-Front.md"), []byte(lang+" short"), 0o644); err != nil {
+				t.Fatalf("write card: %v", err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(source, "EN", "B", "syl-md2ppt.yaml"), []byte("layout:\n  typography:\n    base_size: 14\n"), 0o644); err != nil {
+		t.Fatalf("write dir config: %v", err)
+	}
+
+	res, err := Run(Options{SourceDir: source, OutputArg: filepath.Join(tmp, "out.pptx"), CWD: tmp, CacheDir: filepath.Join(tmp, "cache")})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if slides := res.Outputs[0].Slides; len(slides) != 2 || slides[0].FontSize != 20 || slides[1].FontSize != 14 {
+		t.Fatalf("directory config should only apply to cards beneath it, got %+v", res.Outputs[0].Slides)
+	}
 }
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"syl-md2ppt/internal/diag"
+)
+
+// applyStrict 把命中 codes 的告警升级成错误（codes 为空时全部），
+// 返回按代码分组的报告；没有命中时返回 nil。
+func applyStrict(diags []diag.Diagnostic, codes []string) error {
+	failed := make([]diag.Diagnostic, 0)
+	groups := make([]string, 0)
+	byCode := make(map[string][]string)
+	for i := range diags {
+		if !strictMatch(diags[i].Code, codes) {
+			continue
+		}
+		diags[i].Severity = diag.SeverityError
+		failed = append(failed, diags[i])
+		code := diags[i].Code
+		if _, ok := byCode[code]; !ok {
+			groups = append(groups, code)
+		}
+		byCode[code] = append(byCode[code], diags[i].String())
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s，%d 条按错误处理：", diag.ErrStrict, len(failed))
+	for _, code := range groups {
+		fmt.Fprintf(&b, "\n%s（%d 条）", code, len(byCode[code]))
+		for _, line := range byCode[code] {
+			b.WriteString("\n  " + line)
+		}
+	}
+	return &diag.Error{Err: diag.ErrStrict, Message: b.String(), Diagnostics: failed}
+}
+
+func strictMatch(code string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, code); ok {
+			return true
+		}
+	}
+	return false
+}
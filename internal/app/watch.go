@@ -0,0 +1,184 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
+	"syl-md2ppt/internal/discovery"
+	"syl-md2ppt/internal/output"
+)
+
+// WatchOptions 控制 build --watch 的轮询间隔和防抖时间。
+type WatchOptions struct {
+	Interval time.Duration
+	Debounce time.Duration
+}
+
+// WatchEvent 是一次重新生成的结果。Added、Resolved 是和上一次相比新出现、已消失的告警。
+type WatchEvent struct {
+	Result   Result
+	Err      error
+	Added    []string
+	Resolved []string
+}
+
+// Watch 先生成一次，之后轮询数据源目录和配置文件，改动停下来 Debounce 之后重新配对、生成，
+// 每次的结果交给 onBuild。ctx 取消时返回。文件名里的时间和随机码只在开始时取一次，
+// 之后每次覆盖同一个文件（模板里有 {count} 时页数变了才会换名）。
+func Watch(ctx context.Context, opts Options, wo WatchOptions, onBuild func(WatchEvent)) error {
+	if wo.Interval <= 0 {
+		wo.Interval = 500 * time.Millisecond
+	}
+	if wo.Debounce <= 0 {
+		wo.Debounce = 300 * time.Millisecond
+	}
+	cwd := opts.CWD
+	if cwd == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		cwd = wd
+	}
+	opts.CWD = cwd
+	if opts.OutputArg == output.Stdout {
+		return fmt.Errorf("--watch 要反复写同一个文件，不能输出到标准输出")
+	}
+	now, rnd, _, err := buildClock(opts, cwd)
+	if err != nil {
+		return err
+	}
+	// 随机码最长 32 位；每次生成都从同一段随机数里取，文件名保持不变。
+	seed, err := io.ReadAll(io.LimitReader(rnd, 32))
+	if err != nil {
+		return fmt.Errorf("读取随机数失败：%w", err)
+	}
+	opts.Now = now
+	if opts.SplitLangs && opts.Overwrite != "" && opts.Overwrite != OverwriteAlways {
+		return fmt.Errorf("--watch 和 --split-langs 一起用时只支持 --overwrite=always")
+	}
+
+	roots := []string{toAbsPath(opts.SourceDir, cwd)}
+	if opts.ConfigPath != "" {
+		roots = append(roots, toAbsPath(opts.ConfigPath, cwd))
+	} else {
+		roots = append(roots, filepath.Join(cwd, config.DirConfigName))
+	}
+	if user := config.UserConfigPath(); user != "" {
+		roots = append(roots, user)
+	}
+
+	var last []string
+	build := func() {
+		o := opts
+		o.Rand = bytes.NewReader(seed)
+		res, err := Run(o)
+		if err != nil && !errors.Is(err, diag.ErrStrict) {
+			// 这次没生成出来，告警沿用上一次的，等下次改动再比较。
+			onBuild(WatchEvent{Err: err})
+			return
+		}
+		if err == nil && len(res.Outputs) == 1 {
+			// 文件名只在第一次按 --overwrite 挑选或询问，旧文件也只备份这一次，之后一直覆盖同一个文件。
+			opts.OutputArg = res.Outputs[0].Path
+			opts.Overwrite, opts.Backup = OverwriteAlways, false
+		}
+		ev := WatchEvent{Result: res, Err: err}
+		ev.Added = missingFrom(res.Warnings, last)
+		ev.Resolved = missingFrom(last, res.Warnings)
+		last = res.Warnings
+		onBuild(ev)
+	}
+
+	build()
+	seen := snapshotFiles(roots)
+	ticker := time.NewTicker(wo.Interval)
+	defer ticker.Stop()
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case t := <-ticker.C:
+			cur := snapshotFiles(roots)
+			if !sameSnapshot(seen, cur) {
+				seen, changedAt = cur, t
+				continue
+			}
+			if !changedAt.IsZero() && t.Sub(changedAt) >= wo.Debounce {
+				changedAt = time.Time{}
+				build()
+			}
+		}
+	}
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshotFiles 记录 roots 下所有文件的大小和修改时间；跳过隐藏文件（编辑器临时文件、写到一半的输出）和 .pptx。
+func snapshotFiles(roots []string) map[string]fileStamp {
+	out := make(map[string]fileStamp)
+	for _, root := range roots {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := d.Name()
+			// 隐藏文件不看，但忽略规则改了要重新配对。
+			if path != root && strings.HasPrefix(name, ".") && name != discovery.IgnoreFile {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || strings.EqualFold(filepath.Ext(name), ".pptx") {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				out[path] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+			}
+			return nil
+		})
+	}
+	return out
+}
+
+func sameSnapshot(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w.size != v.size || !w.modTime.Equal(v.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// missingFrom 返回 a 里有、b 里没有的项，保持 a 的顺序。
+func missingFrom(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, s := range b {
+		in[s] = struct{}{}
+	}
+	out := make([]string, 0)
+	for _, s := range a {
+		if _, ok := in[s]; !ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch_RebuildsAndReportsChangedWarnings(t *testing.T) {
+	isolateUserDirs(t)
+	tmp := t.TempDir()
+	source := writeBilingualSource(t, tmp)
+	out := filepath.Join(tmp, "out", "deck.pptx")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan WatchEvent, 8)
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, Options{SourceDir: source, OutputArg: out, CWD: tmp}, WatchOptions{
+			Interval: 10 * time.Millisecond,
+			Debounce: 30 * time.Millisecond,
+		}, func(ev WatchEvent) { events <- ev })
+	}()
+	next := func() WatchEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for a rebuild")
+		}
+		return WatchEvent{}
+	}
+
+	first := next()
+	if first.Err != nil || len(first.Added) != 0 {
+		t.Fatalf("unexpected first build: %#v", first)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Fatalf("first build should write %s: %v", out, err)
+	}
+
+	card := filepath.Join(source, "EN", "D", "1-002-Front.md")
+	if err := os.WriteFile(card, []byte(strings.Repeat("long english text ", 500)), 0o644); err != nil {
+		t.Fatalf("write card: %v", err)
+	}
+	second := next()
+	if second.Err != nil || len(second.Added) != 1 || !strings.Contains(second.Added[0], card) {
+		t.Fatalf("expected one new truncate warning, got %#v", second)
+	}
+	if second.Result.OutputPath != out {
+		t.Fatalf("rebuild should overwrite the same file, got %s", second.Result.OutputPath)
+	}
+
+	if err := os.WriteFile(card, []byte("short again"), 0o644); err != nil {
+		t.Fatalf("write card: %v", err)
+	}
+	third := next()
+	if len(third.Added) != 0 || len(third.Resolved) != 1 {
+		t.Fatalf("expected the warning to be resolved, got %#v", third)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(out))
+	if len(entries) != 1 {
+		t.Fatalf("atomic writes should not leave temp files behind: %v", entries)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Watch returned error: %v", err)
+	}
+}
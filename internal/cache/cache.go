@@ -0,0 +1,132 @@
+// Package cache 是按内容哈希存取的本地缓存，build 用它跳过没改过的卡片。
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pruneInterval 是两次清理之间至少隔的时间，免得每次生成都扫一遍缓存目录。
+const pruneInterval = 24 * time.Hour
+
+// Store 把值用 gob 编码后存成 <dir>/<key 前两位>/<key>.gob。
+type Store struct {
+	dir string
+}
+
+// DefaultDir 返回默认的缓存目录，如 ~/.cache/syl-md2ppt。
+func DefaultDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("找不到缓存目录：%w", err)
+	}
+	return filepath.Join(base, "syl-md2ppt"), nil
+}
+
+func Open(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) Dir() string { return s.dir }
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key+".gob")
+}
+
+// Get 读出 key 对应的值。没有、读不了或格式对不上都当作没命中。
+func (s *Store) Get(key string, v any) bool {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return false
+	}
+	if gob.NewDecoder(bytes.NewReader(data)).Decode(v) != nil {
+		return false
+	}
+	// 命中时更新修改时间，Prune 按它判断条目多久没用过。
+	now := time.Now()
+	_ = os.Chtimes(s.path(key), now, now)
+	return true
+}
+
+// Put 写入 key 对应的值；先写临时文件再改名，并发运行时不会读到写了一半的文件。
+func (s *Store) Put(key string, v any) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Clean 删除整个缓存目录，目录不存在时什么也不做。
+func (s *Store) Clean() error {
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("清理缓存失败（%s）：%w", s.dir, err)
+	}
+	return nil
+}
+
+// Prune 删除超过 maxAge 没用过的条目和残留的临时文件。距上次清理不到 pruneInterval 时什么也不做。
+// 程序升级或重新编译后旧条目不会再命中，靠这里把它们清掉。
+func (s *Store) Prune(maxAge time.Duration) error {
+	marker := filepath.Join(s.dir, "last-prune")
+	now := time.Now()
+	if st, err := os.Stat(marker); err == nil && now.Sub(st.ModTime()) < pruneInterval {
+		return nil
+	}
+	shards, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, shard := range shards {
+		if !shard.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.dir, shard.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !strings.HasSuffix(e.Name(), ".gob") && !strings.HasSuffix(e.Name(), ".tmp") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || now.Sub(info.ModTime()) < maxAge {
+				continue
+			}
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+		// 空了的分片目录顺手删掉；不空时 Remove 会失败，忽略即可。
+		os.Remove(dir)
+	}
+	return os.WriteFile(marker, nil, 0o644)
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type entry struct {
+	Name  string
+	Lines []int
+}
+
+const testKey = "ab0123456789"
+
+func TestStore_PutGetRoundTrip(t *testing.T) {
+	s := Open(t.TempDir())
+	var got entry
+	if s.Get(testKey, &got) {
+		t.Fatal("empty store should miss")
+	}
+	want := entry{Name: "card", Lines: []int{1, 2, 3}}
+	if err := s.Put(testKey, want); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	if !s.Get(testKey, &got) || got.Name != want.Name || len(got.Lines) != 3 || got.Lines[2] != 3 {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir(), "ab", testKey+".gob")); err != nil {
+		t.Fatalf("entry should be sharded by the key prefix: %v", err)
+	}
+}
+
+func TestStore_GetTreatsCorruptEntriesAsMiss(t *testing.T) {
+	s := Open(t.TempDir())
+	path := filepath.Join(s.Dir(), "ab", testKey+".gob")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("not gob"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var got entry
+	if s.Get(testKey, &got) {
+		t.Fatal("corrupt entry should miss")
+	}
+	if err := s.Put(testKey, entry{Name: "fresh"}); err != nil {
+		t.Fatalf("Put should overwrite a corrupt entry: %v", err)
+	}
+	if !s.Get(testKey, &got) || got.Name != "fresh" {
+		t.Fatalf("expected the rewritten entry, got %+v", got)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	const maxAge = 30 * 24 * time.Hour
+	s := Open(t.TempDir())
+	for _, key := range []string{"aa01", "bb01", "bb02"} {
+		if err := s.Put(key, entry{Name: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	age := func(key string, d time.Duration) {
+		t.Helper()
+		old := time.Now().Add(-d)
+		if err := os.Chtimes(s.path(key), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exists := func(key string) bool {
+		_, err := os.Stat(s.path(key))
+		return err == nil
+	}
+	age("aa01", 31*24*time.Hour)
+	age("bb01", 31*24*time.Hour)
+	age("bb02", 29*24*time.Hour)
+
+	// 命中会刷新修改时间，常用的条目不会被清掉。
+	var got entry
+	if !s.Get("bb01", &got) {
+		t.Fatal("bb01 should hit")
+	}
+	if err := s.Prune(maxAge); err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if exists("aa01") || !exists("bb01") || !exists("bb02") {
+		t.Fatalf("only the entry unused for 30 days should go: aa01=%v bb01=%v bb02=%v", exists("aa01"), exists("bb01"), exists("bb02"))
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir(), "aa")); !os.IsNotExist(err) {
+		t.Fatalf("empty shard should be removed, stat err: %v", err)
+	}
+
+	// 距上次清理不到一天时不再扫描。
+	age("bb02", 31*24*time.Hour)
+	if err := s.Prune(maxAge); err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if !exists("bb02") {
+		t.Fatal("a second prune within 24h should do nothing")
+	}
+	old := time.Now().Add(-25 * time.Hour)
+	if err := os.Chtimes(filepath.Join(s.Dir(), "last-prune"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Prune(maxAge); err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if exists("bb02") || !exists("bb01") {
+		t.Fatalf("prune should run again after 24h: bb01=%v bb02=%v", exists("bb01"), exists("bb02"))
+	}
+}
+
+func TestStore_PruneMissingDir(t *testing.T) {
+	if err := Open(filepath.Join(t.TempDir(), "none")).Prune(time.Hour); err != nil {
+		t.Fatalf("pruning a missing cache should be a no-op, got %v", err)
+	}
+}
@@ -1,10 +1,33 @@
 package config
 
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var placeholderRe = regexp.MustCompile(`\{([a-z]*)\}`)
+
 type Config struct {
-	Filename FilenameConfig `yaml:"filename"`
-	Layout   LayoutConfig   `yaml:"layout"`
-	Styles   StylesConfig   `yaml:"styles"`
-	Output   OutputConfig   `yaml:"output"`
+	Languages []LanguageConfig `yaml:"languages"`
+	Filename  FilenameConfig   `yaml:"filename"`
+	Layout    LayoutConfig     `yaml:"layout"`
+	Styles    StylesConfig     `yaml:"styles"`
+	Notes     NotesConfig      `yaml:"notes"`
+	Template  TemplateConfig   `yaml:"template"`
+	Output    OutputConfig     `yaml:"output"`
+	Strict    StrictConfig     `yaml:"strict"`
+}
+
+// LanguageConfig 描述一种语言：数据源子目录、OOXML 语言标签和栏位顺序。
+type LanguageConfig struct {
+	Name  string `yaml:"name"`
+	Dir   string `yaml:"dir"`
+	Lang  string `yaml:"lang"`
+	Order int    `yaml:"order"`
 }
 
 type FilenameConfig struct {
@@ -15,8 +38,16 @@ type LayoutConfig struct {
 	Slide      SlideConfig      `yaml:"slide"`
 	Columns    ColumnsConfig    `yaml:"columns"`
 	Typography TypographyConfig `yaml:"typography"`
+	Overflow   string           `yaml:"overflow"`
 }
 
+// 内容放不下时的处理方式。
+const (
+	OverflowTruncate = "truncate"
+	OverflowShrink   = "shrink"
+	OverflowSplit    = "split"
+)
+
 type SlideConfig struct {
 	Width  float64 `yaml:"width"`
 	Height float64 `yaml:"height"`
@@ -30,15 +61,26 @@ type ColumnsConfig struct {
 }
 
 type TypographyConfig struct {
-	FontFamily  string  `yaml:"font_family"`
-	BaseSize    int     `yaml:"base_size"`
-	MinSize     int     `yaml:"min_size"`
-	LineSpacing float64 `yaml:"line_spacing"`
+	FontFamily  string          `yaml:"font_family"`
+	FontFiles   FontFilesConfig `yaml:"font_files"`
+	BaseSize    int             `yaml:"base_size"`
+	MinSize     int             `yaml:"min_size"`
+	LineSpacing float64         `yaml:"line_spacing"`
+}
+
+// 用于测量字宽的字体文件，相对路径以配置文件所在目录为准。
+type FontFilesConfig struct {
+	Regular    string `yaml:"regular"`
+	Bold       string `yaml:"bold"`
+	Italic     string `yaml:"italic"`
+	BoldItalic string `yaml:"bold_italic"`
 }
 
 type StylesConfig struct {
 	Markers       MarkerSetConfig    `yaml:"markers"`
 	InlineFormula InlineFormulaStyle `yaml:"inline_formula"`
+	InlineCode    InlineCodeStyle    `yaml:"inline_code"`
+	Table         TableStyle         `yaml:"table"`
 }
 
 type MarkerSetConfig struct {
@@ -60,16 +102,110 @@ type InlineFormulaStyle struct {
 	Color     string `yaml:"color"`
 }
 
+// InlineCodeStyle 是 `code` 的等宽字体和底色。
+type InlineCodeStyle struct {
+	Font      string `yaml:"font"`
+	Color     string `yaml:"color"`
+	Highlight string `yaml:"highlight"`
+}
+
+// TableStyle 是表格的配色；band_fill 为空时数据行不加底色。
+type TableStyle struct {
+	HeaderFill  string `yaml:"header_fill"`
+	HeaderColor string `yaml:"header_color"`
+	BandFill    string `yaml:"band_fill"`
+	BorderColor string `yaml:"border_color"`
+}
+
+// NotesConfig 控制演讲者备注：卡片中分隔行之后的内容移到备注页。
+type NotesConfig struct {
+	Separators []string `yaml:"separators"`
+	// Lang 为 all 时各语言备注依次拼接，否则只保留该语言（按 languages 的 name 匹配）。
+	Lang string `yaml:"lang"`
+}
+
+// NotesAllLanguages 表示备注拼接全部语言。
+const NotesAllLanguages = "all"
+
+// TemplateConfig 指定作为母版的 .pptx；相对路径以配置文件所在目录为准。
+type TemplateConfig struct {
+	Path   string `yaml:"path"`
+	Layout string `yaml:"layout"`
+}
+
+// StrictConfig 列出 --strict 时按错误处理的告警代码，支持 * 通配（如 truncate_*）；留空表示全部告警。
+type StrictConfig struct {
+	Codes []string `yaml:"codes"`
+}
+
 type OutputConfig struct {
 	DefaultName DefaultNameConfig `yaml:"default_name"`
 }
 
+// DefaultNameConfig 是没给 --output 或只给了目录时的文件名。Template 里的占位符见 NamePlaceholders，
+// {date} 按 TimestampFormat 格式化，{rand} 是 RandomSuffixLen 位随机码。
 type DefaultNameConfig struct {
+	Template        string `yaml:"template"`
 	TimestampFormat string `yaml:"timestamp_format"`
 	RandomSuffixLen int    `yaml:"random_suffix_len"`
 }
 
+const DefaultNameTemplate = "{date}_{rand}.pptx"
+
+// NamePlaceholders 是文件名模板支持的占位符。
+var NamePlaceholders = []string{"source", "date", "lang", "count", "git", "rand"}
+
+// maxRandomSuffixLen 限制随机码长度，可复现模式下随机码由 32 字节的哈希推出。
+const maxRandomSuffixLen = 32
+
+func DefaultLanguages() []LanguageConfig {
+	return []LanguageConfig{
+		{Name: "EN", Dir: "EN", Lang: "en-US"},
+		{Name: "CN", Dir: "CN", Lang: "zh-CN"},
+	}
+}
+
+var knownLangTags = map[string]string{
+	"EN": "en-US",
+	"CN": "zh-CN",
+	"ZH": "zh-CN",
+	"TW": "zh-TW",
+	"JA": "ja-JP",
+	"JP": "ja-JP",
+	"KO": "ko-KR",
+	"KR": "ko-KR",
+}
+
 func (c *Config) applyDefaults() {
+	langs := make([]LanguageConfig, 0, len(c.Languages))
+	for _, l := range c.Languages {
+		l.Name = strings.TrimSpace(l.Name)
+		l.Dir = strings.TrimSpace(l.Dir)
+		if l.Name == "" && l.Dir == "" {
+			continue
+		}
+		if l.Name == "" {
+			l.Name = l.Dir
+		}
+		if l.Dir == "" {
+			l.Dir = l.Name
+		}
+		l.Lang = strings.TrimSpace(l.Lang)
+		if l.Lang == "" {
+			l.Lang = knownLangTags[strings.ToUpper(l.Name)]
+		}
+		if l.Lang == "" {
+			l.Lang = "en-US"
+		}
+		langs = append(langs, l)
+	}
+	if len(langs) == 0 {
+		langs = DefaultLanguages()
+	}
+	c.Languages = langs
+	sort.SliceStable(c.Languages, func(i, j int) bool {
+		return c.Languages[i].Order < c.Languages[j].Order
+	})
 	if c.Layout.Slide.Width == 0 {
 		c.Layout.Slide.Width = 13.333
 	}
@@ -100,6 +236,14 @@ func (c *Config) applyDefaults() {
 	if c.Layout.Typography.LineSpacing <= 0 {
 		c.Layout.Typography.LineSpacing = 1.2
 	}
+	switch strings.ToLower(strings.TrimSpace(c.Layout.Overflow)) {
+	case OverflowShrink:
+		c.Layout.Overflow = OverflowShrink
+	case OverflowSplit:
+		c.Layout.Overflow = OverflowSplit
+	default:
+		c.Layout.Overflow = OverflowTruncate
+	}
 	if c.Styles.Markers.Star.Prefix == "" {
 		c.Styles.Markers.Star.Prefix = "★"
 	}
@@ -112,6 +256,34 @@ func (c *Config) applyDefaults() {
 	if c.Styles.InlineFormula.Delimiter == "" {
 		c.Styles.InlineFormula.Delimiter = "$"
 	}
+	c.Styles.InlineCode.Font = strings.TrimSpace(c.Styles.InlineCode.Font)
+	if c.Styles.InlineCode.Font == "" {
+		c.Styles.InlineCode.Font = "Consolas"
+	}
+	seps := make([]string, 0, len(c.Notes.Separators))
+	for _, sep := range c.Notes.Separators {
+		if sep = strings.TrimSpace(sep); sep != "" {
+			seps = append(seps, sep)
+		}
+	}
+	if len(seps) == 0 {
+		seps = []string{"<!-- notes -->"}
+	}
+	c.Notes.Separators = seps
+	c.Template.Path = strings.TrimSpace(c.Template.Path)
+	c.Template.Layout = strings.TrimSpace(c.Template.Layout)
+	c.Notes.Lang = strings.TrimSpace(c.Notes.Lang)
+	if c.Notes.Lang == "" || strings.EqualFold(c.Notes.Lang, NotesAllLanguages) {
+		c.Notes.Lang = NotesAllLanguages
+	}
+	name := &c.Output.DefaultName
+	name.Template = strings.TrimSpace(name.Template)
+	if name.Template == "" {
+		name.Template = DefaultNameTemplate
+	}
+	if !strings.HasSuffix(strings.ToLower(name.Template), ".pptx") {
+		name.Template += ".pptx"
+	}
 	if c.Output.DefaultName.TimestampFormat == "" {
 		c.Output.DefaultName.TimestampFormat = "20060102_150405"
 	}
@@ -119,3 +291,26 @@ func (c *Config) applyDefaults() {
 		c.Output.DefaultName.RandomSuffixLen = 6
 	}
 }
+
+// check 列出 Load 会拒绝的取值，Key 是出问题的配置项。
+func (c *Config) check() []Problem {
+	var problems []Problem
+	for _, code := range c.Strict.Codes {
+		if _, err := path.Match(code, ""); err != nil {
+			problems = append(problems, Problem{Key: "strict.codes", Message: fmt.Sprintf("strict.codes 里的 %q 写法不对：%v", code, err)})
+		}
+	}
+	name := c.Output.DefaultName
+	for _, m := range placeholderRe.FindAllStringSubmatch(name.Template, -1) {
+		if !slices.Contains(NamePlaceholders, m[1]) {
+			problems = append(problems, Problem{Key: "output.default_name.template", Message: fmt.Sprintf("output.default_name.template 里的 {%s} 不认识，可用：{%s}", m[1], strings.Join(NamePlaceholders, "}、{"))})
+		}
+	}
+	if strings.ContainsAny(name.Template, `/\`) {
+		problems = append(problems, Problem{Key: "output.default_name.template", Message: fmt.Sprintf("output.default_name.template 只能是文件名，不能带目录：%s", name.Template)})
+	}
+	if name.RandomSuffixLen > maxRandomSuffixLen {
+		problems = append(problems, Problem{Key: "output.default_name.random_suffix_len", Message: fmt.Sprintf("output.default_name.random_suffix_len 最多 %d 位，收到的是 %d", maxRandomSuffixLen, name.RandomSuffixLen)})
+	}
+	return problems
+}
@@ -0,0 +1,597 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+	"syl-md2ppt/internal/diag"
+)
+
+// 配置按层合并，后面的层覆盖前面的层里同一个配置项，没写的项沿用前面的值。
+const (
+	LayerDefault  = "内置默认"
+	LayerTemplate = "PPT 模板"
+	LayerUser     = "用户配置"
+	LayerProject  = "项目配置"
+	LayerDir      = "目录配置"
+	LayerEnv      = "环境变量"
+	LayerFlag     = "命令行"
+)
+
+// EnvPrefix 是覆盖配置项的环境变量前缀，如 SYL_MD2PPT_LAYOUT_OVERFLOW=split。
+const EnvPrefix = "SYL_MD2PPT_"
+
+// DirConfigName 是项目配置和数据源子目录里目录配置的文件名。
+const DirConfigName = "syl-md2ppt.yaml"
+
+// DirKeys 是目录配置里允许写的配置项，只影响单张卡片的排版；其余配置项对整份 PPT 生效，
+// 只能写在用户配置或项目配置里。
+var DirKeys = []string{
+	"layout.overflow",
+	"layout.typography.base_size",
+	"layout.typography.min_size",
+	"layout.typography.line_spacing",
+	"notes",
+	"styles.inline_formula.delimiter",
+	"styles.markers.*.prefix",
+}
+
+// LoadOptions 是 Resolve 的参数。
+type LoadOptions struct {
+	// ConfigPath 是 --config；为空时用当前目录的 syl-md2ppt.yaml。
+	ConfigPath string
+	CWD        string
+	// UserConfig 是用户配置文件，为空时用 UserConfigPath()。
+	UserConfig string
+	// Env 是 KEY=VALUE 形式的环境变量，为 nil 时读 os.Environ()。
+	Env []string
+	// Flags 是命令行参数对应的配置项（如 template.path）和取值，相对路径以 CWD 为准。
+	Flags map[string]Flag
+	// TemplateSize 读出 PPT 模板的页面尺寸（英寸）。给了且用了模板时，模板尺寸作为紧挨着内置默认的一层，
+	// 没有明确配置 layout.slide 时沿用模板的尺寸。
+	TemplateSize func(path string) (width, height float64, err error)
+}
+
+// Flag 是覆盖一个配置项的命令行参数。
+type Flag struct {
+	Name  string // 参数名，如 --template
+	Value string
+}
+
+// Origin 说明一个配置项最后由哪一层设置。Line 是在该文件里的行号，不是文件时为 0。
+type Origin struct {
+	Layer  string `json:"layer" yaml:"layer"`
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	Line   int    `json:"line,omitempty" yaml:"line,omitempty"`
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.Source == "":
+		return o.Layer
+	case o.Line > 0:
+		return fmt.Sprintf("%s %s:%d", o.Layer, o.Source, o.Line)
+	}
+	return o.Layer + " " + o.Source
+}
+
+type layer struct {
+	origin  Origin
+	node    *yaml.Node
+	baseDir string // 相对路径以这里为准，为空时不处理路径
+}
+
+// Loaded 是合并好的配置。Config 是对整份 PPT 生效的配置，卡片所在目录有目录配置时用 ForCard 取。
+type Loaded struct {
+	Config *Config
+	// Source 是项目配置文件，没有时为 EmbeddedSource。
+	Source string
+	// Layers 是用到的各层，从低到高。
+	Layers []Origin
+	// Warnings 是合并时跳过的内容，如不对应任何配置项的环境变量。
+	Warnings []diag.Diagnostic
+
+	base    []layer // 目录配置之前的层
+	top     []layer // 目录配置之后的层
+	origins map[string]Origin
+
+	mu   sync.Mutex
+	dirs map[string]*layer // 目录 -> 目录配置，没有时为 nil
+	card map[string]cardConfig
+}
+
+type cardConfig struct {
+	cfg     *Config
+	origins map[string]Origin
+	shared  map[string][]Origin // 各语言的目录配置都设了、取值一致的项
+}
+
+// UserConfigPath 返回用户配置文件的位置：$XDG_CONFIG_HOME/syl-md2ppt/config.yaml，
+// 没设时是 ~/.config/syl-md2ppt/config.yaml。找不到主目录时返回空。
+func UserConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "syl-md2ppt", "config.yaml")
+}
+
+// Resolve 依次合并内置默认配置、用户配置、项目配置、SYL_MD2PPT_* 环境变量和命令行参数。
+func Resolve(opts LoadOptions) (*Loaded, error) {
+	source, err := Locate(opts.ConfigPath, opts.CWD)
+	if err != nil {
+		return nil, err
+	}
+	def, err := parseLayer(embeddedDefault, Origin{Layer: LayerDefault}, "")
+	if err != nil {
+		return nil, err
+	}
+	l := &Loaded{Source: source, base: []layer{def}, dirs: map[string]*layer{}, card: map[string]cardConfig{}}
+
+	userPath := opts.UserConfig
+	if userPath == "" {
+		userPath = UserConfigPath()
+	}
+	if userPath != "" {
+		user, err := readLayer(userPath, LayerUser, true)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			l.base = append(l.base, *user)
+		}
+	}
+	if source != EmbeddedSource {
+		project, err := readLayer(source, LayerProject, false)
+		if err != nil {
+			return nil, err
+		}
+		l.base = append(l.base, *project)
+	}
+
+	env := opts.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	envLayers, warnings := parseEnv(env, opts.CWD)
+	l.Warnings = warnings
+	l.top = append(l.top, envLayers...)
+	for _, key := range sortedKeys(opts.Flags) {
+		f := opts.Flags[key]
+		l.top = append(l.top, layer{origin: Origin{Layer: LayerFlag, Source: f.Name}, node: valueNode(key, f.Value), baseDir: opts.CWD})
+	}
+
+	layers := append(slices.Clone(l.base), l.top...)
+	l.Config, l.origins, err = merge(layers)
+	if err != nil {
+		return nil, err
+	}
+	if tplPath := l.Config.Template.Path; opts.TemplateSize != nil && tplPath != "" {
+		w, h, err := opts.TemplateSize(tplPath)
+		if err != nil {
+			return nil, err
+		}
+		if w > 0 && h > 0 {
+			raw := fmt.Sprintf("layout:\n  slide:\n    width: %g\n    height: %g\n", w, h)
+			tpl, err := parseLayer([]byte(raw), Origin{Layer: LayerTemplate, Source: tplPath}, "")
+			if err != nil {
+				return nil, err
+			}
+			l.base = slices.Insert(l.base, 1, tpl)
+			layers = append(slices.Clone(l.base), l.top...)
+			if l.Config, l.origins, err = merge(layers); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, ly := range layers {
+		l.Layers = append(l.Layers, ly.origin)
+	}
+	return l, nil
+}
+
+// ForCard 返回对一张卡片生效的配置：在数据源 sourceDir 之下、卡片各语言文件所在目录及其上级目录里的
+// 目录配置叠加在项目配置之上（离卡片越近越优先，各语言那边的取值要一致），环境变量和命令行参数仍然最优先。
+// 没有目录配置时返回 l.Config。可以并发调用。
+func (l *Loaded) ForCard(sourceDir string, paths []string) (*Config, error) {
+	c, err := l.forCard(sourceDir, paths)
+	return c.cfg, err
+}
+
+func (l *Loaded) forCard(sourceDir string, paths []string) (cardConfig, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	dirLayers, shared, err := l.dirLayers(sourceDir, paths)
+	if err != nil || len(dirLayers) == 0 {
+		return cardConfig{cfg: l.Config, origins: l.origins}, err
+	}
+	keys := make([]string, len(dirLayers))
+	for i, d := range dirLayers {
+		keys[i] = d.origin.Source
+	}
+	id := strings.Join(keys, "\x00")
+	if c, ok := l.card[id]; ok {
+		return c, nil
+	}
+	layers := slices.Concat(l.base, dirLayers, l.top)
+	cfg, origins, err := merge(layers)
+	if err != nil {
+		return cardConfig{}, err
+	}
+	c := cardConfig{cfg: cfg, origins: origins, shared: shared}
+	l.card[id] = c
+	return c, nil
+}
+
+// dirLayers 找出对卡片生效的目录配置，按离数据源根目录由近到远排列；数据源根目录本身不算。
+// 卡片各语言文件所在目录的目录配置都生效：每个语言文件沿自己的目录往上离得越近越优先，
+// 不同语言得出的同一个配置项取值必须一致，否则报错。shared 记下取值一致、由不止一个语言的目录配置设置的项。
+func (l *Loaded) dirLayers(sourceDir string, paths []string) (out []layer, shared map[string][]Origin, err error) {
+	root, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var dirs []string
+	chains := make([][]string, len(paths))
+	for i, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		for d := filepath.Dir(abs); d != root && strings.HasPrefix(d, root+string(filepath.Separator)); d = filepath.Dir(d) {
+			chains[i] = append([]string{d}, chains[i]...)
+			if !slices.Contains(dirs, d) {
+				dirs = append(dirs, d)
+			}
+		}
+	}
+	slices.SortStableFunc(dirs, func(a, b string) int {
+		return strings.Count(a, string(filepath.Separator)) - strings.Count(b, string(filepath.Separator))
+	})
+	for _, d := range dirs {
+		ly, ok := l.dirs[d]
+		if !ok {
+			ly, err = readLayer(filepath.Join(d, DirConfigName), LayerDir, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ly != nil {
+				if err := checkDirKeys(*ly); err != nil {
+					return nil, nil, err
+				}
+			}
+			l.dirs[d] = ly
+		}
+		if ly != nil {
+			out = append(out, *ly)
+		}
+	}
+	shared, err = l.crossCheck(chains)
+	return out, shared, err
+}
+
+type dirSetting struct {
+	value  string
+	origin Origin
+}
+
+// crossCheck 按各语言文件自己的目录链算出目录配置设的每一项，比较不同语言的取值。
+func (l *Loaded) crossCheck(chains [][]string) (map[string][]Origin, error) {
+	if len(chains) < 2 {
+		return nil, nil
+	}
+	seen := map[string][]dirSetting{}
+	for _, chain := range chains {
+		eff := map[string]dirSetting{}
+		for _, d := range chain {
+			ly := l.dirs[d]
+			if ly == nil {
+				continue
+			}
+			for key, e := range keyEntries(ly.node) {
+				if e.value.Kind != yaml.MappingNode {
+					eff[key] = dirSetting{value: nodeText(e.value), origin: Origin{Layer: LayerDir, Source: ly.origin.Source, Line: e.line}}
+				}
+			}
+		}
+		for key, s := range eff {
+			if !slices.ContainsFunc(seen[key], func(o dirSetting) bool { return o.origin.Source == s.origin.Source }) {
+				seen[key] = append(seen[key], s)
+			}
+		}
+	}
+	shared := map[string][]Origin{}
+	for _, key := range sortedKeys(seen) {
+		sets := seen[key]
+		for _, s := range sets[1:] {
+			if s.value != sets[0].value {
+				a, b := sets[0], s
+				return nil, fmt.Errorf("%s 第 %d 行和 %s 第 %d 行给同一张卡片的 %s 设了不同的值（%s / %s）；"+
+					"同一张卡片各语言的目录配置要一致，请改成一样，或写到它们共同的上级目录",
+					a.origin.Source, a.origin.Line, b.origin.Source, b.origin.Line, key, a.value, b.value)
+			}
+		}
+		if len(sets) > 1 {
+			for _, s := range sets {
+				shared[key] = append(shared[key], s.origin)
+			}
+		}
+	}
+	return shared, nil
+}
+
+func checkDirKeys(ly layer) error {
+	entries := keyEntries(ly.node)
+	for _, key := range sortedKeys(entries) {
+		if entries[key].value.Kind == yaml.MappingNode {
+			continue
+		}
+		k := ruleKey(key)
+		if !slices.ContainsFunc(DirKeys, func(p string) bool { return k == p || strings.HasPrefix(k, p+".") }) {
+			return fmt.Errorf("%s 第 %d 行的 %s 对整份 PPT 生效，只能写在项目配置里；目录配置只能改：%s",
+				ly.origin.Source, entries[key].line, key, strings.Join(DirKeys, "、"))
+		}
+	}
+	return nil
+}
+
+// Explanation 是一个配置项的实际取值和设置它的层。Also 是卡片其他语言那边设了同样取值的目录配置。
+type Explanation struct {
+	Key    string   `json:"key" yaml:"key"`
+	Value  string   `json:"value" yaml:"value"`
+	Origin Origin   `json:"origin" yaml:"origin"`
+	Also   []Origin `json:"also,omitempty" yaml:"also,omitempty"`
+}
+
+// Explain 列出每个配置项的取值和来源；给了卡片路径时按 ForCard 叠加目录配置。
+// 没有任何一层写过、由程序补上的值来源是“程序默认值”。
+func (l *Loaded) Explain(sourceDir string, paths []string) ([]Explanation, error) {
+	c := cardConfig{cfg: l.Config, origins: l.origins}
+	if len(paths) > 0 {
+		var err error
+		if c, err = l.forCard(sourceDir, paths); err != nil {
+			return nil, err
+		}
+	}
+	var doc yaml.Node
+	if err := doc.Encode(c.cfg); err != nil {
+		return nil, err
+	}
+	entries := keyEntries(&doc)
+	var out []Explanation
+	for _, key := range sortedKeys(entries) {
+		v := entries[key].value
+		if v.Kind == yaml.MappingNode || v.Kind == yaml.SequenceNode && hasComplexItems(v) {
+			continue
+		}
+		if parent := key[:max(strings.LastIndex(key, "."), 0)]; parent != "" {
+			if p, ok := entries[parent]; ok && p.value.Kind == yaml.SequenceNode && !hasComplexItems(p.value) {
+				continue
+			}
+		}
+		e := Explanation{Key: key, Value: nodeText(v), Origin: originOf(c.origins, key)}
+		for _, o := range c.shared[key] {
+			if e.Origin.Layer == LayerDir && o != e.Origin {
+				e.Also = append(e.Also, o)
+			}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func hasComplexItems(seq *yaml.Node) bool {
+	return slices.ContainsFunc(seq.Content, func(n *yaml.Node) bool { return n.Kind != yaml.ScalarNode })
+}
+
+func nodeText(n *yaml.Node) string {
+	if n.Kind == yaml.ScalarNode {
+		return n.Value
+	}
+	items := make([]string, len(n.Content))
+	for i, c := range n.Content {
+		items[i] = c.Value
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// originOf 找设置了 key 或它上级的层。
+func originOf(origins map[string]Origin, key string) Origin {
+	for k := key; ; {
+		if o, ok := origins[k]; ok {
+			return o
+		}
+		i := strings.LastIndex(k, ".")
+		if i < 0 {
+			return Origin{Layer: "程序默认值"}
+		}
+		k = k[:i]
+	}
+}
+
+// merge 按顺序把各层解码到同一个 Config 上，再补默认值、检查取值。
+func merge(layers []layer) (*Config, map[string]Origin, error) {
+	cfg := &Config{}
+	origins := make(map[string]Origin)
+	for _, ly := range layers {
+		if err := ly.node.Decode(cfg); err != nil {
+			return nil, nil, fmt.Errorf("配置读不懂（%s）：%w", ly.origin, err)
+		}
+		entries := keyEntries(ly.node)
+		for _, key := range sortedKeys(entries) {
+			e := entries[key]
+			if e.value.Kind == yaml.MappingNode {
+				// 对象逐项合并，来源记在各项上。
+				continue
+			}
+			// 序列和值整个替换掉，下层写的子项不再算数。
+			for k := range origins {
+				if strings.HasPrefix(k, key+".") {
+					delete(origins, k)
+				}
+			}
+			o := ly.origin
+			if e.line > 0 && o.Layer != LayerEnv && o.Layer != LayerFlag && o.Layer != LayerTemplate {
+				o.Line = e.line
+			}
+			origins[key] = o
+		}
+		if ly.baseDir != "" {
+			cfg.resolveLayerPaths(entries, ly.baseDir)
+		}
+	}
+	cfg.applyDefaults()
+	if problems := cfg.check(); len(problems) > 0 {
+		p := problems[0]
+		return nil, nil, fmt.Errorf("配置有问题（%s）：%s", originOf(origins, p.Key), p.Message)
+	}
+	return cfg, origins, nil
+}
+
+// resolveLayerPaths 把这一层写的相对路径换成以 baseDir 为准的绝对路径。
+func (c *Config) resolveLayerPaths(entries map[string]keyEntry, baseDir string) {
+	files := &c.Layout.Typography.FontFiles
+	fields := map[string]*string{
+		"template.path":                            &c.Template.Path,
+		"layout.typography.font_files.regular":     &files.Regular,
+		"layout.typography.font_files.bold":        &files.Bold,
+		"layout.typography.font_files.italic":      &files.Italic,
+		"layout.typography.font_files.bold_italic": &files.BoldItalic,
+	}
+	for key, p := range fields {
+		if _, ok := entries[key]; ok {
+			if v := strings.TrimSpace(*p); v != "" && !filepath.IsAbs(v) {
+				*p = filepath.Join(baseDir, v)
+			}
+		}
+	}
+}
+
+// readLayer 读取一个配置文件；optional 时文件不存在返回 nil。
+func readLayer(path, name string, optional bool) (*layer, error) {
+	raw, err := os.ReadFile(path)
+	if optional && errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败（%s）：%w", path, err)
+	}
+	ly, err := parseLayer(raw, Origin{Layer: name, Source: path}, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	return &ly, nil
+}
+
+func parseLayer(raw []byte, origin Origin, baseDir string) (layer, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return layer{}, fmt.Errorf("配置文件格式读不懂（%s）：%w", origin.Source, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	return layer{origin: origin, node: &doc, baseDir: baseDir}, nil
+}
+
+// parseEnv 把 SYL_MD2PPT_* 环境变量换成配置层，每个变量一层，按变量名排序。
+// 不对应任何配置项的变量跳过并告警，免得 shell 里留着的旧变量让所有命令都用不了。
+func parseEnv(env []string, cwd string) ([]layer, []diag.Diagnostic) {
+	keys := envKeys()
+	var out []layer
+	var warnings []diag.Diagnostic
+	env = slices.Clone(env)
+	slices.Sort(env)
+	for _, kv := range env {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		key, ok := keys[name]
+		if !ok {
+			warnings = append(warnings, diag.Diagnostic{
+				Code:     diag.CodeUnknownEnv,
+				Severity: diag.SeverityWarning,
+				Message:  fmt.Sprintf("环境变量 %s 不对应任何配置项，已忽略（写法如 %sLAYOUT_OVERFLOW；不用了可以 unset %s）", name, EnvPrefix, name),
+			})
+			continue
+		}
+		out = append(out, layer{origin: Origin{Layer: LayerEnv, Source: name}, node: valueNode(key, value), baseDir: cwd})
+	}
+	return out, warnings
+}
+
+// envKeys 列出能用环境变量覆盖的配置项：环境变量名是配置项路径大写、. 换成 _。
+// 字符串列表用逗号分隔；languages 这样的对象列表不支持。
+func envKeys() map[string]string {
+	out := make(map[string]string)
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			key := joinKey(prefix, name)
+			switch {
+			case f.Type.Kind() == reflect.Struct:
+				walk(f.Type, key)
+			case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() != reflect.String:
+			default:
+				out[EnvPrefix+strings.ToUpper(strings.ReplaceAll(key, ".", "_"))] = key
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	return out
+}
+
+// valueNode 把 a.b.c=value 写成 {a: {b: {c: value}}}。值按 YAML 的规则推断类型，
+// 写到字符串列表上时按逗号拆开。
+func valueNode(key, value string) *yaml.Node {
+	var v *yaml.Node
+	if listKey(key) {
+		v = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				v.Content = append(v.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+			}
+		}
+	} else {
+		v = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	}
+	parts := strings.Split(key, ".")
+	for i := len(parts) - 1; i >= 0; i-- {
+		v = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: parts[i]}, v}}
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{v}}
+}
+
+func listKey(key string) bool {
+	t := reflect.TypeOf(Config{})
+	for _, part := range strings.Split(key, ".") {
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			if name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); name == part {
+				t, found = t.Field(i).Type, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return t.Kind() == reflect.Slice
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"syl-md2ppt/internal/diag"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestResolve_MergesLayersInOrder(t *testing.T) {
+	tmp := t.TempDir()
+	user := filepath.Join(tmp, "home", "config.yaml")
+	writeFile(t, user, "layout:\n  overflow: shrink\n  typography:\n    base_size: 18\ntemplate:\n  path: brand.pptx\n")
+	project := filepath.Join(tmp, "work", "syl-md2ppt.yaml")
+	writeFile(t, project, "layout:\n  typography:\n    base_size: 22\n")
+
+	l, err := Resolve(LoadOptions{
+		CWD:        filepath.Dir(project),
+		UserConfig: user,
+		Env:        []string{"SYL_MD2PPT_LAYOUT_COLUMNS_LEFT_RATIO=0.6", "SYL_MD2PPT_STRICT_CODES=truncate_*, conflict", "HOME=/root"},
+		Flags:      map[string]Flag{"layout.overflow": {Name: "--overflow", Value: "split"}},
+	})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	cfg := l.Config
+	if cfg.Layout.Typography.BaseSize != 22 || cfg.Layout.Typography.MinSize != 12 {
+		t.Fatalf("project should override the user layer and keep defaults, got %+v", cfg.Layout.Typography)
+	}
+	if cfg.Layout.Columns.LeftRatio != 0.6 || strings.Join(cfg.Strict.Codes, "|") != "truncate_*|conflict" {
+		t.Fatalf("environment should override files, got %v %v", cfg.Layout.Columns.LeftRatio, cfg.Strict.Codes)
+	}
+	if cfg.Layout.Overflow != OverflowSplit {
+		t.Fatalf("flags should win, got %s", cfg.Layout.Overflow)
+	}
+	if want := filepath.Join(tmp, "home", "brand.pptx"); cfg.Template.Path != want {
+		t.Fatalf("relative paths should follow the layer that set them, want %s got %s", want, cfg.Template.Path)
+	}
+	if l.Source != project || len(l.Layers) != 6 {
+		t.Fatalf("unexpected source %s or layers %v", l.Source, l.Layers)
+	}
+
+	explain, err := l.Explain("", nil)
+	if err != nil {
+		t.Fatalf("Explain returned error: %v", err)
+	}
+	want := map[string]string{
+		"layout.typography.base_size": "项目配置 " + project + ":3",
+		"layout.typography.min_size":  "内置默认",
+		"layout.columns.left_ratio":   "环境变量 SYL_MD2PPT_LAYOUT_COLUMNS_LEFT_RATIO",
+		"layout.overflow":             "命令行 --overflow",
+		"template.path":               "用户配置 " + user + ":6",
+	}
+	for _, e := range explain {
+		if w, ok := want[e.Key]; ok {
+			if got := e.Origin.String(); !strings.HasPrefix(got, w) {
+				t.Fatalf("%s: want origin %s, got %s", e.Key, w, got)
+			}
+			delete(want, e.Key)
+		}
+	}
+	if len(want) > 0 {
+		t.Fatalf("missing explanations: %v", want)
+	}
+
+	stale, err := Resolve(LoadOptions{CWD: tmp, UserConfig: user, Env: []string{"SYL_MD2PPT_LAYOUT_OVERFLO=split"}})
+	if err != nil {
+		t.Fatalf("unknown SYL_MD2PPT_ variables should not fail Resolve: %v", err)
+	}
+	if len(stale.Warnings) != 1 || stale.Warnings[0].Code != diag.CodeUnknownEnv || !strings.Contains(stale.Warnings[0].Message, "unset SYL_MD2PPT_LAYOUT_OVERFLO") {
+		t.Fatalf("unknown SYL_MD2PPT_ variables should be reported as warnings, got %+v", stale.Warnings)
+	}
+	if stale.Config.Layout.Overflow == OverflowSplit {
+		t.Fatal("unknown SYL_MD2PPT_ variables should be ignored")
+	}
+}
+
+func TestResolve_TemplateSlideSize(t *testing.T) {
+	tmp := t.TempDir()
+	size := func(path string) (float64, float64, error) { return 10, 7.5, nil }
+	opts := LoadOptions{CWD: tmp, UserConfig: filepath.Join(tmp, "none.yaml"), Env: []string{}, TemplateSize: size}
+	l, err := Resolve(opts)
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if l.Config.Layout.Slide.Width != 13.333 {
+		t.Fatalf("without a template the default size should stay, got %v", l.Config.Layout.Slide.Width)
+	}
+
+	opts.Flags = map[string]Flag{"template.path": {Name: "--template", Value: "4x3.pptx"}}
+	l, err = Resolve(opts)
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if s := l.Config.Layout.Slide; s.Width != 10 || s.Height != 7.5 {
+		t.Fatalf("template size should replace the default, got %vx%v", s.Width, s.Height)
+	}
+	explain, err := l.Explain("", nil)
+	if err != nil {
+		t.Fatalf("Explain returned error: %v", err)
+	}
+	for _, e := range explain {
+		if e.Key == "layout.slide.width" && e.Origin.Layer != LayerTemplate {
+			t.Fatalf("slide width should come from the template, got %s", e.Origin)
+		}
+	}
+
+	writeFile(t, filepath.Join(tmp, DirConfigName), "layout:\n  slide:\n    width: 13.333\n")
+	l, err = Resolve(opts)
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if s := l.Config.Layout.Slide; s.Width != 13.333 || s.Height != 7.5 {
+		t.Fatalf("configured width should win over the template, got %vx%v", s.Width, s.Height)
+	}
+}
+
+func TestForCard_DirectoryConfigs(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	writeFile(t, filepath.Join(source, "EN", "A", "syl-md2ppt.yaml"), "layout:\n  typography:\n    base_size: 14\n  overflow: split\n")
+	writeFile(t, filepath.Join(source, "EN", "A", "Deep", "syl-md2ppt.yaml"), "layout:\n  typography:\n    base_size: 12\n    min_size: 8\n")
+	l, err := Resolve(LoadOptions{CWD: tmp, UserConfig: filepath.Join(tmp, "none.yaml"), Env: []string{"SYL_MD2PPT_LAYOUT_OVERFLOW=shrink"}})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+
+	deep, err := l.ForCard(source, []string{filepath.Join(source, "EN", "A", "Deep", "1.md"), filepath.Join(source, "CN", "A", "Deep", "1.md")})
+	if err != nil {
+		t.Fatalf("ForCard returned error: %v", err)
+	}
+	if deep.Layout.Typography.BaseSize != 12 || deep.Layout.Typography.MinSize != 8 {
+		t.Fatalf("nearer directory config should win, got %+v", deep.Layout.Typography)
+	}
+	if deep.Layout.Overflow != OverflowShrink {
+		t.Fatalf("environment should still override directory configs, got %s", deep.Layout.Overflow)
+	}
+	other, err := l.ForCard(source, []string{filepath.Join(source, "EN", "B", "1.md")})
+	if err != nil || other != l.Config {
+		t.Fatalf("cards without directory configs should share the project config, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(source, "CN", "C", "syl-md2ppt.yaml"), "layout:\n  slide:\n    width: 10\n")
+	if _, err := l.ForCard(source, []string{filepath.Join(source, "CN", "C", "1.md")}); err == nil || !strings.Contains(err.Error(), "layout.slide.width") {
+		t.Fatalf("deck-wide keys should be rejected in directory configs, got %v", err)
+	}
+}
+
+func TestForCard_DirectoryConfigsAcrossLanguages(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	en := filepath.Join(source, "EN", "A", "syl-md2ppt.yaml")
+	cn := filepath.Join(source, "CN", "A", "syl-md2ppt.yaml")
+	writeFile(t, en, "layout:\n  overflow: split\n  typography:\n    base_size: 14\n")
+	writeFile(t, cn, "layout:\n  typography:\n    base_size: 14\n")
+	writeFile(t, filepath.Join(source, "CN", "A", "Deep", "syl-md2ppt.yaml"), "layout:\n  typography:\n    base_size: 16\n")
+	l, err := Resolve(LoadOptions{CWD: tmp, UserConfig: filepath.Join(tmp, "none.yaml"), Env: []string{}})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+
+	card := []string{filepath.Join(source, "EN", "A", "1.md"), filepath.Join(source, "CN", "A", "1.md")}
+	cfg, err := l.ForCard(source, card)
+	if err != nil {
+		t.Fatalf("matching values in both language trees should be fine: %v", err)
+	}
+	if cfg.Layout.Typography.BaseSize != 14 || cfg.Layout.Overflow != OverflowSplit {
+		t.Fatalf("both language trees should apply, got %+v %s", cfg.Layout.Typography, cfg.Layout.Overflow)
+	}
+	explain, err := l.Explain(source, card)
+	if err != nil {
+		t.Fatalf("Explain returned error: %v", err)
+	}
+	for _, e := range explain {
+		if e.Key == "layout.typography.base_size" && (len(e.Also) != 1 || e.Also[0].Source == e.Origin.Source) {
+			t.Fatalf("explain should list the other language's config, got %s also %v", e.Origin, e.Also)
+		}
+	}
+
+	_, err = l.ForCard(source, []string{filepath.Join(source, "EN", "A", "Deep", "1.md"), filepath.Join(source, "CN", "A", "Deep", "1.md")})
+	if err == nil || !strings.Contains(err.Error(), "layout.typography.base_size") || !strings.Contains(err.Error(), en+" 第 4 行") || !strings.Contains(err.Error(), "（14 / 16）") {
+		t.Fatalf("conflicting values across language trees should be reported, got %v", err)
+	}
+}
@@ -5,52 +5,71 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-
-	"gopkg.in/yaml.v3"
+	"strings"
 )
 
 //go:embed default.yaml
 var embeddedDefault []byte
 
+// EmbeddedSource 是没有配置文件、使用内置默认配置时 Load 返回的来源。
+const EmbeddedSource = "embedded:default.yaml"
+
+// Load 按层合并配置（见 Resolve），返回对整份 PPT 生效的配置和项目配置文件；
+// 没有项目配置时来源是 EmbeddedSource。
 func Load(pathArg, cwd string) (*Config, string, error) {
-	if cwd == "" {
-		return nil, "", fmt.Errorf("当前目录为空，没法继续")
+	l, err := Resolve(LoadOptions{ConfigPath: pathArg, CWD: cwd})
+	if err != nil {
+		return nil, "", err
 	}
+	return l.Config, l.Source, nil
+}
 
-	var (
-		raw    []byte
-		source string
-		err    error
-	)
-
+// Locate 找出 Load 要读的配置文件：--config 给的文件，否则是当前目录的 syl-md2ppt.yaml，
+// 都没有时返回 EmbeddedSource。
+func Locate(pathArg, cwd string) (string, error) {
+	if cwd == "" {
+		return "", fmt.Errorf("当前目录为空，没法继续")
+	}
 	if pathArg != "" {
-		target := pathArg
-		if !filepath.IsAbs(target) {
-			target = filepath.Join(cwd, target)
-		}
-		raw, err = os.ReadFile(target)
-		if err != nil {
-			return nil, "", fmt.Errorf("读取配置文件失败（%s）：%w", target, err)
-		}
-		source = target
-	} else {
-		project := filepath.Join(cwd, "syl-md2ppt.yaml")
-		if st, statErr := os.Stat(project); statErr == nil && !st.IsDir() {
-			raw, err = os.ReadFile(project)
-			if err != nil {
-				return nil, "", fmt.Errorf("读取配置文件失败（%s）：%w", project, err)
-			}
-			source = project
-		} else {
-			raw = embeddedDefault
-			source = "embedded:default.yaml"
+		if filepath.IsAbs(pathArg) {
+			return pathArg, nil
 		}
+		return filepath.Join(cwd, pathArg), nil
+	}
+	project := filepath.Join(cwd, "syl-md2ppt.yaml")
+	if st, err := os.Stat(project); err == nil && !st.IsDir() {
+		return project, nil
 	}
+	return EmbeddedSource, nil
+}
+
+// 生成配置文件时各顶层配置前面加的说明。
+var sectionComments = map[string]string{
+	"languages": "语言列表：name 是栏和告警里显示的名字，dir 是数据源下的子目录，lang 是文本的语言标记；\n可选 order 决定栏的先后",
+	"filename":  "文件名配对：ignore_unmatched 为 true 时跳过文件名里没有可配对数字的 .md，否则报错",
+	"layout":    "版面：尺寸单位是英寸；left_ratio 是左栏宽度占比；放不下时按 overflow 截断、缩小或拆成续页",
+	"styles":    "样式：以 ★ ● ▲ 开头的行、行内公式、行内代码和表格的颜色（十六进制 RGB）",
+	"notes":     "演讲者备注：卡片里独占一行的分隔符之后的内容写进备注",
+	"template":  "PPT 母版：相对路径以本文件所在目录为准",
+	"output":    "输出文件名",
+	"strict":    "严格模式（--strict）",
+}
 
-	cfg := &Config{}
-	if err := yaml.Unmarshal(raw, cfg); err != nil {
-		return nil, "", fmt.Errorf("配置文件格式读不懂（%s）：%w", source, err)
+// CommentedDefault 返回内置默认配置，每个顶层配置前加上说明，用作新项目的 syl-md2ppt.yaml。
+func CommentedDefault() []byte {
+	var b strings.Builder
+	b.WriteString("# syl-md2ppt 配置文件，由 syl-md2ppt init 按内置默认值生成。\n")
+	b.WriteString("# 放在运行命令的目录下会自动读取，也可以用 --config 指定；删掉的项按默认值处理。\n")
+	for _, line := range strings.SplitAfter(string(embeddedDefault), "\n") {
+		if key, _, ok := strings.Cut(line, ":"); ok && key != "" && !strings.HasPrefix(key, " ") {
+			if c, ok := sectionComments[key]; ok {
+				b.WriteString("\n# " + strings.ReplaceAll(c, "\n", "\n# ") + "\n")
+			}
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		b.WriteString(line)
 	}
-	cfg.applyDefaults()
-	return cfg, source, nil
+	return []byte(b.String())
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -54,3 +55,85 @@ func TestLoadConfig_DefaultFallback(t *testing.T) {
 		t.Fatalf("default base font should be positive")
 	}
 }
+
+func TestLoadConfig_ResolvesPathsRelativeToConfig(t *testing.T) {
+	tmp := t.TempDir()
+	cfgDir := filepath.Join(tmp, "conf")
+	if err := os.MkdirAll(cfgDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cfgPath := filepath.Join(cfgDir, "syl.yaml")
+	raw := "layout:\n  typography:\n    font_files:\n      regular: fonts/a.ttf\n      bold: /abs/b.ttf\ntemplate:\n  path: brand/master.pptx\n  layout: Blank\n"
+	if err := os.WriteFile(cfgPath, []byte(raw), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, _, err := Load(cfgPath, tmp)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if got := cfg.Layout.Typography.FontFiles.Regular; got != filepath.Join(cfgDir, "fonts", "a.ttf") {
+		t.Fatalf("unexpected regular font path: %s", got)
+	}
+	if got := cfg.Layout.Typography.FontFiles.Bold; got != "/abs/b.ttf" {
+		t.Fatalf("absolute path should be kept, got: %s", got)
+	}
+	if got := cfg.Template.Path; got != filepath.Join(cfgDir, "brand", "master.pptx") {
+		t.Fatalf("unexpected template path: %s", got)
+	}
+}
+
+func TestLoadConfig_LanguagesDefaultsAndOrder(t *testing.T) {
+	tmp := t.TempDir()
+	cfg, _, err := Load("", tmp)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(cfg.Languages) != 2 || cfg.Languages[0].Dir != "EN" || cfg.Languages[1].Lang != "zh-CN" {
+		t.Fatalf("unexpected default languages: %#v", cfg.Languages)
+	}
+
+	cfgPath := filepath.Join(tmp, "langs.yaml")
+	raw := "languages:\n  - { name: KO, order: 3 }\n  - { name: EN, order: 1 }\n  - { name: JA, dir: Japanese, order: 2 }\n"
+	if err := os.WriteFile(cfgPath, []byte(raw), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, _, err = Load(cfgPath, tmp)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := []LanguageConfig{
+		{Name: "EN", Dir: "EN", Lang: "en-US", Order: 1},
+		{Name: "JA", Dir: "Japanese", Lang: "ja-JP", Order: 2},
+		{Name: "KO", Dir: "KO", Lang: "ko-KR", Order: 3},
+	}
+	if len(cfg.Languages) != len(want) {
+		t.Fatalf("unexpected languages: %#v", cfg.Languages)
+	}
+	for i := range want {
+		if cfg.Languages[i] != want[i] {
+			t.Fatalf("language %d: want %#v, got %#v", i, want[i], cfg.Languages[i])
+		}
+	}
+}
+
+func TestLoadConfig_RejectsBadStrictPattern(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "bad.yaml")
+	if err := os.WriteFile(path, []byte("strict:\n  codes: [\"truncate_[\"]\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, _, err := Load(path, tmp); err == nil {
+		t.Fatalf("expected an error for a malformed strict.codes pattern")
+	}
+}
+
+func TestLoadConfig_RejectsUnknownNamePlaceholder(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "bad.yaml")
+	if err := os.WriteFile(path, []byte("output:\n  default_name:\n    template: \"{source}_{author}.pptx\"\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, _, err := Load(path, tmp); err == nil || !strings.Contains(err.Error(), "{author}") {
+		t.Fatalf("expected an error naming the unknown placeholder, got: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema 按 Config 的结构生成 JSON Schema（draft 2020-12），取值约束和 Validate 一致，
+// 默认值取自内置的 default.yaml。编辑器可以用它补全和检查 syl-md2ppt.yaml。
+func Schema() ([]byte, error) {
+	def := &Config{}
+	if err := yaml.Unmarshal(embeddedDefault, def); err != nil {
+		return nil, err
+	}
+	root := schemaFor(reflect.TypeOf(*def), reflect.ValueOf(*def), "")
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "syl-md2ppt 配置"
+	return json.MarshalIndent(root, "", "  ")
+}
+
+func schemaFor(t reflect.Type, v reflect.Value, key string) map[string]any {
+	s := map[string]any{}
+	if c, ok := sectionComments[key]; ok {
+		s["description"] = strings.ReplaceAll(c, "\n", "")
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		props := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			var fv reflect.Value
+			if v.IsValid() {
+				fv = v.Field(i)
+			}
+			props[name] = schemaFor(f.Type, fv, joinKey(key, name))
+		}
+		s["type"] = "object"
+		s["properties"] = props
+		s["additionalProperties"] = false
+		return s
+	case reflect.Slice:
+		s["type"] = "array"
+		s["items"] = schemaFor(t.Elem(), reflect.Value{}, joinKey(key, "*"))
+		if v.IsValid() && v.Len() > 0 && t.Elem().Kind() == reflect.String {
+			s["default"] = v.Interface()
+		}
+		return s
+	case reflect.Bool:
+		s["type"] = "boolean"
+	case reflect.Int:
+		s["type"] = "integer"
+	case reflect.Float64:
+		s["type"] = "number"
+	default:
+		s["type"] = "string"
+	}
+	if v.IsValid() && !v.IsZero() {
+		s["default"] = v.Interface()
+	}
+	rule := fieldRules[ruleKey(key)]
+	if rule.Min != nil {
+		if rule.Exclusive {
+			s["exclusiveMinimum"] = *rule.Min
+		} else {
+			s["minimum"] = *rule.Min
+		}
+	}
+	if rule.Max != nil {
+		if rule.Exclusive {
+			s["exclusiveMaximum"] = *rule.Max
+		} else {
+			s["maximum"] = *rule.Max
+		}
+	}
+	if len(rule.Enum) > 0 {
+		s["enum"] = rule.Enum
+	}
+	if rule.Pattern != nil {
+		s["pattern"] = rule.Pattern.String()
+	}
+	return s
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem 是配置文件里的一处问题。Line 从 1 开始，定位不到具体行时为 0。
+type Problem struct {
+	Line    int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Key     string `json:"key,omitempty" yaml:"key,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("第 %d 行：%s", p.Line, p.Message)
+	}
+	return p.Message
+}
+
+// fieldRule 是一个配置项取值的约束，Validate 和 Schema 共用。Min、Max 为 nil 表示不限，
+// Exclusive 时不含端点。Load 对超出范围的值大多悄悄改用默认值，Validate 把它们都报出来。
+type fieldRule struct {
+	Min, Max  *float64
+	Exclusive bool
+	Enum      []string
+	Pattern   *regexp.Regexp
+}
+
+func bound(v float64) *float64 { return &v }
+
+// 颜色写 6 位十六进制 RGB，# 可写可不写，和生成时的处理一致。
+var colorPattern = regexp.MustCompile(`^#?([0-9A-Fa-f]{6})?$`)
+
+// fieldRules 按配置项路径列出约束，序列里的元素写成 *。
+var fieldRules = map[string]fieldRule{
+	"layout.slide.width":                    {Min: bound(0), Exclusive: true},
+	"layout.slide.height":                   {Min: bound(0), Exclusive: true},
+	"layout.slide.unit":                     {Enum: []string{"in"}},
+	"layout.columns.left_ratio":             {Min: bound(0), Max: bound(1), Exclusive: true},
+	"layout.columns.gap":                    {Min: bound(0), Exclusive: true},
+	"layout.columns.padding":                {Min: bound(0), Exclusive: true},
+	"layout.typography.base_size":           {Min: bound(0), Exclusive: true},
+	"layout.typography.min_size":            {Min: bound(0), Exclusive: true},
+	"layout.typography.line_spacing":        {Min: bound(0), Exclusive: true},
+	"layout.overflow":                       {Enum: []string{OverflowTruncate, OverflowShrink, OverflowSplit}},
+	"styles.markers.*.color":                {Pattern: colorPattern},
+	"styles.markers.*.highlight":            {Pattern: colorPattern},
+	"styles.inline_formula.color":           {Pattern: colorPattern},
+	"styles.inline_formula.highlight":       {Pattern: colorPattern},
+	"styles.inline_code.color":              {Pattern: colorPattern},
+	"styles.inline_code.highlight":          {Pattern: colorPattern},
+	"styles.table.header_fill":              {Pattern: colorPattern},
+	"styles.table.header_color":             {Pattern: colorPattern},
+	"styles.table.band_fill":                {Pattern: colorPattern},
+	"styles.table.border_color":             {Pattern: colorPattern},
+	"output.default_name.random_suffix_len": {Min: bound(1), Max: bound(maxRandomSuffixLen)},
+}
+
+var (
+	yamlLineRe     = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	unknownFieldRe = regexp.MustCompile(`^field (\S+) not found in type`)
+)
+
+// Validate 严格检查一份配置文件的内容，逐条列出问题和所在行：YAML 写错、不认识的配置项、
+// 类型不对、超出范围（Load 会悄悄改成默认值）的取值，以及 Load 会拒绝的取值。没问题时返回 nil。
+func Validate(raw []byte) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return []Problem{syntaxProblem(err.Error())}
+	}
+	var problems []Problem
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return []Problem{syntaxProblem(err.Error())}
+		}
+		for _, msg := range te.Errors {
+			problems = append(problems, decodeProblem(msg))
+		}
+	}
+
+	entries := keyEntries(&doc)
+	for i, p := range problems {
+		// 解码器只给出行号和字段名，找回完整路径。
+		for _, key := range sortedKeys(entries) {
+			e := entries[key]
+			if e.line != p.Line {
+				continue
+			}
+			switch {
+			case p.Key != "" && (key == p.Key || strings.HasSuffix(key, "."+p.Key)):
+				problems[i].Key = key
+				problems[i].Message = "不认识的配置项：" + key
+			case p.Key == "" && e.value.Kind == yaml.ScalarNode:
+				problems[i].Key = key
+				problems[i].Message = key + " " + p.Message
+			}
+		}
+	}
+	for _, key := range sortedKeys(entries) {
+		e := entries[key]
+		rule, ok := fieldRules[ruleKey(key)]
+		if !ok || e.value.Kind != yaml.ScalarNode {
+			continue
+		}
+		if msg := rule.check(e.value.Value); msg != "" {
+			problems = append(problems, Problem{Line: e.line, Key: key, Message: key + " " + msg})
+		}
+	}
+
+	cfg.applyDefaults()
+	checks := cfg.check()
+	if lang := cfg.Notes.Lang; lang != NotesAllLanguages && !slices.ContainsFunc(cfg.Languages, func(l LanguageConfig) bool { return strings.EqualFold(l.Name, lang) }) {
+		checks = append(checks, Problem{Key: "notes.lang", Message: fmt.Sprintf("notes.lang 应该是 all 或 languages 里的语言名，收到的是 %s", lang)})
+	}
+	if t := cfg.Layout.Typography; t.MinSize > t.BaseSize {
+		checks = append(checks, Problem{Key: "layout.typography.min_size", Message: fmt.Sprintf("layout.typography.min_size（%d）不能大于 base_size（%d），否则放不下时没法缩小字号", t.MinSize, t.BaseSize)})
+	}
+	for _, p := range checks {
+		if slices.ContainsFunc(problems, func(q Problem) bool { return q.Key == p.Key }) {
+			continue
+		}
+		if e, ok := entries[p.Key]; ok {
+			p.Line = e.line
+		}
+		problems = append(problems, p)
+	}
+	slices.SortStableFunc(problems, func(a, b Problem) int { return a.Line - b.Line })
+	return problems
+}
+
+func (r fieldRule) check(value string) string {
+	if len(r.Enum) > 0 {
+		if !slices.Contains(r.Enum, strings.ToLower(strings.TrimSpace(value))) {
+			return fmt.Sprintf("只支持 %s，收到的是 %q", strings.Join(r.Enum, "、"), value)
+		}
+		return ""
+	}
+	if r.Pattern != nil {
+		if !r.Pattern.MatchString(value) {
+			return fmt.Sprintf("应该是 6 位十六进制颜色（如 1F2937 或 #1F2937），收到的是 %q", value)
+		}
+		return ""
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		// 类型不对的值解码时已经报过。
+		return ""
+	}
+	low := r.Min != nil && (v < *r.Min || r.Exclusive && v == *r.Min)
+	high := r.Max != nil && (v > *r.Max || r.Exclusive && v == *r.Max)
+	if !low && !high {
+		return ""
+	}
+	switch {
+	case r.Min != nil && r.Max != nil && r.Exclusive:
+		return fmt.Sprintf("应该在 %g 和 %g 之间（不含两端），收到的是 %s", *r.Min, *r.Max, value)
+	case r.Min != nil && r.Max != nil:
+		return fmt.Sprintf("应该在 %g 到 %g 之间，收到的是 %s", *r.Min, *r.Max, value)
+	case r.Exclusive:
+		return fmt.Sprintf("应该大于 %g，收到的是 %s", *r.Min, value)
+	}
+	return fmt.Sprintf("不能小于 %g，收到的是 %s", *r.Min, value)
+}
+
+func syntaxProblem(msg string) Problem {
+	if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return Problem{Line: line, Message: "YAML 写法不对：" + m[2]}
+	}
+	return Problem{Message: "配置文件格式读不懂：" + strings.TrimPrefix(msg, "yaml: ")}
+}
+
+func decodeProblem(msg string) Problem {
+	m := yamlLineRe.FindStringSubmatch(msg)
+	if m == nil {
+		return Problem{Message: "值的类型不对：" + msg}
+	}
+	line, _ := strconv.Atoi(m[1])
+	if f := unknownFieldRe.FindStringSubmatch(m[2]); f != nil {
+		return Problem{Line: line, Key: f[1], Message: "不认识的配置项：" + f[1]}
+	}
+	return Problem{Line: line, Message: "值的类型不对：" + m[2]}
+}
+
+// keyEntry 是文档里写了的一个配置项：所在行和值节点。
+type keyEntry struct {
+	line  int
+	value *yaml.Node
+}
+
+// keyEntries 列出文档里写了的每个配置项，键是用 . 连接的路径，序列元素用下标。
+func keyEntries(doc *yaml.Node) map[string]keyEntry {
+	out := make(map[string]keyEntry)
+	var walk func(prefix string, line int, n *yaml.Node)
+	walk = func(prefix string, line int, n *yaml.Node) {
+		if prefix != "" {
+			out[prefix] = keyEntry{line: line, value: n}
+		}
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(prefix, c.Line, c)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k := n.Content[i]
+				walk(joinKey(prefix, k.Value), k.Line, n.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(joinKey(prefix, strconv.Itoa(i)), c.Line, c)
+			}
+		}
+	}
+	walk("", 0, doc)
+	return out
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// ruleKey 把 styles.markers.star.color、languages.0.name 这样的路径换成 fieldRules 里的写法。
+func ruleKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, p := range parts {
+		if _, err := strconv.Atoi(p); err == nil || i == 2 && strings.HasPrefix(key, "styles.markers.") {
+			parts[i] = "*"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidate_ReportsEachProblemWithLine(t *testing.T) {
+	raw := `layout:
+  columns:
+    left_ratio: 1.5
+    gapp: 0.2
+  typography:
+    base_size: big
+  overflow: wrap
+styles:
+  table:
+    header_fill: "#12345"
+    header_color: "#1F2937"
+output:
+  default_name:
+    template: "{nope}.pptx"
+`
+	problems := Validate([]byte(raw))
+	want := []struct {
+		line int
+		key  string
+	}{
+		{3, "layout.columns.left_ratio"},
+		{4, "layout.columns.gapp"},
+		{6, "layout.typography.base_size"},
+		{7, "layout.overflow"},
+		{10, "styles.table.header_fill"},
+		{14, "output.default_name.template"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, w := range want {
+		if problems[i].Line != w.line || problems[i].Key != w.key {
+			t.Fatalf("problem %d: want line %d %s, got %+v", i, w.line, w.key, problems[i])
+		}
+	}
+	if !strings.Contains(problems[0].String(), "第 3 行") || !strings.Contains(problems[1].Message, "不认识的配置项") {
+		t.Fatalf("unexpected messages: %v", problems)
+	}
+
+	if p := Validate(embeddedDefault); len(p) != 0 {
+		t.Fatalf("embedded default should be valid, got %v", p)
+	}
+	if p := Validate([]byte("layout: [")); len(p) != 1 || p[0].Line != 1 {
+		t.Fatalf("syntax errors should be reported with their line, got %v", p)
+	}
+}
+
+func TestValidate_MinSizeAboveBaseSize(t *testing.T) {
+	raw := "layout:\n  typography:\n    base_size: 14\n    min_size: 16\n"
+	problems := Validate([]byte(raw))
+	if len(problems) != 1 || problems[0].Key != "layout.typography.min_size" || problems[0].Line != 4 {
+		t.Fatalf("min_size above base_size should be reported on its line, got %v", problems)
+	}
+	// 只写 min_size 时和默认的 base_size 比较。
+	if p := Validate([]byte("layout:\n  typography:\n    min_size: 30\n")); len(p) != 1 {
+		t.Fatalf("min_size above the default base_size should be reported, got %v", p)
+	}
+	if p := Validate([]byte("layout:\n  typography:\n    base_size: 14\n    min_size: 14\n")); len(p) != 0 {
+		t.Fatalf("equal sizes are fine, got %v", p)
+	}
+}
+
+func TestSchema_DescribesFieldsAndRules(t *testing.T) {
+	raw, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema returned error: %v", err)
+	}
+	var s struct {
+		AdditionalProperties bool `json:"additionalProperties"`
+		Properties           map[string]struct {
+			Properties map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if s.AdditionalProperties {
+		t.Fatalf("unknown top-level keys should be rejected")
+	}
+	ratio := s.Properties["layout"].Properties["columns"].Properties["left_ratio"]
+	if ratio["type"] != "number" || ratio["exclusiveMaximum"] != 1.0 || ratio["default"] != 0.5 {
+		t.Fatalf("left_ratio should carry its type, range and default: %v", ratio)
+	}
+}
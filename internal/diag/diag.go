@@ -0,0 +1,103 @@
+// Package diag 定义配对、排版、生成各阶段共用的诊断信息和错误类型，
+// 调用方按 Code、Severity 和 errors.Is/As 判断，不用再解析中文提示。
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// 配对阶段的诊断代码。
+const (
+	CodeUnmatchedFile = "unmatched_filename"
+	CodeConflict      = "conflict"
+	CodeMissingPair   = "missing_pair"
+)
+
+// 读配置阶段的诊断代码。
+const (
+	CodeUnknownEnv   = "unknown_env"
+	CodeFontFallback = "font_fallback"
+)
+
+// 排版阶段的诊断类别；完整代码是 类别_语言，如 truncate_en。
+const (
+	KindTruncate = "truncate"
+	KindOverflow = "overflow"
+	KindFormula  = "formula"
+	KindImage    = "image"
+	KindLint     = "lint"
+)
+
+// Sentinel errors，配合 errors.Is 使用。
+var (
+	ErrMissingPair = errors.New("各语言文件没配齐")
+	ErrConflict    = errors.New("配对冲突")
+	ErrNoSources   = errors.New("没找到可用的多语言 Markdown 文件")
+	// ErrStrict 表示严格模式下有告警按错误处理，和其他失败区分开。
+	ErrStrict = errors.New("严格模式下有告警")
+)
+
+// Diagnostic 是一条诊断。Slide 从 1 开始，0 表示和具体页面无关；
+// Paths 是相关的文件（排版告警是所在语言的卡片，冲突是各语言的候选文件）。
+type Diagnostic struct {
+	Code     string   `json:"code" yaml:"code"`
+	Severity Severity `json:"severity" yaml:"severity"`
+	Slide    int      `json:"slide,omitempty" yaml:"slide,omitempty"`
+	Paths    []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+	Group    string   `json:"group,omitempty" yaml:"group,omitempty"`
+	Message  string   `json:"message" yaml:"message"`
+	// Column 是排版时所在语言栏的下标，只在 render 和 app 之间传递。
+	Column int `json:"-" yaml:"-"`
+}
+
+// LangCode 拼出带语言的代码，如 LangCode(KindTruncate, "EN") == "truncate_en"。
+func LangCode(kind, lang string) string {
+	return kind + "_" + strings.ToLower(lang)
+}
+
+// Kind 返回代码的类别；配对阶段的代码原样返回。
+func (d Diagnostic) Kind() string {
+	for _, kind := range []string{KindTruncate, KindOverflow, KindFormula, KindImage, KindLint} {
+		if strings.HasPrefix(d.Code, kind+"_") {
+			return kind
+		}
+	}
+	return d.Code
+}
+
+// String 返回命令行里显示的单行文字：和页面有关的带上页码和文件。
+func (d Diagnostic) String() string {
+	if d.Slide == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("[%3d] - %s %s", d.Slide, strings.Join(d.Paths, " "), d.Message)
+}
+
+// Error 是带诊断明细的错误：errors.Is 判断类别，errors.As 取出明细。
+type Error struct {
+	Err         error
+	Message     string
+	Diagnostics []Diagnostic
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Diagnostics 取出 err 里带的诊断明细，没有时返回 nil。
+func Diagnostics(err error) []Diagnostic {
+	var de *Error
+	if errors.As(err, &de) {
+		return de.Diagnostics
+	}
+	return nil
+}
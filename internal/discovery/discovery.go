@@ -1,21 +1,29 @@
 package discovery
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
 	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
 )
 
 type Pair struct {
-	RelPath  string
-	ENPath   string
-	CNPath   string
-	Numbers  []int
+	RelPath string
+	Paths   []string
+	Numbers []int
+	// Group 是配对用的数字键；Conflict 表示同一数字键下有多个候选，需要人工确认。
+	Group    string
+	Conflict bool
 	sideRank int
 }
 
@@ -33,29 +41,34 @@ type parsedFile struct {
 	sortHint   string
 }
 
-func Discover(source string, cfg *config.Config, opts DiscoverOptions) ([]Pair, []string, error) {
+// Discover 扫描各语言目录并配对。配不齐时返回的错误 errors.Is diag.ErrMissingPair，
+// FailOnConflict 时遇到冲突返回的错误 errors.Is diag.ErrConflict；两者都可以用 diag.Diagnostics 取出明细。
+func Discover(source string, cfg *config.Config, opts DiscoverOptions) ([]Pair, []diag.Diagnostic, error) {
 	if cfg == nil {
 		return nil, nil, fmt.Errorf("配置为空，没法继续")
 	}
-	enRoot := filepath.Join(source, "EN")
-	cnRoot := filepath.Join(source, "CN")
-
-	enGroups, warnEN, err := scanSide(enRoot, cfg)
-	if err != nil {
-		return nil, nil, err
+	langs := cfg.Languages
+	if len(langs) == 0 {
+		langs = config.DefaultLanguages()
 	}
-	cnGroups, warnCN, err := scanSide(cnRoot, cfg)
+
+	ignore, err := loadIgnore(source)
 	if err != nil {
 		return nil, nil, err
 	}
-	warnings := append(warnEN, warnCN...)
-
-	keys := make(map[string]struct{}, len(enGroups)+len(cnGroups))
-	for k := range enGroups {
-		keys[k] = struct{}{}
-	}
-	for k := range cnGroups {
-		keys[k] = struct{}{}
+	sides := make([]map[string][]parsedFile, len(langs))
+	warnings := make([]diag.Diagnostic, 0)
+	keys := make(map[string]struct{})
+	for i, lang := range langs {
+		groups, warn, err := scanSide(filepath.Join(source, lang.Dir), cfg, ignore)
+		if err != nil {
+			return nil, nil, err
+		}
+		sides[i] = groups
+		warnings = append(warnings, warn...)
+		for k := range groups {
+			keys[k] = struct{}{}
+		}
 	}
 	keyList := make([]string, 0, len(keys))
 	for k := range keys {
@@ -64,53 +77,85 @@ func Discover(source string, cfg *config.Config, opts DiscoverOptions) ([]Pair,
 	sort.Strings(keyList)
 
 	pairs := make([]Pair, 0)
-	missing := make([]string, 0)
-	conflicts := make([]string, 0)
+	missing := make([]diag.Diagnostic, 0)
+	conflicts := make([]diag.Diagnostic, 0)
 	for _, key := range keyList {
-		enGroup := enGroups[key]
-		cnGroup := cnGroups[key]
-		switch {
-		case len(enGroup) == 0:
-			missing = append(missing, fmt.Sprintf("英文目录缺少对应编号文件：%s", displayGroupKey(key)))
-			continue
-		case len(cnGroup) == 0:
-			missing = append(missing, fmt.Sprintf("中文目录缺少对应编号文件：%s", displayGroupKey(key)))
+		groups := make([][]parsedFile, len(langs))
+		complete := true
+		for i := range langs {
+			groups[i] = sides[i][key]
+			if len(groups[i]) == 0 {
+				complete = false
+			}
+		}
+		if !complete {
+			for i := range langs {
+				if len(groups[i]) == 0 {
+					missing = append(missing, missingPair(key, groupPaths(groups),
+						fmt.Sprintf("%s 目录缺少对应编号文件：%s", langs[i].Dir, displayGroupKey(key))))
+				}
+			}
 			continue
-		case len(enGroup) != len(cnGroup):
-			missing = append(missing, fmt.Sprintf("中英文编号组文件数量不一致（%s）：EN=%d，CN=%d", displayGroupKey(key), len(enGroup), len(cnGroup)))
+		}
+		if !sameGroupSize(groups) {
+			missing = append(missing, missingPair(key, groupPaths(groups),
+				fmt.Sprintf("各语言编号组文件数量不一致（%s）：%s", displayGroupKey(key), joinGroupCounts(langs, groups))))
 			continue
 		}
 
-		sortParsedFiles(enGroup)
-		sortParsedFiles(cnGroup)
-		if isConflictGroup(enGroup, cnGroup) {
-			conflictMsg := fmt.Sprintf(
-				"冲突组：%s；同一数字键对应多个候选，请人工确认。EN=[%s]；CN=[%s]",
-				displayGroupKey(key),
-				joinRelPaths(enGroup),
-				joinRelPaths(cnGroup),
-			)
-			warnings = append(warnings, conflictMsg)
-			conflicts = append(conflicts, conflictMsg)
+		for _, g := range groups {
+			sortParsedFiles(g)
+		}
+		conflict := isConflictGroup(groups)
+		if conflict {
+			d := diag.Diagnostic{
+				Code:     diag.CodeConflict,
+				Severity: diag.SeverityWarning,
+				Paths:    groupPaths(groups),
+				Group:    displayGroupKey(key),
+				Message: fmt.Sprintf(
+					"冲突组：%s；同一数字键对应多个候选，请人工确认。%s",
+					displayGroupKey(key),
+					joinGroupPaths(langs, groups),
+				),
+			}
+			warnings = append(warnings, d)
+			conflicts = append(conflicts, d)
 		}
-		for i := range enGroup {
+		for i := range groups[0] {
+			paths := make([]string, len(groups))
+			for j, g := range groups {
+				paths[j] = g[i].absPath
+			}
 			pairs = append(pairs, Pair{
-				RelPath:  enGroup[i].relPath,
-				ENPath:   enGroup[i].absPath,
-				CNPath:   cnGroup[i].absPath,
-				Numbers:  enGroup[i].numberList,
-				sideRank: enGroup[i].sideRank,
+				RelPath:  groups[0][i].relPath,
+				Paths:    paths,
+				Numbers:  groups[0][i].numberList,
+				Group:    displayGroupKey(key),
+				Conflict: conflict,
+				sideRank: groups[0][i].sideRank,
 			})
 		}
 	}
 
 	if len(missing) > 0 {
-		sort.Strings(missing)
-		return nil, warnings, fmt.Errorf("中英文文件没配齐：%s", strings.Join(missing, "；"))
+		sortByMessage(missing)
+		return nil, warnings, &diag.Error{
+			Err:         diag.ErrMissingPair,
+			Message:     fmt.Sprintf("%s：%s", diag.ErrMissingPair, joinMessages(missing)),
+			Diagnostics: missing,
+		}
 	}
 	if opts.FailOnConflict && len(conflicts) > 0 {
-		sort.Strings(conflicts)
-		return nil, warnings, fmt.Errorf("发现 %d 组配对冲突：%s", len(conflicts), strings.Join(conflicts, "；"))
+		sortByMessage(conflicts)
+		for i := range conflicts {
+			conflicts[i].Severity = diag.SeverityError
+		}
+		return nil, warnings, &diag.Error{
+			Err:         diag.ErrConflict,
+			Message:     fmt.Sprintf("发现 %d 组%s：%s", len(conflicts), diag.ErrConflict, joinMessages(conflicts)),
+			Diagnostics: conflicts,
+		}
 	}
 
 	sort.Slice(pairs, func(i, j int) bool {
@@ -126,31 +171,126 @@ func Discover(source string, cfg *config.Config, opts DiscoverOptions) ([]Pair,
 	return pairs, warnings, nil
 }
 
-func scanSide(root string, cfg *config.Config) (map[string][]parsedFile, []string, error) {
+func missingPair(key string, paths []string, msg string) diag.Diagnostic {
+	return diag.Diagnostic{
+		Code:     diag.CodeMissingPair,
+		Severity: diag.SeverityError,
+		Paths:    paths,
+		Group:    displayGroupKey(key),
+		Message:  msg,
+	}
+}
+
+func groupPaths(groups [][]parsedFile) []string {
+	paths := make([]string, 0)
+	for _, g := range groups {
+		for _, f := range g {
+			paths = append(paths, f.absPath)
+		}
+	}
+	return paths
+}
+
+func sortByMessage(in []diag.Diagnostic) {
+	sort.Slice(in, func(i, j int) bool { return in[i].Message < in[j].Message })
+}
+
+func joinMessages(in []diag.Diagnostic) string {
+	msgs := make([]string, len(in))
+	for i, d := range in {
+		msgs[i] = d.Message
+	}
+	return strings.Join(msgs, "；")
+}
+
+// IgnoreFile 是数据源根目录下的忽略规则，每行一个通配符，# 开头为注释。
+// 不含 / 的规则按文件名或目录名匹配，含 / 的按语言目录下的相对路径匹配；以 / 结尾的只匹配目录。
+const IgnoreFile = ".md2pptignore"
+
+type ignoreRule struct {
+	pattern string
+	dirOnly bool
+	rooted  bool
+}
+
+func loadIgnore(source string) ([]ignoreRule, error) {
+	data, err := os.ReadFile(filepath.Join(source, IgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败：%w", IgnoreFile, err)
+	}
+	var rules []ignoreRule
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule := ignoreRule{pattern: strings.TrimPrefix(text, "/")}
+		if strings.HasSuffix(rule.pattern, "/") {
+			rule.dirOnly = true
+			rule.pattern = strings.TrimRight(rule.pattern, "/")
+		}
+		rule.rooted = strings.Contains(rule.pattern, "/") || strings.HasPrefix(text, "/")
+		if _, err := path.Match(rule.pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s 第 %d 行的通配符写错了：%s", IgnoreFile, line, text)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ignored 判断语言目录下的相对路径 rel 是否被忽略。
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		target := path.Base(rel)
+		if r.rooted {
+			target = rel
+		}
+		if ok, _ := path.Match(r.pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func scanSide(root string, cfg *config.Config, ignore []ignoreRule) (map[string][]parsedFile, []diag.Diagnostic, error) {
 	entries := make(map[string][]parsedFile)
-	warnings := make([]string, 0)
+	warnings := make([]diag.Diagnostic, 0)
 
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
-		if d.IsDir() {
-			return nil
-		}
-		if strings.ToLower(filepath.Ext(d.Name())) != ".md" {
-			return nil
-		}
-
 		rel, err := filepath.Rel(root, path)
 		if err != nil {
 			return err
 		}
 		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && ignored(ignore, rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.ToLower(filepath.Ext(d.Name())) != ".md" || ignored(ignore, rel, false) {
+			return nil
+		}
 
 		numberKey, numberList := numberFingerprint(filepath.Base(path))
 		if numberKey == "" {
 			if cfg.Filename.IgnoreUnmatched {
-				warnings = append(warnings, fmt.Sprintf("这个文件名里没有可配对的唯一数字，先跳过：%s", rel))
+				warnings = append(warnings, diag.Diagnostic{
+					Code:     diag.CodeUnmatchedFile,
+					Severity: diag.SeverityWarning,
+					Paths:    []string{path},
+					Message:  fmt.Sprintf("这个文件名里没有可配对的唯一数字，先跳过：%s", rel),
+				})
 				return nil
 			}
 			return fmt.Errorf("文件名里没有可配对的唯一数字：%s", rel)
@@ -365,24 +505,40 @@ func compareIntSlice(a, b []int) int {
 	return 0
 }
 
-func isConflictGroup(enGroup, cnGroup []parsedFile) bool {
-	if len(enGroup) <= 1 && len(cnGroup) <= 1 {
-		return false
-	}
-	if len(enGroup) > 2 || len(cnGroup) > 2 {
-		return true
+func sameGroupSize(groups [][]parsedFile) bool {
+	for _, g := range groups[1:] {
+		if len(g) != len(groups[0]) {
+			return false
+		}
 	}
-	ef, eb, eu := sideStats(enGroup)
-	cf, cb, cu := sideStats(cnGroup)
+	return true
+}
 
-	if ef > 1 || eb > 1 || cf > 1 || cb > 1 {
-		return true
+func isConflictGroup(groups [][]parsedFile) bool {
+	multi := false
+	for _, g := range groups {
+		if len(g) > 2 {
+			return true
+		}
+		if len(g) > 1 {
+			multi = true
+		}
 	}
-	if (eu > 0 && len(enGroup) > 1) || (cu > 0 && len(cnGroup) > 1) {
-		return true
+	if !multi {
+		return false
 	}
-	if ef != cf || eb != cb {
-		return true
+	f0, b0, _ := sideStats(groups[0])
+	for _, g := range groups {
+		f, b, u := sideStats(g)
+		if f > 1 || b > 1 {
+			return true
+		}
+		if u > 0 && len(g) > 1 {
+			return true
+		}
+		if f != f0 || b != b0 {
+			return true
+		}
 	}
 	return false
 }
@@ -411,3 +567,19 @@ func joinRelPaths(group []parsedFile) string {
 	}
 	return strings.Join(parts, ", ")
 }
+
+func joinGroupPaths(langs []config.LanguageConfig, groups [][]parsedFile) string {
+	parts := make([]string, 0, len(groups))
+	for i, g := range groups {
+		parts = append(parts, fmt.Sprintf("%s=[%s]", langs[i].Name, joinRelPaths(g)))
+	}
+	return strings.Join(parts, "；")
+}
+
+func joinGroupCounts(langs []config.LanguageConfig, groups [][]parsedFile) string {
+	parts := make([]string, 0, len(groups))
+	for i, g := range groups {
+		parts = append(parts, fmt.Sprintf("%s=%d", langs[i].Name, len(g)))
+	}
+	return strings.Join(parts, "，")
+}
@@ -1,12 +1,14 @@
 package discovery
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
 )
 
 func TestNumberFingerprintUsesNonRepeatedNumbers(t *testing.T) {
@@ -60,8 +62,8 @@ func TestDiscoverSortAndPairByUniqueNumbers(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Discover returned error: %v", err)
 	}
-	if len(warnings) != 1 {
-		t.Fatalf("expected 1 warning for unmatched file, got %d", len(warnings))
+	if len(warnings) != 1 || warnings[0].Code != diag.CodeUnmatchedFile {
+		t.Fatalf("expected 1 warning for unmatched file, got %#v", warnings)
 	}
 	if len(pairs) != 3 {
 		t.Fatalf("expected 3 pairs, got %d", len(pairs))
@@ -129,8 +131,8 @@ func TestDiscoverFailsOnMissingPair(t *testing.T) {
 	cfg.Filename.IgnoreUnmatched = true
 
 	_, _, err := Discover(source, cfg, DiscoverOptions{})
-	if err == nil {
-		t.Fatalf("expected pairing error, got nil")
+	if !errors.Is(err, diag.ErrMissingPair) {
+		t.Fatalf("expected missing pair error, got %v", err)
 	}
 }
 
@@ -161,7 +163,7 @@ func TestDiscoverWarnsOnConflictGroup(t *testing.T) {
 	}
 	hasConflict := false
 	for _, w := range warnings {
-		if strings.Contains(w, "冲突组：") && strings.Contains(w, "D/12") {
+		if w.Code == diag.CodeConflict && w.Group == "D/12" && len(w.Paths) == 4 {
 			hasConflict = true
 			break
 		}
@@ -169,6 +171,11 @@ func TestDiscoverWarnsOnConflictGroup(t *testing.T) {
 	if !hasConflict {
 		t.Fatalf("expected conflict warning, got: %#v", warnings)
 	}
+
+	_, _, err = Discover(source, cfg, DiscoverOptions{FailOnConflict: true})
+	if !errors.Is(err, diag.ErrConflict) || len(diag.Diagnostics(err)) != 1 {
+		t.Fatalf("expected conflict error with details, got: %v", err)
+	}
 }
 
 func TestDiscoverPairsDifferentZeroPadding(t *testing.T) {
@@ -200,6 +207,83 @@ func TestDiscoverPairsDifferentZeroPadding(t *testing.T) {
 	}
 }
 
+func TestDiscoverMatchesConfiguredLanguages(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	for _, dir := range []string{"EN", "JA", "KO"} {
+		if err := os.MkdirAll(filepath.Join(source, dir, "D"), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	mustWrite(t, filepath.Join(source, "EN", "D", "1-002-Front.md"), "EN")
+	mustWrite(t, filepath.Join(source, "JA", "D", "1-002-Front.md"), "JA")
+	mustWrite(t, filepath.Join(source, "KO", "D", "1-002-Front.md"), "KO")
+
+	cfg := &config.Config{}
+	cfg.Filename.IgnoreUnmatched = true
+	cfg.Languages = []config.LanguageConfig{
+		{Name: "EN", Dir: "EN"},
+		{Name: "JA", Dir: "JA"},
+		{Name: "KO", Dir: "KO"},
+	}
+	pairs, _, err := Discover(source, cfg, DiscoverOptions{})
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(pairs) != 1 || len(pairs[0].Paths) != 3 {
+		t.Fatalf("expected one group with 3 paths, got %#v", pairs)
+	}
+	for i, dir := range []string{"EN", "JA", "KO"} {
+		if !strings.Contains(pairs[0].Paths[i], string(filepath.Separator)+dir+string(filepath.Separator)) {
+			t.Fatalf("path %d should come from %s, got %s", i, dir, pairs[0].Paths[i])
+		}
+	}
+
+	if err := os.Remove(filepath.Join(source, "KO", "D", "1-002-Front.md")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	_, _, err = Discover(source, cfg, DiscoverOptions{})
+	if err == nil || !strings.Contains(err.Error(), "KO 目录缺少对应编号文件") {
+		t.Fatalf("expected missing KO error, got: %v", err)
+	}
+	missing := diag.Diagnostics(err)
+	if len(missing) != 1 || missing[0].Code != diag.CodeMissingPair || missing[0].Group != "D/1-2" || len(missing[0].Paths) != 2 {
+		t.Fatalf("expected one missing_pair diagnostic, got: %#v", missing)
+	}
+}
+
+func TestDiscoverHonorsIgnoreFile(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "SPI")
+	for _, dir := range []string{"EN/D/drafts", "CN/D/drafts", "EN/Old", "CN/Old"} {
+		if err := os.MkdirAll(filepath.Join(source, dir), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	for _, lang := range []string{"EN", "CN"} {
+		mustWrite(t, filepath.Join(source, lang, "D", "0-001-Front.md"), lang+" front")
+		mustWrite(t, filepath.Join(source, lang, "D", "drafts", "0-002-Front.md"), "draft")
+		mustWrite(t, filepath.Join(source, lang, "Old", "0-003-Front.md"), "old")
+	}
+	// 只有 EN 有的草稿被忽略后不算缺文件。
+	mustWrite(t, filepath.Join(source, "EN", "D", "0-004-Front.draft.md"), "draft")
+	mustWrite(t, filepath.Join(source, IgnoreFile), "# 草稿\ndrafts/\n*.draft.md\n/Old/0-003-*\n")
+
+	cfg := &config.Config{}
+	pairs, warnings, err := Discover(source, cfg, DiscoverOptions{})
+	if err != nil {
+		t.Fatalf("Discover returned error: %v", err)
+	}
+	if len(pairs) != 1 || pairs[0].RelPath != "D/0-001-Front.md" || len(warnings) != 0 {
+		t.Fatalf("ignored files should be skipped, got %#v %#v", pairs, warnings)
+	}
+
+	mustWrite(t, filepath.Join(source, IgnoreFile), "[oops\n")
+	if _, _, err := Discover(source, cfg, DiscoverOptions{}); err == nil || !strings.Contains(err.Error(), "第 1 行") {
+		t.Fatalf("a malformed pattern should be reported with its line, got %v", err)
+	}
+}
+
 func mustWrite(t *testing.T, path, data string) {
 	t.Helper()
 	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
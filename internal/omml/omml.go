@@ -0,0 +1,604 @@
+// Package omml 把常用的 LaTeX 公式子集转换成 Office Math（OMML）。
+package omml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Namespace 是 OMML 的命名空间。
+const Namespace = "http://schemas.openxmlformats.org/officeDocument/2006/math"
+
+// RunProps 是 rPr 的占位符：排版时转换一次存下来，写出时用 strings.ReplaceAll 换成实际的字号和颜色。
+const RunProps = "<!--rPr-->"
+
+// Convert 把 LaTeX 转成 <m:oMath> 片段；rPr 会原样写进每个 m:r，用来带上字号和字体。
+func Convert(latex string, rPr string) (string, error) {
+	p := &parser{src: []rune(latex), rPr: rPr}
+	nodes, err := p.parseSeq(0)
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.src) {
+		return "", fmt.Errorf("多余的 %q", string(p.src[p.pos]))
+	}
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("公式为空")
+	}
+	var b strings.Builder
+	b.WriteString(`<m:oMath xmlns:m="` + Namespace + `">`)
+	for _, n := range nodes {
+		n.write(&b, rPr)
+	}
+	b.WriteString(`</m:oMath>`)
+	return b.String(), nil
+}
+
+type node interface {
+	write(b *strings.Builder, rPr string)
+}
+
+// run 是一段连续文本；plain 表示正体（函数名、\text）。
+type run struct {
+	text  string
+	plain bool
+}
+
+type frac struct{ num, den []node }
+
+type rad struct{ deg, body []node }
+
+type script struct {
+	base     []node
+	sub, sup []node
+}
+
+type nary struct {
+	chr      string
+	sub, sup []node
+	body     []node
+}
+
+type limLow struct{ base, lim []node }
+
+type delim struct {
+	beg, end string
+	body     []node
+}
+
+type accent struct {
+	chr  string
+	bar  bool
+	body []node
+}
+
+func (r run) write(b *strings.Builder, rPr string) {
+	b.WriteString(`<m:r>`)
+	if r.plain {
+		b.WriteString(`<m:rPr><m:sty m:val="p"/></m:rPr>`)
+	}
+	b.WriteString(rPr)
+	b.WriteString(`<m:t>` + escape(r.text) + `</m:t></m:r>`)
+}
+
+func (f frac) write(b *strings.Builder, rPr string) {
+	b.WriteString(`<m:f>`)
+	writeArg(b, "m:num", f.num, rPr)
+	writeArg(b, "m:den", f.den, rPr)
+	b.WriteString(`</m:f>`)
+}
+
+func (r rad) write(b *strings.Builder, rPr string) {
+	b.WriteString(`<m:rad>`)
+	if len(r.deg) == 0 {
+		b.WriteString(`<m:radPr><m:degHide m:val="1"/></m:radPr>`)
+	}
+	writeArg(b, "m:deg", r.deg, rPr)
+	writeArg(b, "m:e", r.body, rPr)
+	b.WriteString(`</m:rad>`)
+}
+
+func (s script) write(b *strings.Builder, rPr string) {
+	switch {
+	case len(s.sub) > 0 && len(s.sup) > 0:
+		b.WriteString(`<m:sSubSup>`)
+		writeArg(b, "m:e", s.base, rPr)
+		writeArg(b, "m:sub", s.sub, rPr)
+		writeArg(b, "m:sup", s.sup, rPr)
+		b.WriteString(`</m:sSubSup>`)
+	case len(s.sub) > 0:
+		b.WriteString(`<m:sSub>`)
+		writeArg(b, "m:e", s.base, rPr)
+		writeArg(b, "m:sub", s.sub, rPr)
+		b.WriteString(`</m:sSub>`)
+	default:
+		b.WriteString(`<m:sSup>`)
+		writeArg(b, "m:e", s.base, rPr)
+		writeArg(b, "m:sup", s.sup, rPr)
+		b.WriteString(`</m:sSup>`)
+	}
+}
+
+func (n nary) write(b *strings.Builder, rPr string) {
+	b.WriteString(`<m:nary><m:naryPr><m:chr m:val="` + escape(n.chr) + `"/>`)
+	if len(n.sub) == 0 {
+		b.WriteString(`<m:subHide m:val="1"/>`)
+	}
+	if len(n.sup) == 0 {
+		b.WriteString(`<m:supHide m:val="1"/>`)
+	}
+	b.WriteString(`</m:naryPr>`)
+	writeArg(b, "m:sub", n.sub, rPr)
+	writeArg(b, "m:sup", n.sup, rPr)
+	writeArg(b, "m:e", n.body, rPr)
+	b.WriteString(`</m:nary>`)
+}
+
+func (l limLow) write(b *strings.Builder, rPr string) {
+	b.WriteString(`<m:limLow>`)
+	writeArg(b, "m:e", l.base, rPr)
+	writeArg(b, "m:lim", l.lim, rPr)
+	b.WriteString(`</m:limLow>`)
+}
+
+func (d delim) write(b *strings.Builder, rPr string) {
+	b.WriteString(`<m:d><m:dPr><m:begChr m:val="` + escape(d.beg) + `"/><m:endChr m:val="` + escape(d.end) + `"/></m:dPr>`)
+	writeArg(b, "m:e", d.body, rPr)
+	b.WriteString(`</m:d>`)
+}
+
+func (a accent) write(b *strings.Builder, rPr string) {
+	if a.bar {
+		b.WriteString(`<m:bar><m:barPr><m:pos m:val="top"/></m:barPr>`)
+		writeArg(b, "m:e", a.body, rPr)
+		b.WriteString(`</m:bar>`)
+		return
+	}
+	b.WriteString(`<m:acc><m:accPr><m:chr m:val="` + escape(a.chr) + `"/></m:accPr>`)
+	writeArg(b, "m:e", a.body, rPr)
+	b.WriteString(`</m:acc>`)
+}
+
+func writeArg(b *strings.Builder, tag string, nodes []node, rPr string) {
+	b.WriteString(`<` + tag + `>`)
+	for _, n := range nodes {
+		n.write(b, rPr)
+	}
+	b.WriteString(`</` + tag + `>`)
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+type parser struct {
+	src []rune
+	pos int
+	rPr string
+}
+
+// 结束符：0 表示读到结尾，其余为 '}'、']' 或 \right。
+const (
+	stopEOF = iota
+	stopBrace
+	stopBracket
+	stopRight
+)
+
+func (p *parser) parseSeq(stop int) ([]node, error) {
+	nodes := make([]node, 0)
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			if stop != stopEOF {
+				return nil, fmt.Errorf("括号没有闭合")
+			}
+			return mergeRuns(nodes), nil
+		}
+		ch := p.src[p.pos]
+		switch {
+		case ch == '}':
+			if stop != stopBrace {
+				return nil, fmt.Errorf("多余的 }")
+			}
+			return mergeRuns(nodes), nil
+		case ch == ']' && stop == stopBracket:
+			return mergeRuns(nodes), nil
+		case stop == stopRight && p.peekCommand() == "right":
+			return mergeRuns(nodes), nil
+		}
+
+		n, err := p.parseItem(stop)
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+}
+
+// parseItem 读一个原子，并带上后面的上下标。
+func (p *parser) parseItem(stop int) (node, error) {
+	p.skipSpace()
+	ch := p.src[p.pos]
+	if ch == '^' || ch == '_' {
+		// 没有底数的上下标挂在空文本上。
+		return p.parseScripts(nil)
+	}
+	atom, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+	if atom == nil {
+		return nil, nil
+	}
+	switch a := atom.(type) {
+	case *nary:
+		if err := p.parseLimits(&a.sub, &a.sup); err != nil {
+			return nil, err
+		}
+		// 求和号的作用范围一直到同层的下一个加减号、关系符或闭合括号，圆括号和方括号里的不算。
+		var body []node
+		depth := 0
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.src) {
+				break
+			}
+			ch := p.src[p.pos]
+			if p.atStop(stop) && (depth == 0 || ch != ']') || depth == 0 && len(body) > 0 && p.atBinaryOp() {
+				break
+			}
+			switch ch {
+			case '(', '[':
+				depth++
+			case ')', ']':
+				if depth > 0 {
+					depth--
+				}
+			}
+			n, err := p.parseItem(stop)
+			if err != nil {
+				return nil, err
+			}
+			if n != nil {
+				body = append(body, n)
+			}
+		}
+		a.body = mergeRuns(body)
+		return *a, nil
+	case *limLow:
+		var sup []node
+		if err := p.parseLimits(&a.lim, &sup); err != nil {
+			return nil, err
+		}
+		if len(a.lim) == 0 {
+			return run{text: "lim", plain: true}, nil
+		}
+		return *a, nil
+	}
+	return p.parseScripts([]node{atom})
+}
+
+func (p *parser) parseScripts(base []node) (node, error) {
+	s := script{base: base}
+	if err := p.parseLimits(&s.sub, &s.sup); err != nil {
+		return nil, err
+	}
+	if len(s.sub) == 0 && len(s.sup) == 0 {
+		if len(base) == 0 {
+			return nil, nil
+		}
+		return base[0], nil
+	}
+	if len(s.base) == 0 {
+		s.base = []node{run{text: ""}}
+	}
+	return s, nil
+}
+
+func (p *parser) parseLimits(sub, sup *[]node) error {
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil
+		}
+		var target *[]node
+		switch p.src[p.pos] {
+		case '_':
+			target = sub
+		case '^':
+			target = sup
+		default:
+			return nil
+		}
+		if len(*target) > 0 {
+			return fmt.Errorf("上下标重复")
+		}
+		p.pos++
+		arg, err := p.parseArg()
+		if err != nil {
+			return err
+		}
+		*target = arg
+	}
+}
+
+// parseArg 读一个参数：花括号分组或单个原子。
+func (p *parser) parseArg() ([]node, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("缺少参数")
+	}
+	if p.src[p.pos] == '{' {
+		p.pos++
+		nodes, err := p.parseSeq(stopBrace)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		return nodes, nil
+	}
+	atom, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+	if atom == nil {
+		return nil, fmt.Errorf("缺少参数")
+	}
+	return []node{atom}, nil
+}
+
+func (p *parser) parseAtom() (node, error) {
+	ch := p.src[p.pos]
+	switch {
+	case ch == '{':
+		p.pos++
+		nodes, err := p.parseSeq(stopBrace)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		if len(nodes) == 1 {
+			return nodes[0], nil
+		}
+		return delim{body: nodes}, nil
+	case ch == '\\':
+		return p.parseCommand()
+	case ch == '}' || ch == '^' || ch == '_':
+		return nil, fmt.Errorf("位置不对的 %q", string(ch))
+	case ch == '&' || ch == '#' || ch == '%':
+		return nil, fmt.Errorf("不支持的字符 %q", string(ch))
+	}
+	p.pos++
+	return run{text: string(ch)}, nil
+}
+
+// atStop 判断是否到了当前分组的结束符；多余的 } 留给 parseSeq 报错。
+func (p *parser) atStop(stop int) bool {
+	switch ch := p.src[p.pos]; {
+	case ch == '}':
+		return true
+	case ch == ']':
+		return stop == stopBracket
+	}
+	return stop == stopRight && p.peekCommand() == "right"
+}
+
+// atBinaryOp 判断下一个符号是不是加减号或关系符。
+func (p *parser) atBinaryOp() bool {
+	if strings.ContainsRune("+-=<>,", p.src[p.pos]) {
+		return true
+	}
+	_, ok := binaryOps[p.peekCommand()]
+	return ok
+}
+
+func (p *parser) peekCommand() string {
+	if p.pos >= len(p.src) || p.src[p.pos] != '\\' {
+		return ""
+	}
+	i := p.pos + 1
+	for i < len(p.src) && unicode.IsLetter(p.src[i]) {
+		i++
+	}
+	return string(p.src[p.pos+1 : i])
+}
+
+func (p *parser) parseCommand() (node, error) {
+	p.pos++ // 跳过反斜杠
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("公式以反斜杠结尾")
+	}
+	if !unicode.IsLetter(p.src[p.pos]) {
+		ch := p.src[p.pos]
+		p.pos++
+		switch ch {
+		case ',', ';', ':', ' ':
+			return run{text: " "}, nil
+		case '!':
+			return nil, nil
+		case '{', '}', '%', '&', '#', '_', '$', '|':
+			return run{text: string(ch)}, nil
+		case '\\':
+			return nil, fmt.Errorf("不支持换行 \\\\")
+		}
+		return nil, fmt.Errorf("不认识的命令 \\%s", string(ch))
+	}
+	name := p.peekName()
+
+	if sym, ok := symbols[name]; ok {
+		return run{text: sym}, nil
+	}
+	if chr, ok := naryOps[name]; ok {
+		return &nary{chr: chr}, nil
+	}
+	if _, ok := functions[name]; ok {
+		if name == "lim" {
+			return &limLow{base: []node{run{text: "lim", plain: true}}}, nil
+		}
+		return run{text: name, plain: true}, nil
+	}
+	if chr, ok := accents[name]; ok {
+		body, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		return accent{chr: chr, bar: chr == "", body: body}, nil
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac":
+		num, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		den, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		return frac{num: num, den: den}, nil
+	case "sqrt":
+		var deg []node
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			p.pos++
+			d, err := p.parseSeq(stopBracket)
+			if err != nil {
+				return nil, err
+			}
+			p.pos++
+			deg = d
+		}
+		body, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		return rad{deg: deg, body: body}, nil
+	case "text", "mathrm", "textrm", "operatorname":
+		text, err := p.parseRawGroup()
+		if err != nil {
+			return nil, err
+		}
+		return run{text: text, plain: true}, nil
+	case "left":
+		beg, err := p.parseDelimiter()
+		if err != nil {
+			return nil, err
+		}
+		body, err := p.parseSeq(stopRight)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++ // 跳过 \right
+		p.peekName()
+		end, err := p.parseDelimiter()
+		if err != nil {
+			return nil, err
+		}
+		return delim{beg: beg, end: end, body: body}, nil
+	case "right":
+		return nil, fmt.Errorf("\\right 前面缺少 \\left")
+	case "quad", "qquad":
+		return run{text: "  "}, nil
+	case "displaystyle", "textstyle":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("不认识的命令 \\%s", name)
+}
+
+// peekName 读出命令名并前移。
+func (p *parser) peekName() string {
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// parseRawGroup 读取 {...} 里的原文，用于 \text。
+func (p *parser) parseRawGroup() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return "", fmt.Errorf("\\text 后面要跟 {...}")
+	}
+	depth := 0
+	start := p.pos + 1
+	for i := p.pos; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				p.pos = i + 1
+				return string(p.src[start:i]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("括号没有闭合")
+}
+
+func (p *parser) parseDelimiter() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("缺少定界符")
+	}
+	ch := p.src[p.pos]
+	p.pos++
+	switch ch {
+	case '(', ')', '[', ']', '|':
+		return string(ch), nil
+	case '.':
+		return "", nil
+	case '\\':
+		if p.pos < len(p.src) && (p.src[p.pos] == '{' || p.src[p.pos] == '}' || p.src[p.pos] == '|') {
+			ch = p.src[p.pos]
+			p.pos++
+			if ch == '|' {
+				return "‖", nil
+			}
+			return string(ch), nil
+		}
+		switch name := p.peekName(); name {
+		case "langle":
+			return "⟨", nil
+		case "rangle":
+			return "⟩", nil
+		case "lvert", "rvert", "vert":
+			return "|", nil
+		case "lVert", "rVert", "Vert":
+			return "‖", nil
+		}
+	}
+	return "", fmt.Errorf("不支持的定界符")
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// mergeRuns 把相邻的同类文本合成一个 m:r。
+func mergeRuns(nodes []node) []node {
+	out := make([]node, 0, len(nodes))
+	for _, n := range nodes {
+		r, ok := n.(run)
+		if ok && len(out) > 0 {
+			if prev, ok := out[len(out)-1].(run); ok && prev.plain == r.plain {
+				prev.text += r.text
+				out[len(out)-1] = prev
+				continue
+			}
+		}
+		out = append(out, n)
+	}
+	return out
+}
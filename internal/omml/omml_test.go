@@ -0,0 +1,60 @@
+package omml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		src  string
+		want []string
+	}{
+		{`\frac{a}{b}`, []string{`<m:f><m:num><m:r><m:t>a</m:t></m:r></m:num><m:den><m:r><m:t>b</m:t></m:r></m:den></m:f>`}},
+		{`x_i^2`, []string{`<m:sSubSup><m:e><m:r><m:t>x</m:t></m:r></m:e><m:sub><m:r><m:t>i</m:t></m:r></m:sub><m:sup><m:r><m:t>2</m:t></m:r></m:sup></m:sSubSup>`}},
+		{`10^{-3}`, []string{`<m:r><m:t>1</m:t></m:r>`, `<m:sSup><m:e><m:r><m:t>0</m:t></m:r></m:e><m:sup><m:r><m:t>-3</m:t></m:r></m:sup></m:sSup>`}},
+		{`\sqrt{2}`, []string{`<m:rad><m:radPr><m:degHide m:val="1"/></m:radPr><m:deg></m:deg><m:e><m:r><m:t>2</m:t></m:r></m:e></m:rad>`}},
+		{`\sqrt[3]{x}`, []string{`<m:deg><m:r><m:t>3</m:t></m:r></m:deg>`}},
+		{`\alpha + \Omega \leq \infty`, []string{`<m:t>α+Ω≤∞</m:t>`}},
+		{`\sum_{i=1}^{n} x_i`, []string{`<m:nary><m:naryPr><m:chr m:val="∑"/></m:naryPr><m:sub><m:r><m:t>i=1</m:t></m:r></m:sub><m:sup><m:r><m:t>n</m:t></m:r></m:sup><m:e><m:sSub>`}},
+		{`\int f`, []string{`<m:subHide m:val="1"/><m:supHide m:val="1"/>`}},
+		{`\sin x \times \text{a b}`, []string{`<m:r><m:rPr><m:sty m:val="p"/></m:rPr><m:t>sin</m:t></m:r><m:r><m:t>x×</m:t></m:r><m:r><m:rPr><m:sty m:val="p"/></m:rPr><m:t>a b</m:t></m:r>`}},
+		{`\left( a < b \right)`, []string{`<m:d><m:dPr><m:begChr m:val="("/><m:endChr m:val=")"/></m:dPr><m:e><m:r><m:t>a&lt;b</m:t></m:r></m:e></m:d>`}},
+		{`\hat{x}`, []string{`<m:acc><m:accPr><m:chr m:val="̂"/></m:accPr>`}},
+		{`x^{}`, []string{`<m:r><m:t>x</m:t></m:r></m:oMath>`}},
+		{`\sum_{i=1}^{N}(x_i-\mu)^2 + 1`, []string{`<m:e><m:r><m:t>(</m:t></m:r><m:sSub><m:e><m:r><m:t>x</m:t></m:r></m:e><m:sub><m:r><m:t>i</m:t></m:r></m:sub></m:sSub><m:r><m:t>-μ</m:t></m:r><m:sSup><m:e><m:r><m:t>)</m:t></m:r></m:e><m:sup><m:r><m:t>2</m:t></m:r></m:sup></m:sSup></m:e></m:nary><m:r><m:t>+1</m:t></m:r>`}},
+		{`{\sum_i a_i} = b`, []string{`</m:e></m:nary><m:r><m:t>=b</m:t></m:r>`}},
+	}
+	for _, c := range cases {
+		got, err := Convert(c.src, "")
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", c.src, err)
+		}
+		if !strings.HasPrefix(got, `<m:oMath xmlns:m="`+Namespace+`">`) {
+			t.Fatalf("%s: missing oMath root: %s", c.src, got)
+		}
+		for _, w := range c.want {
+			if !strings.Contains(got, w) {
+				t.Fatalf("%s: want %s in %s", c.src, w, got)
+			}
+		}
+	}
+}
+
+func TestConvertKeepsRunProperties(t *testing.T) {
+	got, err := Convert("x", `<a:rPr sz="2000"/>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, `<m:r><a:rPr sz="2000"/><m:t>x</m:t></m:r>`) {
+		t.Fatalf("rPr should be written into each run: %s", got)
+	}
+}
+
+func TestConvertRejectsUnsupported(t *testing.T) {
+	for _, src := range []string{``, `\frac{a}`, `{x`, `x}`, `\foo`, `a & b`, `x^1^2`, `\left( x`, `a \\ b`, `^{}`, `_{}`} {
+		if _, err := Convert(src, ""); err == nil {
+			t.Fatalf("%q: expected error", src)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package omml
+
+var symbols = map[string]string{
+	// 希腊字母
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "varpi": "ϖ", "rho": "ρ",
+	"varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+
+	// 运算符和关系符
+	"times": "×", "cdot": "⋅", "div": "÷", "pm": "±", "mp": "∓", "ast": "∗",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "simeq": "≃", "propto": "∝",
+	"ll": "≪", "gg": "≫", "lt": "<", "gt": ">",
+	"in": "∈", "notin": "∉", "subset": "⊂", "subseteq": "⊆", "supset": "⊃",
+	"cup": "∪", "cap": "∩", "setminus": "∖", "emptyset": "∅",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "Rightarrow": "⇒", "Leftarrow": "⇐",
+	"leftrightarrow": "↔", "Leftrightarrow": "⇔", "mapsto": "↦",
+	"forall": "∀", "exists": "∃", "neg": "¬", "land": "∧", "lor": "∨",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "prime": "′", "degree": "°",
+	"ldots": "…", "cdots": "⋯", "dots": "…", "vdots": "⋮",
+	"mid": "|", "vert": "|", "lvert": "|", "rvert": "|", "langle": "⟨", "rangle": "⟩",
+}
+
+// binaryOps 结束求和号的作用范围。
+var binaryOps = map[string]struct{}{
+	"pm": {}, "mp": {}, "leq": {}, "le": {}, "geq": {}, "ge": {}, "neq": {}, "ne": {},
+	"approx": {}, "equiv": {}, "sim": {}, "simeq": {}, "propto": {}, "ll": {}, "gg": {},
+	"lt": {}, "gt": {}, "to": {}, "rightarrow": {}, "leftarrow": {}, "Rightarrow": {},
+	"Leftarrow": {}, "leftrightarrow": {}, "Leftrightarrow": {}, "mapsto": {},
+}
+
+var naryOps = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+	"bigcup": "⋃", "bigcap": "⋂",
+}
+
+var functions = map[string]struct{}{
+	"sin": {}, "cos": {}, "tan": {}, "cot": {}, "sec": {}, "csc": {},
+	"arcsin": {}, "arccos": {}, "arctan": {}, "sinh": {}, "cosh": {}, "tanh": {},
+	"log": {}, "ln": {}, "lg": {}, "exp": {}, "max": {}, "min": {}, "sup": {}, "inf": {},
+	"det": {}, "dim": {}, "gcd": {}, "Pr": {}, "lim": {}, "arg": {}, "deg": {},
+	"var": {}, "cov": {}, "Var": {}, "Cov": {}, "E": {},
+}
+
+// accents 的值为空表示用 m:bar 画上划线。
+var accents = map[string]string{
+	"bar": "", "overline": "", "hat": "̂", "widehat": "̂", "tilde": "̃", "widetilde": "̃",
+	"vec": "⃗", "dot": "̇", "ddot": "̈",
+}
@@ -3,50 +3,129 @@ package output
 import (
 	"fmt"
 	"io"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"syl-md2ppt/internal/config"
 )
 
 const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-func ResolveOutputPath(outputArg, cwd string, now time.Time, rand io.Reader) (string, error) {
+// Stdout 作为输出路径时表示把 PPT 写到标准输出。
+const Stdout = "-"
+
+var (
+	placeholderRe = regexp.MustCompile(`\{([a-z]+)\}`)
+	// 文件名里不能出现的字符，占位符的取值里出现时换成下划线。
+	unsafeNameChars = strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_")
+	repeatedSepRe   = regexp.MustCompile(`([_\-. ])[_\-. ]+`)
+)
+
+// NameData 是默认文件名模板里占位符的取值，见 config.NamePlaceholders。
+type NameData struct {
+	Source string // 数据源目录名
+	Lang   string // 语言名，多语言合排时是各语言用 - 连起来
+	Count  int    // 页数
+	Git    string // 数据源所在 git 仓库的短提交号，不在仓库里时为空
+	Time   time.Time
+	Rand   string
+}
+
+// UsesDefaultName 判断 --output 的取值是否要按模板生成文件名（没给或给的是目录）。
+func UsesDefaultName(outputArg string) bool {
+	return outputArg != Stdout && !strings.HasSuffix(strings.ToLower(outputArg), ".pptx")
+}
+
+// NewNameData 准备和页面无关的占位符：数据源目录名、时间，以及模板用到时的随机码和 git 提交号。
+// Lang、Count 等排版后再填。
+func NewNameData(outputArg string, cfg config.DefaultNameConfig, sourceDir string, now time.Time, rand io.Reader) (NameData, error) {
+	data := NameData{Source: filepath.Base(filepath.Clean(sourceDir)), Time: now}
+	if !UsesDefaultName(outputArg) {
+		return data, nil
+	}
+	if strings.Contains(cfg.Template, "{rand}") {
+		sfx, err := randomSuffix(rand, cfg.RandomSuffixLen)
+		if err != nil {
+			return NameData{}, err
+		}
+		data.Rand = sfx
+	}
+	if strings.Contains(cfg.Template, "{git}") {
+		data.Git = gitShortHash(sourceDir)
+	}
+	return data, nil
+}
+
+func ResolveOutputPath(outputArg, cwd string, cfg config.DefaultNameConfig, data NameData) (string, error) {
+	if outputArg == Stdout {
+		return Stdout, nil
+	}
 	if strings.TrimSpace(cwd) == "" {
 		return "", fmt.Errorf("当前目录为空，没法确定输出位置")
 	}
 
 	if strings.TrimSpace(outputArg) == "" {
-		name, err := defaultName(now, rand)
-		if err != nil {
-			return "", err
-		}
-		return filepath.Join(cwd, name), nil
+		return filepath.Join(cwd, defaultName(cfg, data)), nil
 	}
 
-	if strings.HasSuffix(strings.ToLower(outputArg), ".pptx") {
+	if !UsesDefaultName(outputArg) {
 		if filepath.IsAbs(outputArg) {
 			return outputArg, nil
 		}
 		return filepath.Join(cwd, outputArg), nil
 	}
 
-	name, err := defaultName(now, rand)
-	if err != nil {
-		return "", err
-	}
-
+	name := defaultName(cfg, data)
 	if filepath.IsAbs(outputArg) {
 		return filepath.Join(outputArg, name), nil
 	}
 	return filepath.Join(cwd, outputArg, name), nil
 }
 
-func defaultName(now time.Time, rand io.Reader) (string, error) {
-	sfx, err := randomSuffix(rand, 6)
+// defaultName 按模板拼出文件名。取值为空的占位符连同多出来的分隔符一起去掉。
+func defaultName(cfg config.DefaultNameConfig, data NameData) string {
+	tpl := cfg.Template
+	if tpl == "" {
+		tpl = config.DefaultNameTemplate
+	}
+	name := placeholderRe.ReplaceAllStringFunc(tpl, func(m string) string {
+		var v string
+		switch m[1 : len(m)-1] {
+		case "source":
+			v = data.Source
+		case "date":
+			v = data.Time.Format(cfg.TimestampFormat)
+		case "lang":
+			v = data.Lang
+		case "count":
+			v = strconv.Itoa(data.Count)
+		case "git":
+			v = data.Git
+		case "rand":
+			v = data.Rand
+		default:
+			return m
+		}
+		return unsafeNameChars.Replace(v)
+	})
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	base = strings.Trim(repeatedSepRe.ReplaceAllString(base, "$1"), "_-. ")
+	if base == "" {
+		base = "deck"
+	}
+	return base + ".pptx"
+}
+
+func gitShortHash(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").Output()
 	if err != nil {
-		return "", err
+		return ""
 	}
-	return now.Format("20060102_150405") + "_" + sfx + ".pptx", nil
+	return strings.TrimSpace(string(out))
 }
 
 func randomSuffix(rand io.Reader, n int) (string, error) {
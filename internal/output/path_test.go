@@ -2,20 +2,40 @@ package output
 
 import (
 	"bytes"
+	"io"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"syl-md2ppt/internal/config"
 )
 
+var defaultNameCfg = config.DefaultNameConfig{
+	Template:        config.DefaultNameTemplate,
+	TimestampFormat: "20060102_150405",
+	RandomSuffixLen: 6,
+}
+
+func resolve(t *testing.T, outputArg, cwd string, cfg config.DefaultNameConfig, data NameData, rand io.Reader) string {
+	t.Helper()
+	names, err := NewNameData(outputArg, cfg, "/data/SPI", data.Time, rand)
+	if err != nil {
+		t.Fatalf("NewNameData returned error: %v", err)
+	}
+	names.Lang, names.Count = data.Lang, data.Count
+	got, err := ResolveOutputPath(outputArg, cwd, cfg, names)
+	if err != nil {
+		t.Fatalf("ResolveOutputPath returned error: %v", err)
+	}
+	return got
+}
+
 func TestResolveOutputPath_DefaultName(t *testing.T) {
 	now := time.Date(2026, 2, 20, 18, 4, 5, 0, time.UTC)
 	cwd := "/tmp/work"
 	randSrc := bytes.NewBufferString("ABCDEF")
 
-	got, err := ResolveOutputPath("", cwd, now, randSrc)
-	if err != nil {
-		t.Fatalf("ResolveOutputPath returned error: %v", err)
-	}
+	got := resolve(t, "", cwd, defaultNameCfg, NameData{Time: now}, randSrc)
 
 	want := filepath.Join(cwd, "20260220_180405_ABCDEF.pptx")
 	if got != want {
@@ -28,10 +48,7 @@ func TestResolveOutputPath_AsDirectory(t *testing.T) {
 	cwd := "/tmp/work"
 	randSrc := bytes.NewBufferString("QWERTY")
 
-	got, err := ResolveOutputPath("/tmp/outdir", cwd, now, randSrc)
-	if err != nil {
-		t.Fatalf("ResolveOutputPath returned error: %v", err)
-	}
+	got := resolve(t, "/tmp/outdir", cwd, defaultNameCfg, NameData{Time: now}, randSrc)
 
 	want := filepath.Join("/tmp/outdir", "20260220_180405_QWERTY.pptx")
 	if got != want {
@@ -42,15 +59,48 @@ func TestResolveOutputPath_AsDirectory(t *testing.T) {
 func TestResolveOutputPath_AsFile(t *testing.T) {
 	now := time.Date(2026, 2, 20, 18, 4, 5, 0, time.UTC)
 	cwd := "/tmp/work"
-	randSrc := bytes.NewBufferString("ZXCVBN")
 
-	got, err := ResolveOutputPath("./final.pptx", cwd, now, randSrc)
-	if err != nil {
-		t.Fatalf("ResolveOutputPath returned error: %v", err)
-	}
+	// 明确给了文件名时不需要随机数。
+	got := resolve(t, "./final.pptx", cwd, defaultNameCfg, NameData{Time: now}, nil)
 
 	want := filepath.Join(cwd, "final.pptx")
 	if got != want {
 		t.Fatalf("unexpected output path\nwant: %s\n got: %s", want, got)
 	}
 }
+
+func TestResolveOutputPath_Stdout(t *testing.T) {
+	got, err := ResolveOutputPath("-", "/tmp/work", defaultNameCfg, NameData{})
+	if err != nil || got != Stdout {
+		t.Fatalf("expected stdout marker, got %q (%v)", got, err)
+	}
+}
+
+func TestResolveOutputPath_HonorsConfig(t *testing.T) {
+	now := time.Date(2026, 2, 20, 18, 4, 5, 0, time.UTC)
+	cfg := config.DefaultNameConfig{Template: config.DefaultNameTemplate, TimestampFormat: "2006-01-02", RandomSuffixLen: 3}
+
+	got := resolve(t, "", "/tmp/work", cfg, NameData{Time: now}, bytes.NewBufferString("xyz"))
+
+	if want := filepath.Join("/tmp/work", "2026-02-20_XYZ.pptx"); got != want {
+		t.Fatalf("unexpected output path\nwant: %s\n got: %s", want, got)
+	}
+}
+
+func TestResolveOutputPath_Template(t *testing.T) {
+	now := time.Date(2026, 2, 20, 18, 4, 5, 0, time.UTC)
+	cfg := defaultNameCfg
+	cfg.Template = "{source}_{date}_{lang}_{count}.pptx"
+
+	got := resolve(t, "out", "/tmp/work", cfg, NameData{Time: now, Lang: "EN-CN", Count: 42}, nil)
+	if want := filepath.Join("/tmp/work", "out", "SPI_20260220_180405_EN-CN_42.pptx"); got != want {
+		t.Fatalf("unexpected output path\nwant: %s\n got: %s", want, got)
+	}
+
+	// 取值为空的占位符不留下多余的分隔符。
+	cfg.Template = "{source}_{lang}_{count}.pptx"
+	got = resolve(t, "", "/tmp/work", cfg, NameData{Time: now, Count: 3}, nil)
+	if want := filepath.Join("/tmp/work", "SPI_3.pptx"); got != want {
+		t.Fatalf("unexpected output path\nwant: %s\n got: %s", want, got)
+	}
+}
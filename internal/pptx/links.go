@@ -0,0 +1,31 @@
+package pptx
+
+import "fmt"
+
+const relTypeHyperlink = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+
+// hyperlinks 给一页里的链接分配外部关系，同一个地址只登记一次。
+type hyperlinks struct {
+	next int
+	ids  map[string]string
+	rels []relationship
+}
+
+func newHyperlinks(firstRel int) *hyperlinks {
+	return &hyperlinks{next: firstRel, ids: make(map[string]string)}
+}
+
+// id 返回链接的关系 ID；没有链接或不需要写链接时返回空。
+func (h *hyperlinks) id(url string) string {
+	if h == nil || url == "" {
+		return ""
+	}
+	if id, ok := h.ids[url]; ok {
+		return id
+	}
+	id := fmt.Sprintf("rId%d", h.next)
+	h.next++
+	h.ids[url] = id
+	h.rels = append(h.rels, relationship{ID: id, Type: relTypeHyperlink, Target: url, TargetMode: "External"})
+	return id
+}
@@ -0,0 +1,164 @@
+package pptx
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path"
+	"strings"
+
+	"syl-md2ppt/internal/render"
+)
+
+const (
+	relTypeImage = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	// svgBlipExtURI 是 PowerPoint 2016 起识别 SVG 图片的扩展。
+	svgBlipExtURI = "{96DAC541-7B7A-43D3-8B79-37D633B846F1}"
+)
+
+var imageContentTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"svg":  "image/svg+xml",
+}
+
+// picture 是某一页上的一张图片及其关系 ID。
+type picture struct {
+	column   int
+	image    *render.Image
+	relID    string
+	svgRelID string
+}
+
+// mediaSet 收集写入 ppt/media 的图片，同一个文件只存一份；新图片第一次用到时交给 emit 写出。
+type mediaSet struct {
+	emit     func(name string, data []byte) error
+	taken    map[string]bool   // 已占用的部件名（不含扩展名），模板自带的媒体也算在内
+	parts    map[string]string // 源文件路径 -> 部件名
+	used     map[string]bool   // 用到的扩展名
+	fallback string            // SVG 的 PNG 占位图
+}
+
+func newMediaSet(existing []string, emit func(name string, data []byte) error) *mediaSet {
+	m := &mediaSet{emit: emit, taken: make(map[string]bool), parts: make(map[string]string), used: make(map[string]bool)}
+	for _, name := range existing {
+		if strings.HasPrefix(name, "ppt/media/") {
+			m.taken[strings.TrimSuffix(name, path.Ext(name))] = true
+		}
+	}
+	return m
+}
+
+// nextPart 占用下一个空闲的编号。
+func (m *mediaSet) nextPart(ext string) string {
+	for i := 1; ; i++ {
+		base := fmt.Sprintf("ppt/media/image%d", i)
+		if !m.taken[base] {
+			m.taken[base] = true
+			return base + "." + ext
+		}
+	}
+}
+
+func (m *mediaSet) add(img *render.Image) (string, error) {
+	if part, ok := m.parts[img.Path]; ok {
+		return part, nil
+	}
+	data, err := os.ReadFile(img.Path)
+	if err != nil {
+		return "", fmt.Errorf("读取图片失败（%s）：%w", img.Path, err)
+	}
+	ext := img.Format
+	if _, ok := imageContentTypes[ext]; !ok {
+		return "", fmt.Errorf("不支持的图片格式（%s）", img.Path)
+	}
+	part := m.nextPart(ext)
+	if err := m.emit(part, data); err != nil {
+		return "", err
+	}
+	m.parts[img.Path] = part
+	m.used[ext] = true
+	return part, nil
+}
+
+// svgFallback 返回 SVG 的占位 PNG：不认识 SVG 的阅读器会显示一块透明区域。
+func (m *mediaSet) svgFallback() (string, error) {
+	if m.fallback != "" {
+		return m.fallback, nil
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+	part := m.nextPart("png")
+	if err := m.emit(part, buf.Bytes()); err != nil {
+		return "", err
+	}
+	m.fallback = part
+	m.used["png"] = true
+	return m.fallback, nil
+}
+
+// slidePictures 登记一页里的全部图片，返回图片和新增的关系；关系 ID 从 firstRel 开始。
+func (m *mediaSet) slidePictures(slide render.Slide, firstRel int) ([]picture, []relationship, error) {
+	pics := make([]picture, 0)
+	rels := make([]relationship, 0)
+	next := firstRel
+	addRel := func(part string) string {
+		id := fmt.Sprintf("rId%d", next)
+		next++
+		rels = append(rels, relationship{ID: id, Type: relTypeImage, Target: "../media/" + strings.TrimPrefix(part, "ppt/media/")})
+		return id
+	}
+	for c, col := range slide.Columns {
+		for _, block := range col.Blocks {
+			if block.Kind != render.BlockImage || block.Image == nil {
+				continue
+			}
+			part, err := m.add(block.Image)
+			if err != nil {
+				return nil, nil, err
+			}
+			pic := picture{column: c, image: block.Image}
+			if block.Image.Format == "svg" {
+				fallback, err := m.svgFallback()
+				if err != nil {
+					return nil, nil, err
+				}
+				pic.relID = addRel(fallback)
+				pic.svgRelID = addRel(part)
+			} else {
+				pic.relID = addRel(part)
+			}
+			pics = append(pics, pic)
+		}
+	}
+	return pics, rels, nil
+}
+
+// contentTypes 给用到的图片扩展名补上 Default 声明。
+func (m *mediaSet) contentTypes(base string) string {
+	idx := strings.LastIndex(base, "</Types>")
+	if idx < 0 {
+		return base
+	}
+	var defaults strings.Builder
+	for _, ext := range []string{"png", "jpeg", "svg"} {
+		if !m.used[ext] || strings.Contains(base, `Extension="`+ext+`"`) {
+			continue
+		}
+		defaults.WriteString(`<Default Extension="` + ext + `" ContentType="` + imageContentTypes[ext] + `"/>`)
+	}
+	return base[:idx] + defaults.String() + base[idx:]
+}
+
+func pictureXML(pic picture, x, y int64, shapeID int) string {
+	img := pic.image
+	blip := `<a:blip r:embed="` + pic.relID + `"/>`
+	if pic.svgRelID != "" {
+		blip = `<a:blip r:embed="` + pic.relID + `"><a:extLst><a:ext uri="` + svgBlipExtURI + `"><asvg:svgBlip xmlns:asvg="http://schemas.microsoft.com/office/drawing/2016/SVG/main" r:embed="` + pic.svgRelID + `"/></a:ext></a:extLst></a:blip>`
+	}
+	return fmt.Sprintf(`<p:pic><p:nvPicPr><p:cNvPr id="%d" name="Picture %d" descr="%s"/><p:cNvPicPr><a:picLocks noChangeAspect="1"/></p:cNvPicPr><p:nvPr/></p:nvPicPr><p:blipFill>%s<a:stretch><a:fillRect/></a:stretch></p:blipFill><p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr></p:pic>`,
+		shapeID, shapeID, escapeXMLText(img.Alt), blip,
+		x+toEMU(img.LeftIn), y+toEMU(img.TopIn), toEMU(img.DisplayW/72), toEMU(img.DisplayH/72))
+}
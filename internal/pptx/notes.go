@@ -0,0 +1,78 @@
+package pptx
+
+import (
+	"fmt"
+	"strings"
+
+	"syl-md2ppt/internal/render"
+)
+
+const notesFontSize = 12
+
+// 备注页为纵向 A4 比例（与 presentation.xml 的 notesSz 一致）。
+const (
+	notesWidthEMU  = 6858000
+	notesHeightEMU = 9144000
+)
+
+func notesMasterXML() string {
+	imgX, imgY, imgW, imgH := notesImageRect()
+	bodyX, bodyY, bodyW, bodyH := notesBodyRect()
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:notesMaster xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"><p:cSld><p:bg><p:bgRef idx="1001"><a:schemeClr val="bg1"/></p:bgRef></p:bg><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/>` +
+		fmt.Sprintf(`<p:sp><p:nvSpPr><p:cNvPr id="2" name="Slide Image Placeholder 1"/><p:cNvSpPr><a:spLocks noGrp="1" noRot="1" noChangeAspect="1"/></p:cNvSpPr><p:nvPr><p:ph type="sldImg" idx="2"/></p:nvPr></p:nvSpPr><p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/><a:ln w="12700"><a:solidFill><a:prstClr val="black"/></a:solidFill></a:ln></p:spPr></p:sp>`, imgX, imgY, imgW, imgH) +
+		fmt.Sprintf(`<p:sp><p:nvSpPr><p:cNvPr id="3" name="Notes Placeholder 2"/><p:cNvSpPr><a:spLocks noGrp="1"/></p:cNvSpPr><p:nvPr><p:ph type="body" sz="quarter" idx="3"/></p:nvPr></p:nvSpPr><p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></p:spPr><p:txBody><a:bodyPr vert="horz" lIns="91440" tIns="45720" rIns="91440" bIns="45720" rtlCol="0"/><a:lstStyle/><a:p><a:pPr lvl="0"/><a:r><a:rPr lang="en-US"/><a:t>Notes</a:t></a:r></a:p></p:txBody></p:sp>`, bodyX, bodyY, bodyW, bodyH) +
+		`</p:spTree></p:cSld><p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/>` +
+		fmt.Sprintf(`<p:notesStyle><a:lvl1pPr marL="0" algn="l" defTabSz="914400" rtl="0" eaLnBrk="1" latinLnBrk="0" hangingPunct="1"><a:defRPr sz="%d" kern="1200"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mn-lt"/><a:ea typeface="+mn-ea"/><a:cs typeface="+mn-cs"/></a:defRPr></a:lvl1pPr></p:notesStyle></p:notesMaster>`, notesFontSize*100)
+}
+
+func notesMasterRelsXML(theme string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme" Target="../theme/` + theme + `"/></Relationships>`
+}
+
+// notesSlideXML 按语言顺序拼接备注，语言之间空一行。
+func notesSlideXML(notes []render.Note, styles StylePalette, links *hyperlinks) string {
+	var body strings.Builder
+	for i, note := range notes {
+		lang := escapeXMLText(columnLangTag(render.Column{Lang: note.Lang, Tag: note.Tag}))
+		if i > 0 {
+			body.WriteString(`<a:p><a:endParaRPr lang="` + lang + `"/></a:p>`)
+		}
+		var numbering listNumbering
+		for _, block := range note.Blocks {
+			body.WriteString(paragraphXML(block, notesFontSize, numbering.startAt(block), textStyle{lang: lang, styles: styles, links: links}))
+		}
+	}
+	if body.Len() == 0 {
+		body.WriteString(`<a:p><a:endParaRPr lang="en-US"/></a:p>`)
+	}
+
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:notes xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"><p:cSld><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/>` +
+		`<p:sp><p:nvSpPr><p:cNvPr id="2" name="Slide Image Placeholder 1"/><p:cNvSpPr><a:spLocks noGrp="1" noRot="1" noChangeAspect="1"/></p:cNvSpPr><p:nvPr><p:ph type="sldImg"/></p:nvPr></p:nvSpPr><p:spPr/></p:sp>` +
+		`<p:sp><p:nvSpPr><p:cNvPr id="3" name="Notes Placeholder 2"/><p:cNvSpPr><a:spLocks noGrp="1"/></p:cNvSpPr><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr><p:spPr/><p:txBody><a:bodyPr/><a:lstStyle/>` + body.String() + `</p:txBody></p:sp>` +
+		`</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:notes>`
+}
+
+func notesSlideRelsXML(slideNo int, extra []relationship) string {
+	var rels strings.Builder
+	for _, rel := range extra {
+		rels.WriteString(relationshipXML(rel))
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesMaster" Target="../notesMasters/notesMaster1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="../slides/slide%d.xml"/>%s</Relationships>`, slideNo, rels.String())
+}
+
+func notesImageRect() (x, y, cx, cy int64) {
+	cx = notesWidthEMU * 8 / 10
+	cy = cx * 9 / 16
+	return (notesWidthEMU - cx) / 2, notesHeightEMU / 12, cx, cy
+}
+
+func notesBodyRect() (x, y, cx, cy int64) {
+	_, imgY, _, imgH := notesImageRect()
+	x = notesWidthEMU / 10
+	y = imgY + imgH + notesHeightEMU/24
+	return x, y, notesWidthEMU - 2*x, notesHeightEMU - y - notesHeightEMU/12
+}
@@ -0,0 +1,102 @@
+package pptx
+
+import (
+	"fmt"
+	"strings"
+
+	"syl-md2ppt/internal/omml"
+	"syl-md2ppt/internal/render"
+)
+
+var tableAlign = map[string]string{"left": "l", "center": "ctr", "right": "r"}
+
+// tableXML 输出一张原生表格；x、y 是所在栏文本框的左上角。
+func tableXML(tbl *render.Table, x, y int64, shapeID, fontSize int, ts textStyle) string {
+	frame := func(equations bool) string {
+		ts := ts
+		ts.equations = equations
+		return fmt.Sprintf(`<p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="%d" name="Table %d"/><p:cNvGraphicFramePr><a:graphicFrameLocks noGrp="1"/></p:cNvGraphicFramePr><p:nvPr/></p:nvGraphicFramePr><p:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></p:xfrm><a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/table">%s</a:graphicData></a:graphic></p:graphicFrame>`,
+			shapeID, shapeID, x+toEMU(tbl.LeftIn), y+toEMU(tbl.TopIn), toEMU(tbl.DisplayW/72), toEMU(tbl.DisplayH/72), tblXML(tbl, fontSize, ts))
+	}
+	if !tableHasMath(tbl) {
+		return frame(false)
+	}
+	return `<mc:AlternateContent xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"><mc:Choice xmlns:a14="http://schemas.microsoft.com/office/drawing/2010/main" Requires="a14">` + frame(true) + `</mc:Choice><mc:Fallback>` + frame(false) + `</mc:Fallback></mc:AlternateContent>`
+}
+
+func tblXML(tbl *render.Table, fontSize int, ts textStyle) string {
+	lang, styles := ts.lang, ts.styles
+	cols := 0
+	if len(tbl.Rows) > 0 {
+		cols = len(tbl.Rows[0].Cells)
+	}
+	if cols == 0 {
+		return `<a:tbl/>`
+	}
+	var b strings.Builder
+	b.WriteString(`<a:tbl><a:tblPr firstRow="1"`)
+	if styles.TableBandFill != "" {
+		b.WriteString(` bandRow="1"`)
+	}
+	b.WriteString(`/><a:tblGrid>`)
+	colW := toEMU(tbl.DisplayW / 72 / float64(cols))
+	for i := 0; i < cols; i++ {
+		b.WriteString(fmt.Sprintf(`<a:gridCol w="%d"/>`, colW))
+	}
+	b.WriteString(`</a:tblGrid>`)
+	for r, row := range tbl.Rows {
+		b.WriteString(fmt.Sprintf(`<a:tr h="%d">`, toEMU(row.HeightPt/72)))
+		color := styles.BaseColor
+		fill := ""
+		switch {
+		case r == 0:
+			color, fill = styles.TableHeaderColor, styles.TableHeaderFill
+		case r%2 == 0:
+			fill = styles.TableBandFill
+		}
+		for c, cell := range row.Cells {
+			b.WriteString(`<a:tc><a:txBody><a:bodyPr/><a:lstStyle/><a:p>`)
+			if c < len(tbl.Align) && tableAlign[tbl.Align[c]] != "" {
+				b.WriteString(`<a:pPr algn="` + tableAlign[tbl.Align[c]] + `"/>`)
+			}
+			b.WriteString(runsXML(cell, fontSize, color, ts))
+			b.WriteString(`<a:endParaRPr lang="` + lang + `" sz="` + fmt.Sprint(fontSize*100) + `"/></a:p></a:txBody>`)
+			b.WriteString(tableCellPropsXML(fill, styles.TableBorderColor))
+			b.WriteString(`</a:tc>`)
+		}
+		b.WriteString(`</a:tr>`)
+	}
+	b.WriteString(`</a:tbl>`)
+	return b.String()
+}
+
+func tableCellPropsXML(fill, border string) string {
+	var b strings.Builder
+	b.WriteString(`<a:tcPr>`)
+	for _, side := range []string{"lnL", "lnR", "lnT", "lnB"} {
+		b.WriteString(`<a:` + side + ` w="12700"><a:solidFill><a:srgbClr val="` + border + `"/></a:solidFill></a:` + side + `>`)
+	}
+	if fill != "" {
+		b.WriteString(`<a:solidFill><a:srgbClr val="` + fill + `"/></a:solidFill>`)
+	} else {
+		b.WriteString(`<a:noFill/>`)
+	}
+	b.WriteString(`</a:tcPr>`)
+	return b.String()
+}
+
+func tableHasMath(tbl *render.Table) bool {
+	for _, row := range tbl.Rows {
+		for _, cell := range row.Cells {
+			for _, r := range cell {
+				if !r.Formula {
+					continue
+				}
+				if _, err := omml.Convert(r.Text, ""); err == nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
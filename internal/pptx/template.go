@@ -0,0 +1,350 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	relTypeSlide       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+	relTypeNotesMaster = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesMaster"
+	relTypeTheme       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+)
+
+// 模板里和已有幻灯片绑定的部件，生成前全部去掉；母版、版式、主题和媒体保留。
+var strippedPrefixes = []string{"ppt/slides/", "ppt/notesSlides/", "ppt/notesMasters/", "ppt/comments/"}
+
+var (
+	strippedOverrideRe = regexp.MustCompile(`<Override\b[^>]*PartName="/ppt/(?:slides|notesSlides|notesMasters|comments)/[^"]*"[^>]*/>`)
+	layoutPartRe       = regexp.MustCompile(`^ppt/slideLayouts/slideLayout(\d+)\.xml$`)
+	layoutNameRe       = regexp.MustCompile(`<p:cSld\b[^>]*\bname="([^"]*)"`)
+	layoutTypeRe       = regexp.MustCompile(`<p:sldLayout\b[^>]*\btype="([^"]*)"`)
+	relIDRe            = regexp.MustCompile(`^rId(\d+)$`)
+	slideSizeRe        = regexp.MustCompile(`<p:sldSz\b[^>]*>`)
+	sizeAttrRe         = regexp.MustCompile(`\b(cx|cy)="(\d+)"`)
+	xmlAttrUnescaper   = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'")
+)
+
+type relationship struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr,omitempty"`
+}
+
+type relationshipList struct {
+	Rels []relationship `xml:"Relationship"`
+}
+
+// template 是去掉旧幻灯片后的模板包，以及生成新页面需要的关系信息。
+type template struct {
+	files        map[string][]byte
+	presRels     []relationship // presentation.xml 保留下来的关系（母版、主题、属性等）
+	layoutTarget string         // 生成页使用的版式，相对 ppt/slides/ 的路径
+	firstRelID   int            // 新幻灯片可用的第一个 rId 编号
+	notesTheme   string         // 备注母版主题的部件名，如 theme2.xml
+}
+
+func loadTemplate(templatePath, layoutName string) (*template, error) {
+	raw := defaultTemplate
+	label := "内置模板"
+	if strings.TrimSpace(templatePath) != "" {
+		b, err := os.ReadFile(templatePath)
+		if err != nil {
+			return nil, fmt.Errorf("读取 PPT 模板失败（%s）：%w", templatePath, err)
+		}
+		raw = b
+		label = templatePath
+	}
+
+	files, err := readZipFiles(raw)
+	if err != nil {
+		if label == "内置模板" {
+			return nil, fmt.Errorf("加载内置 PPT 模板失败：%w", err)
+		}
+		return nil, fmt.Errorf("PPT 模板读不懂（%s）：%w", label, err)
+	}
+	for _, name := range []string{"[Content_Types].xml", "ppt/presentation.xml", "ppt/_rels/presentation.xml.rels"} {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("PPT 模板缺少 %s（%s）", name, label)
+		}
+	}
+
+	contentTypes := string(files["[Content_Types].xml"])
+	// 旧备注母版的主题只给它自己用，跟着一起去掉。
+	for name, data := range files {
+		if !strings.HasPrefix(name, "ppt/notesMasters/_rels/") {
+			continue
+		}
+		rels, err := parseRelationships(data)
+		if err != nil {
+			return nil, fmt.Errorf("PPT 模板关系文件读不懂（%s）：%w", name, err)
+		}
+		for _, rel := range rels {
+			if rel.Type != relTypeTheme {
+				continue
+			}
+			part := path.Join("ppt/notesMasters", rel.Target)
+			delete(files, part)
+			contentTypes = removeOverride(contentTypes, "/"+part)
+		}
+	}
+	for name := range files {
+		for _, prefix := range strippedPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				delete(files, name)
+				break
+			}
+		}
+	}
+	// 只被旧幻灯片引用的媒体、图表、嵌入对象没人再用：顺着关系找出还用得到的部件，其余去掉。
+	// 找不到 presentation.xml 说明关系文件不全，这时不动，免得误删。
+	if used := reachableParts(files); used["ppt/presentation.xml"] {
+		for name := range files {
+			if name == "[Content_Types].xml" || used[name] || used[relsOwner(name)] {
+				continue
+			}
+			delete(files, name)
+			contentTypes = removeOverride(contentTypes, "/"+name)
+		}
+	}
+	files["[Content_Types].xml"] = []byte(strippedOverrideRe.ReplaceAllString(contentTypes, ""))
+
+	rels, err := parseRelationships(files["ppt/_rels/presentation.xml.rels"])
+	if err != nil {
+		return nil, fmt.Errorf("PPT 模板关系文件读不懂（ppt/_rels/presentation.xml.rels）：%w", err)
+	}
+	tpl := &template{files: files, firstRelID: 1}
+	for _, rel := range rels {
+		if rel.Type == relTypeSlide || rel.Type == relTypeNotesMaster {
+			continue
+		}
+		tpl.presRels = append(tpl.presRels, rel)
+		if m := relIDRe.FindStringSubmatch(rel.ID); m != nil {
+			if n, _ := strconv.Atoi(m[1]); n >= tpl.firstRelID {
+				tpl.firstRelID = n + 1
+			}
+		}
+	}
+
+	layout, err := pickLayout(files, layoutName)
+	if err != nil {
+		return nil, fmt.Errorf("%w（%s）", err, label)
+	}
+	tpl.layoutTarget = "../slideLayouts/" + path.Base(layout)
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("theme%d.xml", i)
+		if _, ok := files["ppt/theme/"+name]; !ok {
+			tpl.notesTheme = name
+			break
+		}
+	}
+	return tpl, nil
+}
+
+// CheckTemplate 提前确认模板能用、版式能找到，避免扫描完数据源才报错。
+func CheckTemplate(templatePath, layoutName string) error {
+	_, err := loadTemplate(templatePath, layoutName)
+	return err
+}
+
+// TemplateSlideSize 读出模板的页面尺寸（英寸），模板里没写时返回 0。
+func TemplateSlideSize(templatePath string) (width, height float64, err error) {
+	raw, err := os.ReadFile(templatePath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取 PPT 模板失败（%s）：%w", templatePath, err)
+	}
+	files, err := readZipFiles(raw)
+	if err != nil {
+		return 0, 0, fmt.Errorf("PPT 模板读不懂（%s）：%w", templatePath, err)
+	}
+	tag := slideSizeRe.Find(files["ppt/presentation.xml"])
+	for _, m := range sizeAttrRe.FindAllSubmatch(tag, -1) {
+		n, _ := strconv.ParseInt(string(m[2]), 10, 64)
+		if string(m[1]) == "cx" {
+			width = float64(n) / emuPerInch
+		} else {
+			height = float64(n) / emuPerInch
+		}
+	}
+	return width, height, nil
+}
+
+func (t *template) slideRelID(slideNo int) string {
+	return fmt.Sprintf("rId%d", t.firstRelID+slideNo-1)
+}
+
+// notesMasterRelID 排在所有幻灯片关系之后。
+func (t *template) notesMasterRelID(slideCount int) string {
+	return fmt.Sprintf("rId%d", t.firstRelID+slideCount)
+}
+
+// masterTheme 返回备注母版可复用的主题内容：取编号最小的主题。
+func (t *template) masterTheme() []byte {
+	names := make([]string, 0)
+	for name := range t.files {
+		if strings.HasPrefix(name, "ppt/theme/") && strings.HasSuffix(name, ".xml") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return t.files[names[0]]
+}
+
+// pickLayout 按名称找版式；名称为空时优先用空白版式，没有就用第一个。
+func pickLayout(files map[string][]byte, name string) (string, error) {
+	type layoutInfo struct {
+		part string
+		num  int
+		name string
+		kind string
+	}
+	layouts := make([]layoutInfo, 0)
+	for part, data := range files {
+		m := layoutPartRe.FindStringSubmatch(part)
+		if m == nil {
+			continue
+		}
+		info := layoutInfo{part: part}
+		info.num, _ = strconv.Atoi(m[1])
+		if nm := layoutNameRe.FindSubmatch(data); nm != nil {
+			info.name = xmlAttrUnescaper.Replace(string(nm[1]))
+		}
+		if tm := layoutTypeRe.FindSubmatch(data); tm != nil {
+			info.kind = string(tm[1])
+		}
+		layouts = append(layouts, info)
+	}
+	if len(layouts) == 0 {
+		return "", fmt.Errorf("PPT 模板里没有任何版式")
+	}
+	sort.Slice(layouts, func(i, j int) bool { return layouts[i].num < layouts[j].num })
+
+	name = strings.TrimSpace(name)
+	if name != "" {
+		names := make([]string, 0, len(layouts))
+		for _, l := range layouts {
+			if strings.EqualFold(strings.TrimSpace(l.name), name) {
+				return l.part, nil
+			}
+			names = append(names, l.name)
+		}
+		return "", fmt.Errorf("PPT 模板里没有名为 %s 的版式，可选：%s", name, strings.Join(names, "、"))
+	}
+	for _, l := range layouts {
+		if l.kind == "blank" {
+			return l.part, nil
+		}
+	}
+	return layouts[0].part, nil
+}
+
+// reachableParts 从包的根关系出发，沿关系找到所有还被引用的部件；根本身记为空字符串。
+func reachableParts(files map[string][]byte) map[string]bool {
+	used := map[string]bool{"": true}
+	queue := []string{""}
+	for len(queue) > 0 {
+		part := queue[0]
+		queue = queue[1:]
+		rels, err := parseRelationships(files[relsPath(part)])
+		if err != nil {
+			continue
+		}
+		for _, rel := range rels {
+			if rel.TargetMode == "External" || rel.Type == relTypeSlide || rel.Type == relTypeNotesMaster {
+				continue
+			}
+			target := strings.TrimPrefix(rel.Target, "/")
+			if !strings.HasPrefix(rel.Target, "/") {
+				target = path.Join(path.Dir(part), rel.Target)
+			}
+			if _, ok := files[target]; ok && !used[target] {
+				used[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+	return used
+}
+
+// relsPath 返回部件的关系文件，如 ppt/slides/slide1.xml -> ppt/slides/_rels/slide1.xml.rels。
+func relsPath(part string) string {
+	if part == "" {
+		return "_rels/.rels"
+	}
+	return path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+}
+
+// relsOwner 是 relsPath 的反过来，不是关系文件时返回 "-"。
+func relsOwner(name string) string {
+	dir := path.Dir(name)
+	if path.Base(dir) != "_rels" || !strings.HasSuffix(name, ".rels") {
+		return "-"
+	}
+	if name == "_rels/.rels" {
+		return ""
+	}
+	return path.Join(path.Dir(dir), strings.TrimSuffix(path.Base(name), ".rels"))
+}
+
+func readZipFiles(raw []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(zr.File)+16)
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("读取模板文件失败（%s）：%w", f.Name, err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取模板内容失败（%s）：%w", f.Name, err)
+		}
+		files[f.Name] = b
+	}
+	return files, nil
+}
+
+func parseRelationships(data []byte) ([]relationship, error) {
+	var list relationshipList
+	if err := xml.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return list.Rels, nil
+}
+
+func relationshipXML(rel relationship) string {
+	mode := ""
+	if rel.TargetMode != "" {
+		mode = ` TargetMode="` + escapeXMLText(rel.TargetMode) + `"`
+	}
+	return `<Relationship Id="` + escapeXMLText(rel.ID) + `" Type="` + escapeXMLText(rel.Type) + `" Target="` + escapeXMLText(rel.Target) + `"` + mode + `/>`
+}
+
+func removeOverride(contentTypes, partName string) string {
+	re := regexp.MustCompile(`<Override\b[^>]*PartName="` + regexp.QuoteMeta(partName) + `"[^>]*/>`)
+	return re.ReplaceAllString(contentTypes, "")
+}
@@ -0,0 +1,154 @@
+package pptx
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"syl-md2ppt/internal/render"
+)
+
+// writeBrandedTemplate 在内置模板基础上加一页旧幻灯片（带只有它用的图片和图表）、一个版式用的媒体文件，并给版式 2 改名。
+func writeBrandedTemplate(t *testing.T, path string) {
+	t.Helper()
+	files, err := readZipFiles(defaultTemplate)
+	if err != nil {
+		t.Fatalf("read default template: %v", err)
+	}
+	files["ppt/slides/slide1.xml"] = []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?><p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"><p:cSld><p:spTree><a:t>OLD SLIDE</a:t></p:spTree></p:cSld></p:sld>`)
+	files["ppt/slides/_rels/slide1.xml.rels"] = []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/old.png"/><Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart" Target="/ppt/charts/chart1.xml"/></Relationships>`)
+	files["ppt/media/old.png"] = []byte("OLD PNG")
+	files["ppt/charts/chart1.xml"] = []byte("<c:chartSpace/>")
+	files["ppt/media/logo.png"] = []byte("PNG")
+	files["ppt/slideLayouts/_rels/slideLayout2.xml.rels"] = []byte(strings.Replace(string(files["ppt/slideLayouts/_rels/slideLayout2.xml.rels"]), "</Relationships>", `<Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/logo.png"/></Relationships>`, 1))
+	files["ppt/slideLayouts/slideLayout2.xml"] = []byte(strings.Replace(string(files["ppt/slideLayouts/slideLayout2.xml"]), `name="Title and Content"`, `name="Branded Content"`, 1))
+	files["[Content_Types].xml"] = []byte(strings.Replace(string(files["[Content_Types].xml"]), "</Types>", `<Override ContentType="application/vnd.openxmlformats-officedocument.presentationml.slide+xml" PartName="/ppt/slides/slide1.xml"/><Override ContentType="application/vnd.openxmlformats-officedocument.drawingml.chart+xml" PartName="/ppt/charts/chart1.xml"/></Types>`, 1))
+	files["ppt/_rels/presentation.xml.rels"] = []byte(strings.Replace(string(files["ppt/_rels/presentation.xml.rels"]), "</Relationships>", `<Relationship Id="rId12" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/></Relationships>`, 1))
+	files["ppt/presentation.xml"] = []byte(strings.Replace(string(files["ppt/presentation.xml"]), "</p:sldMasterIdLst>", `</p:sldMasterIdLst><p:sldIdLst><p:sldId id="300" r:id="rId12"/></p:sldIdLst>`, 1))
+	writeZipFiles(t, path, files)
+}
+
+func writeZipFiles(t *testing.T, path string, files map[string][]byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("zip write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+}
+
+func TestWritePPTX_UserTemplate(t *testing.T) {
+	tmp := t.TempDir()
+	tplPath := filepath.Join(tmp, "brand.pptx")
+	writeBrandedTemplate(t, tplPath)
+	out := filepath.Join(tmp, "out.pptx")
+
+	column := []render.Column{{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: "NEW"}}}}}}
+	deck := Deck{
+		TemplatePath:   tplPath,
+		TemplateLayout: "branded content",
+		Slides:         []render.Slide{{FontSize: 20, Columns: column}, {FontSize: 20, Columns: column}},
+	}
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	if !zipHasFile(&zr.Reader, "ppt/media/logo.png") {
+		t.Fatalf("template media should be kept")
+	}
+	if zipHasFile(&zr.Reader, "ppt/media/old.png") || zipHasFile(&zr.Reader, "ppt/charts/chart1.xml") {
+		t.Fatalf("parts only the old slides used should be dropped")
+	}
+	if slide := readZipFile(t, &zr.Reader, "ppt/slides/slide1.xml"); strings.Contains(slide, "OLD SLIDE") || !strings.Contains(slide, "NEW") {
+		t.Fatalf("template slides should be replaced, got: %s", slide)
+	}
+	if rels := readZipFile(t, &zr.Reader, "ppt/slides/_rels/slide2.xml.rels"); !strings.Contains(rels, "../slideLayouts/slideLayout2.xml") {
+		t.Fatalf("expected named layout, got: %s", rels)
+	}
+
+	presRels := readZipFile(t, &zr.Reader, "ppt/_rels/presentation.xml.rels")
+	if strings.Count(presRels, `Id="rId12"`) != 0 || strings.Count(presRels, "relationships/slide\"") != 2 {
+		t.Fatalf("old slide relationship should be dropped, got: %s", presRels)
+	}
+	for _, id := range []string{"rId7", "rId8"} {
+		if strings.Count(presRels, `Id="`+id+`"`) != 1 {
+			t.Fatalf("expected exactly one %s, got: %s", id, presRels)
+		}
+	}
+	presentation := readZipFile(t, &zr.Reader, "ppt/presentation.xml")
+	if strings.Contains(presentation, `id="300"`) || strings.Count(presentation, "<p:sldId ") != 2 {
+		t.Fatalf("unexpected slide id list: %s", presentation)
+	}
+	types := readZipFile(t, &zr.Reader, "[Content_Types].xml")
+	if strings.Count(types, `PartName="/ppt/slides/slide1.xml"`) != 1 || strings.Contains(types, "chart1.xml") {
+		t.Fatalf("expected a single slide1 override, got: %s", types)
+	}
+}
+
+func TestWritePPTX_KeepsTemplateSlideSize(t *testing.T) {
+	tmp := t.TempDir()
+	files, err := readZipFiles(defaultTemplate)
+	if err != nil {
+		t.Fatalf("read default template: %v", err)
+	}
+	pres := slideSizeRe.ReplaceAll(files["ppt/presentation.xml"], []byte(`<p:sldSz cx="9144000" cy="6858000" type="screen4x3"/>`))
+	files["ppt/presentation.xml"] = pres
+	tplPath := filepath.Join(tmp, "4x3.pptx")
+	writeZipFiles(t, tplPath, files)
+
+	w, h, err := TemplateSlideSize(tplPath)
+	if err != nil || w != 10 || h != 7.5 {
+		t.Fatalf("expected a 10x7.5in template, got %vx%v (%v)", w, h, err)
+	}
+	out := filepath.Join(tmp, "out.pptx")
+	column := []render.Column{{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: "NEW"}}}}}}
+	deck := Deck{TemplatePath: tplPath, SlideWidthIn: w, SlideHeightIn: h, Slides: []render.Slide{{FontSize: 20, Columns: column}}}
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	if p := readZipFile(t, &zr.Reader, "ppt/presentation.xml"); !strings.Contains(p, `<p:sldSz cx="9144000" cy="6858000" type="screen4x3"/>`) {
+		t.Fatalf("4:3 template should keep its slide size, got: %s", p)
+	}
+}
+
+func TestWritePPTX_UnknownTemplateLayout(t *testing.T) {
+	tmp := t.TempDir()
+	err := CheckTemplate("", "Corporate Cover")
+	if err == nil || !strings.Contains(err.Error(), "没有名为 Corporate Cover 的版式") || !strings.Contains(err.Error(), "Blank") {
+		t.Fatalf("expected layout error listing choices, got: %v", err)
+	}
+
+	bad := filepath.Join(tmp, "bad.pptx")
+	if err := os.WriteFile(bad, []byte("not a zip"), 0o644); err != nil {
+		t.Fatalf("write bad template: %v", err)
+	}
+	if err := CheckTemplate(bad, ""); err == nil || !strings.Contains(err.Error(), "PPT 模板读不懂") {
+		t.Fatalf("expected unreadable template error, got: %v", err)
+	}
+}
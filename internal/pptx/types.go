@@ -1,6 +1,10 @@
 package pptx
 
-import "syl-md2ppt/internal/render"
+import (
+	"time"
+
+	"syl-md2ppt/internal/render"
+)
 
 type Deck struct {
 	SlideWidthIn  float64
@@ -9,8 +13,21 @@ type Deck struct {
 	GapIn         float64
 	PaddingIn     float64
 	FontFamily    string
-	Styles        StylePalette
-	Slides        []render.Slide
+	// TemplatePath 为空时使用内置模板；TemplateLayout 为空时优先用空白版式。
+	TemplatePath   string
+	TemplateLayout string
+	Styles         StylePalette
+	// Modified 写进文档属性和 zip 各部件的修改时间，零值表示当前时间。
+	Modified time.Time
+	Slides   []render.Slide
+	// Prerendered 是和 Slides 一一对应的现成页面（比如来自缓存），XML 为空的页面现场生成。
+	Prerendered []SlidePart
+}
+
+// SlidePart 是生成好的一页：slide XML 和其中超链接的地址（按关系编号的顺序）。
+type SlidePart struct {
+	XML   string
+	Links []string
 }
 
 type StylePalette struct {
@@ -20,4 +37,12 @@ type StylePalette struct {
 	WarnColor    string
 	FormulaColor string
 	FormulaFill  string
+	CodeFont     string
+	CodeColor    string
+	CodeFill     string
+	// 表格配色；TableBandFill 为空时数据行不加底色。
+	TableHeaderFill  string
+	TableHeaderColor string
+	TableBandFill    string
+	TableBorderColor string
 }
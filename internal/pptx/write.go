@@ -1,20 +1,20 @@
 package pptx
 
 import (
-	"archive/zip"
 	"bytes"
 	_ "embed"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"syl-md2ppt/internal/omml"
 	"syl-md2ppt/internal/render"
 )
 
@@ -24,11 +24,10 @@ const emuPerInch = 914400
 var defaultTemplate []byte
 
 var (
-	xmlDeclRe       = regexp.MustCompile(`^\s*<\?xml[^>]*\?>`)
-	slideOverrideRe = regexp.MustCompile(`<Override PartName="/ppt/slides/slide\d+\.xml" ContentType="application/vnd\.openxmlformats-officedocument\.presentationml\.slide\+xml"\s*/>`)
-	slideRelRe      = regexp.MustCompile(`<Relationship Id="rId\d+" Type="http://schemas\.openxmlformats\.org/officeDocument/2006/relationships/slide" Target="slides/slide\d+\.xml"\s*/>`)
+	xmlDeclRe = regexp.MustCompile(`^\s*<\?xml[^>]*\?>`)
 )
 
+// Write 把 deck 写成 outPath；先写临时文件，成功后再替换。
 func Write(outPath string, deck Deck) error {
 	if outPath == "" {
 		return fmt.Errorf("输出路径为空，没法生成 PPT")
@@ -36,84 +35,78 @@ func Write(outPath string, deck Deck) error {
 	if len(deck.Slides) == 0 {
 		return fmt.Errorf("没有可写入的页面，PPT 生成不了")
 	}
-	applyDeckDefaults(&deck)
+	return writeFileAtomic(outPath, func(w io.Writer) error {
+		return WriteDeck(w, deck)
+	})
+}
 
-	files, err := loadTemplateFiles()
+// WriteDeck 把 deck 的所有页面依次写进 w（比如标准输出），不关闭 w。
+func WriteDeck(w io.Writer, deck Deck) error {
+	pw, err := NewWriter(w, deck)
 	if err != nil {
 		return err
 	}
-
-	files["docProps/core.xml"] = []byte(corePropsXML(time.Now().UTC()))
-	files["docProps/app.xml"] = []byte(appPropsXML(len(deck.Slides)))
-	files["ppt/presentation.xml"] = []byte(presentationXML(string(files["ppt/presentation.xml"]), len(deck.Slides), toEMU(deck.SlideWidthIn), toEMU(deck.SlideHeightIn)))
-	files["ppt/_rels/presentation.xml.rels"] = []byte(presentationRelsXML(string(files["ppt/_rels/presentation.xml.rels"]), len(deck.Slides)))
-	files["[Content_Types].xml"] = []byte(contentTypesXML(string(files["[Content_Types].xml"]), len(deck.Slides)))
-
 	for i, s := range deck.Slides {
-		slidePath := fmt.Sprintf("ppt/slides/slide%d.xml", i+1)
-		relPath := fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i+1)
-		files[slidePath] = []byte(slideXML(s, deck))
-		files[relPath] = []byte(slideRelsXML())
+		part := SlidePart{}
+		if i < len(deck.Prerendered) {
+			part = deck.Prerendered[i]
+		}
+		if err := pw.AddRenderedSlide(s, part); err != nil {
+			return err
+		}
 	}
+	return pw.Close()
+}
 
-	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
-		return fmt.Errorf("创建输出目录失败：%w", err)
+// RenderSlide 生成一页的 XML。结果只取决于页面内容和 deck 的版面、配色，
+// 可以缓存起来，之后放进 Deck.Prerendered 复用。
+func RenderSlide(deck Deck, s render.Slide) (SlidePart, error) {
+	applyDeckDefaults(&deck)
+	discard := func(string, []byte) error { return nil }
+	pics, picRels, err := newMediaSet(nil, discard).slidePictures(s, 3)
+	if err != nil {
+		return SlidePart{}, err
+	}
+	links := newHyperlinks(3 + len(picRels))
+	part := SlidePart{XML: slideXML(s, deck, pics, links)}
+	for _, rel := range links.rels {
+		part.Links = append(part.Links, rel.Target)
 	}
+	return part, nil
+}
 
-	f, err := os.Create(outPath)
+// writeFileAtomic 先写到同目录的临时文件，写完再改名覆盖目标，
+// 正在打开这个文件的程序不会读到写了一半的内容。
+func writeFileAtomic(outPath string, write func(io.Writer) error) error {
+	dir := filepath.Dir(outPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("创建输出目录失败：%w", err)
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(outPath)+".*.tmp")
 	if err != nil {
 		return fmt.Errorf("创建输出文件失败：%w", err)
 	}
-	defer f.Close()
-
-	zw := zip.NewWriter(f)
-	defer zw.Close()
-
-	names := make([]string, 0, len(files))
-	for name := range files {
-		names = append(names, name)
+	tmp := f.Name()
+	err = write(f)
+	if err == nil {
+		err = f.Chmod(0o644)
 	}
-	sort.Strings(names)
-
-	for _, name := range names {
-		w, err := zw.Create(name)
-		if err != nil {
-			return fmt.Errorf("写入 PPT 结构失败（%s）：%w", name, err)
-		}
-		if _, err := w.Write(files[name]); err != nil {
-			return fmt.Errorf("写入 PPT 内容失败（%s）：%w", name, err)
-		}
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("写入 PPT 文件收尾失败：%w", closeErr)
 	}
-
-	if err := zw.Close(); err != nil {
-		return fmt.Errorf("写入 PPT 文件收尾失败：%w", err)
+	if err == nil {
+		if err = os.Rename(tmp, outPath); err != nil {
+			err = fmt.Errorf("保存输出文件失败：%w", err)
+		}
 	}
-	return nil
-}
-
-func loadTemplateFiles() (map[string][]byte, error) {
-	zr, err := zip.NewReader(bytes.NewReader(defaultTemplate), int64(len(defaultTemplate)))
 	if err != nil {
-		return nil, fmt.Errorf("加载内置 PPT 模板失败：%w", err)
-	}
-	files := make(map[string][]byte, len(zr.File)+16)
-	for _, f := range zr.File {
-		rc, err := f.Open()
-		if err != nil {
-			return nil, fmt.Errorf("读取模板文件失败（%s）：%w", f.Name, err)
-		}
-		b, err := io.ReadAll(rc)
-		rc.Close()
-		if err != nil {
-			return nil, fmt.Errorf("读取模板内容失败（%s）：%w", f.Name, err)
-		}
-		files[f.Name] = b
+		_ = os.Remove(tmp)
 	}
-	return files, nil
+	return err
 }
 
 func toEMU(in float64) int64 {
-	return int64(in * emuPerInch)
+	return int64(math.Round(in * emuPerInch))
 }
 
 func applyDeckDefaults(deck *Deck) {
@@ -153,62 +146,160 @@ func applyDeckDefaults(deck *Deck) {
 	if deck.Styles.FormulaFill == "" {
 		deck.Styles.FormulaFill = "FFF176"
 	}
+	if deck.Styles.CodeFont == "" {
+		deck.Styles.CodeFont = "Consolas"
+	}
+	if deck.Styles.CodeColor == "" {
+		deck.Styles.CodeColor = "9F1239"
+	}
+	if deck.Styles.CodeFill == "" {
+		deck.Styles.CodeFill = "F3F4F6"
+	}
+	if deck.Styles.TableHeaderFill == "" {
+		deck.Styles.TableHeaderFill = "1F2937"
+	}
+	if deck.Styles.TableHeaderColor == "" {
+		deck.Styles.TableHeaderColor = "FFFFFF"
+	}
+	if deck.Styles.TableBorderColor == "" {
+		deck.Styles.TableBorderColor = "D1D5DB"
+	}
 }
 
-func slideXML(slide render.Slide, deck Deck) string {
+func slideXML(slide render.Slide, deck Deck, pics []picture, links *hyperlinks) string {
 	pad := toEMU(deck.PaddingIn)
 	gap := toEMU(deck.GapIn)
 	totalW := toEMU(deck.SlideWidthIn)
 	totalH := toEMU(deck.SlideHeightIn)
-	usableW := totalW - 2*pad - gap
-	leftW := int64(float64(usableW) * deck.LeftRatio)
-	rightW := usableW - leftW
+	n := len(slide.Columns)
+	usableW := totalW - 2*pad
+	if n > 1 {
+		usableW -= gap * int64(n-1)
+	}
 	h := totalH - 2*pad
 
-	en := renderColumnXML(slide, 0, pad, pad, leftW, h, 2, deck)
-	cn := renderColumnXML(slide, 1, pad+leftW+gap, pad, rightW, h, 3, deck)
+	var columns strings.Builder
+	ratios := columnRatios(slide.Columns, deck.LeftRatio)
+	x := pad
+	// 图片、表格的形状编号从 100 开始，避开文本框和角标。
+	nextID := 100
+	for i := range slide.Columns {
+		w := int64(float64(usableW) * ratios[i])
+		if i == n-1 {
+			w = totalW - pad - x
+		}
+		columns.WriteString(renderColumnXML(slide, i, x, pad, w, h, i+2, deck, links))
+		for _, pic := range pics {
+			if pic.column == i {
+				columns.WriteString(pictureXML(pic, x, pad, nextID))
+				nextID++
+			}
+		}
+		ts := textStyle{lang: escapeXMLText(columnLangTag(slide.Columns[i])), styles: deck.Styles, links: links}
+		for _, block := range slide.Columns[i].Blocks {
+			if block.Kind == render.BlockTable && block.Table != nil {
+				columns.WriteString(tableXML(block.Table, x, pad, nextID, slide.FontSize, ts))
+				nextID++
+			}
+		}
+		x += w + gap
+	}
 	badge := ""
 	if slide.HasTruncationBadge {
 		badge = truncationBadgeXML(totalW, totalH, pad)
 	}
+	if slide.PageCount > 1 {
+		badge += pageLabelXML(slide.Page, slide.PageCount, totalW, totalH, pad)
+	}
 
 	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
-<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:cSld><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/>` + en + cn + badge + `</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`
+<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><p:cSld><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr/>` + columns.String() + badge + `</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`
 }
 
-func renderColumnXML(slide render.Slide, colIndex int, x, y, cx, cy int64, shapeID int, deck Deck) string {
-	if colIndex >= len(slide.Columns) {
-		return ""
+// columnRatios 优先使用排版时算好的栏宽比例；没有时两栏按 LeftRatio，其余平分。
+func columnRatios(columns []render.Column, leftRatio float64) []float64 {
+	out := make([]float64, len(columns))
+	complete := len(columns) > 0
+	for i, c := range columns {
+		out[i] = c.Ratio
+		if c.Ratio <= 0 {
+			complete = false
+		}
 	}
-	column := slide.Columns[colIndex]
-	lang := "en-US"
-	if strings.EqualFold(column.Lang, "CN") {
-		lang = "zh-CN"
+	if complete {
+		return out
 	}
+	if len(columns) == 2 {
+		out[0], out[1] = leftRatio, 1-leftRatio
+		return out
+	}
+	for i := range out {
+		out[i] = 1 / float64(len(columns))
+	}
+	return out
+}
 
-	var paragraphs strings.Builder
-	for _, block := range column.Blocks {
-		paragraphs.WriteString(paragraphXML(block, slide.FontSize, lang, deck.Styles))
+func columnLangTag(column render.Column) string {
+	if column.Tag != "" {
+		return column.Tag
+	}
+	if strings.EqualFold(column.Lang, "CN") {
+		return "zh-CN"
 	}
-	if paragraphs.Len() == 0 {
-		paragraphs.WriteString(`<a:p><a:endParaRPr lang="` + lang + `"/></a:p>`)
+	return "en-US"
+}
+
+func renderColumnXML(slide render.Slide, colIndex int, x, y, cx, cy int64, shapeID int, deck Deck, links *hyperlinks) string {
+	if colIndex >= len(slide.Columns) {
+		return ""
 	}
+	column := slide.Columns[colIndex]
+	lang := escapeXMLText(columnLangTag(column))
 
-	name := "TextBox EN"
-	numCol := slide.ENNumCol
-	if colIndex == 1 {
-		name = "TextBox CN"
-		numCol = slide.CNNumCol
+	paragraphs := func(equations bool) string {
+		var b strings.Builder
+		var numbering listNumbering
+		for _, block := range column.Blocks {
+			b.WriteString(paragraphXML(block, slide.FontSize, numbering.startAt(block), textStyle{lang: lang, styles: deck.Styles, equations: equations, links: links}))
+		}
+		if b.Len() == 0 {
+			b.WriteString(`<a:p><a:endParaRPr lang="` + lang + `"/></a:p>`)
+		}
+		return b.String()
 	}
+
+	name := escapeXMLText("TextBox " + column.Lang)
+	numCol := column.NumCol
 	if numCol < 1 {
 		numCol = 1
 	}
 	bodyPr := `<a:bodyPr wrap="square" numCol="` + strconv.Itoa(numCol) + `"><a:spAutoFit/></a:bodyPr>`
 
-	return fmt.Sprintf(`<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr><p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr><p:txBody>%s<a:lstStyle/>%s</p:txBody></p:sp>`, shapeID, name, x, y, cx, cy, bodyPr, paragraphs.String())
+	shape := func(body string) string {
+		return fmt.Sprintf(`<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr><p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr><p:txBody>%s<a:lstStyle/>%s</p:txBody></p:sp>`, shapeID, name, x, y, cx, cy, bodyPr, body)
+	}
+	if !hasMath(column.Blocks) {
+		return shape(paragraphs(false))
+	}
+	// 公式对象要求 PowerPoint 2010 及以上；其他阅读器退回到高亮文本。
+	return `<mc:AlternateContent xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"><mc:Choice xmlns:a14="http://schemas.microsoft.com/office/drawing/2010/main" Requires="a14">` + shape(paragraphs(true)) + `</mc:Choice><mc:Fallback>` + shape(paragraphs(false)) + `</mc:Fallback></mc:AlternateContent>`
+}
+
+// textStyle 是写文字时共用的设置。equations 为 true 时公式写成 a14:m 公式对象，
+// 转换失败的仍按高亮文本显示；links 登记超链接关系，为 nil 时不写链接。
+type textStyle struct {
+	lang      string
+	styles    StylePalette
+	equations bool
+	links     *hyperlinks
 }
 
-func paragraphXML(block render.Block, fontSize int, lang string, styles StylePalette) string {
+// paragraphXML 输出一个段落。
+func paragraphXML(block render.Block, fontSize, startAt int, ts textStyle) string {
+	lang, styles := ts.lang, ts.styles
+	if f := block.Frame(); f != nil {
+		return framePlaceholderXML(f, fontSize, lang)
+	}
 	runs := block.Runs
 	if len(runs) == 0 {
 		return `<a:p><a:endParaRPr lang="` + lang + `"/></a:p>`
@@ -227,9 +318,41 @@ func paragraphXML(block render.Block, fontSize int, lang string, styles StylePal
 		runs = append([]render.Run{{Text: prefix, Bold: block.Marker != render.MarkerDot}}, runs...)
 	}
 
+	size := fontSize
+	if scale := block.FontScale(); scale != 1 {
+		size = int(math.Round(float64(fontSize) * scale))
+	}
+
+	color := styles.BaseColor
+	switch block.Marker {
+	case render.MarkerStar:
+		color = styles.StarColor
+	case render.MarkerWarn:
+		color = styles.WarnColor
+	case render.MarkerDot:
+		color = styles.DotColor
+	}
+
 	var b strings.Builder
 	b.WriteString(`<a:p>`) // keep minimal to maximize compatibility
+	b.WriteString(paragraphPropsXML(block, fontSize, startAt, prefix != ""))
+	b.WriteString(runsXML(runs, size, color, ts))
+	b.WriteString(`<a:endParaRPr lang="` + lang + `"/></a:p>`)
+	return b.String()
+}
+
+// runsXML 输出一串文字；公式用 FormulaColor 高亮，行内代码用等宽字体和底色。
+func runsXML(runs []render.Run, size int, color string, ts textStyle) string {
+	lang, styles := ts.lang, ts.styles
+	var b strings.Builder
 	for _, r := range runs {
+		if r.Formula && ts.equations {
+			rPr := `<a:rPr lang="` + lang + `" sz="` + strconv.Itoa(size*100) + `"><a:solidFill><a:srgbClr val="` + color + `"/></a:solidFill><a:latin typeface="Cambria Math"/></a:rPr>`
+			if r.Math != "" {
+				b.WriteString(`<a14:m>` + strings.ReplaceAll(r.Math, omml.RunProps, rPr) + `</a14:m>`)
+				continue
+			}
+		}
 		rawText := r.Text
 		if r.Formula {
 			// 公式按原样展示：保留 $...$ 包裹符号。
@@ -239,45 +362,134 @@ func paragraphXML(block render.Block, fontSize int, lang string, styles StylePal
 		if text == "" {
 			continue
 		}
-		color := styles.BaseColor
+		runColor := color
 		highlight := ""
-		switch block.Marker {
-		case render.MarkerStar:
-			color = styles.StarColor
-		case render.MarkerWarn:
-			color = styles.WarnColor
-		case render.MarkerDot:
-			color = styles.DotColor
-		}
-		if r.Formula {
-			color = styles.FormulaColor
+		switch {
+		case r.Formula:
+			runColor = styles.FormulaColor
 			highlight = styles.FormulaFill
+		case r.Code:
+			runColor = styles.CodeColor
+			highlight = styles.CodeFill
 		}
-		b.WriteString(`<a:r><a:rPr lang="` + lang + `" sz="` + strconv.Itoa(fontSize*100) + `"`)
+		b.WriteString(`<a:r><a:rPr lang="` + lang + `" sz="` + strconv.Itoa(size*100) + `"`)
 		if r.Bold {
 			b.WriteString(` b="1"`)
 		}
 		if r.Italic {
 			b.WriteString(` i="1"`)
 		}
-		b.WriteString(`><a:solidFill><a:srgbClr val="` + color + `"/></a:solidFill>`)
+		if r.Underline {
+			b.WriteString(` u="sng"`)
+		}
+		if r.Strike {
+			b.WriteString(` strike="sngStrike"`)
+		}
+		b.WriteString(`><a:solidFill><a:srgbClr val="` + runColor + `"/></a:solidFill>`)
 		if highlight != "" {
 			b.WriteString(`<a:highlight><a:srgbClr val="` + highlight + `"/></a:highlight>`)
 		}
+		if r.Code {
+			font := escapeXMLText(styles.CodeFont)
+			b.WriteString(`<a:latin typeface="` + font + `"/><a:cs typeface="` + font + `"/>`)
+		}
+		if id := ts.links.id(r.Link); id != "" {
+			b.WriteString(`<a:hlinkClick r:id="` + id + `"/>`)
+		}
 		b.WriteString(`</a:rPr><a:t>` + text + `</a:t></a:r>`)
 	}
-	b.WriteString(`<a:endParaRPr lang="` + lang + `"/></a:p>`)
 	return b.String()
 }
 
+// framePlaceholderXML 输出一个固定行高的空段落，给图片、表格占位，让后面的文字排在它们下方。
+func framePlaceholderXML(f *render.Frame, fontSize int, lang string) string {
+	if f.ReservePt <= 0 {
+		return ""
+	}
+	// spcPts 单位为百分之一磅，上限 1584 磅。
+	pts := int(math.Min(math.Round(f.ReservePt*100), 158400))
+	return `<a:p><a:pPr><a:lnSpc><a:spcPts val="` + strconv.Itoa(pts) + `"/></a:lnSpc></a:pPr><a:endParaRPr lang="` + lang + `" sz="` + strconv.Itoa(fontSize*100) + `"/></a:p>`
+}
+
+// hasMath 判断栏内是否有能转成公式对象的公式。
+func hasMath(blocks []render.Block) bool {
+	for _, block := range blocks {
+		for _, r := range block.Runs {
+			if r.Formula && r.Math != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 各级无序列表使用的项目符号。
+var bulletChars = []string{"•", "–", "▪"}
+
+// paragraphPropsXML 输出缩进层级和项目符号；顶格普通段落和标题不写 a:pPr。
+func paragraphPropsXML(block render.Block, fontSize, startAt int, hasMarker bool) string {
+	lvl := block.OutlineLevel()
+	if block.Kind == render.BlockHeading || (block.Kind == render.BlockParagraph && lvl == 0) {
+		return ""
+	}
+	emu := func(em float64) int64 { return int64(em * float64(fontSize) * 12700) }
+	attrs := fmt.Sprintf(` marL="%d" lvl="%d"`, emu(block.IndentEm()), lvl)
+	if hang := block.HangingEm(); hang > 0 {
+		attrs += fmt.Sprintf(` indent="%d"`, -emu(hang))
+	}
+
+	bullet := `<a:buNone/>`
+	switch {
+	case block.Kind == render.BlockNumbered:
+		start := ""
+		if startAt > 1 {
+			start = ` startAt="` + strconv.Itoa(startAt) + `"`
+		}
+		bullet = `<a:buFont typeface="+mj-lt"/><a:buAutoNum type="arabicPeriod"` + start + `/>`
+	case block.Kind == render.BlockBullet && !hasMarker:
+		// ★/●/▲ 自带前缀，不再叠加项目符号。
+		bullet = `<a:buFont typeface="Arial"/><a:buChar char="` + bulletChars[lvl%len(bulletChars)] + `"/>`
+	}
+	return `<a:pPr` + attrs + `>` + bullet + `</a:pPr>`
+}
+
+// listNumbering 给连续的有序列表项算出起始编号；PowerPoint 会按 startAt 自动续号。
+type listNumbering struct {
+	open   [9]bool
+	starts [9]int
+}
+
+func (n *listNumbering) startAt(block render.Block) int {
+	lvl := block.OutlineLevel()
+	switch block.Kind {
+	case render.BlockHeading:
+		n.closeFrom(0)
+	case render.BlockNumbered:
+		n.closeFrom(lvl + 1)
+		if !n.open[lvl] {
+			n.open[lvl], n.starts[lvl] = true, block.Number
+		}
+		return n.starts[lvl]
+	default:
+		n.closeFrom(lvl)
+	}
+	return 0
+}
+
+func (n *listNumbering) closeFrom(lvl int) {
+	for i := lvl; i < len(n.open); i++ {
+		n.open[i] = false
+	}
+}
+
 func escapeXMLText(s string) string {
 	var buf bytes.Buffer
 	xml.EscapeText(&buf, []byte(s))
 	return buf.String()
 }
 
-func contentTypesXML(base string, slideCount int) string {
-	clean := slideOverrideRe.ReplaceAllString(base, "")
+func contentTypesXML(base string, slideCount int, notesSlides []int, notesTheme string) string {
+	clean := strippedOverrideRe.ReplaceAllString(base, "")
 	idx := strings.LastIndex(clean, "</Types>")
 	if idx < 0 {
 		return clean
@@ -286,23 +498,35 @@ func contentTypesXML(base string, slideCount int) string {
 	for i := 1; i <= slideCount; i++ {
 		overrides.WriteString(fmt.Sprintf(`<Override PartName="/ppt/slides/slide%d.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slide+xml"/>`, i))
 	}
+	if len(notesSlides) > 0 {
+		overrides.WriteString(`<Override PartName="/ppt/notesMasters/notesMaster1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.notesMaster+xml"/>`)
+		overrides.WriteString(`<Override PartName="/ppt/theme/` + notesTheme + `" ContentType="application/vnd.openxmlformats-officedocument.theme+xml"/>`)
+	}
+	for _, n := range notesSlides {
+		overrides.WriteString(fmt.Sprintf(`<Override PartName="/ppt/notesSlides/notesSlide%d.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.notesSlide+xml"/>`, n))
+	}
 	return clean[:idx] + overrides.String() + clean[idx:]
 }
 
-func presentationRelsXML(base string, slideCount int) string {
-	clean := slideRelRe.ReplaceAllString(base, "")
-	idx := strings.LastIndex(clean, "</Relationships>")
-	if idx < 0 {
-		return clean
-	}
+func presentationRelsXML(tpl *template, slideCount int, hasNotes bool) string {
 	var rels strings.Builder
+	rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for _, rel := range tpl.presRels {
+		rels.WriteString(relationshipXML(rel))
+	}
 	for i := 1; i <= slideCount; i++ {
-		rels.WriteString(fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide%d.xml"/>`, i+6, i))
+		rels.WriteString(relationshipXML(relationship{ID: tpl.slideRelID(i), Type: relTypeSlide, Target: fmt.Sprintf("slides/slide%d.xml", i)}))
+	}
+	if hasNotes {
+		rels.WriteString(relationshipXML(relationship{ID: tpl.notesMasterRelID(slideCount), Type: relTypeNotesMaster, Target: "notesMasters/notesMaster1.xml"}))
 	}
-	return clean[:idx] + rels.String() + clean[idx:]
+	rels.WriteString(`</Relationships>`)
+	return rels.String()
 }
 
-func presentationXML(base string, slideCount int, cx, cy int64) string {
+// presentationXML 保留模板的母版列表、备注尺寸和默认文字样式，其余按生成结果重写。
+func presentationXML(base string, tpl *template, slideCount int, hasNotes bool, cx, cy int64) string {
 	noDecl := xmlDeclRe.ReplaceAllString(base, "")
 	noDecl = strings.TrimSpace(noDecl)
 	openEnd := strings.Index(noDecl, ">")
@@ -315,29 +539,75 @@ func presentationXML(base string, slideCount int, cx, cy int64) string {
 		defaultTextStyle = `<p:defaultTextStyle><a:defPPr><a:defRPr lang="en-US"/></a:defPPr></p:defaultTextStyle>`
 	}
 
+	masters := extractTag(noDecl, "p:sldMasterIdLst")
+	if masters == "" {
+		masters = `<p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst>`
+	}
+	notesSize := extractTag(noDecl, "p:notesSz")
+	if notesSize == "" {
+		notesSize = `<p:notesSz cx="6858000" cy="9144000"/>`
+	}
+	notesMaster := ""
+	if hasNotes {
+		notesMaster = `<p:notesMasterIdLst><p:notesMasterId r:id="` + tpl.notesMasterRelID(slideCount) + `"/></p:notesMasterIdLst>`
+	}
+
 	var slideIDs strings.Builder
 	if slideCount > 0 {
 		slideIDs.WriteString(`<p:sldIdLst>`)
 		for i := 1; i <= slideCount; i++ {
-			slideIDs.WriteString(fmt.Sprintf(`<p:sldId id="%d" r:id="rId%d"/>`, 255+i, 6+i))
+			slideIDs.WriteString(fmt.Sprintf(`<p:sldId id="%d" r:id="%s"/>`, 255+i, tpl.slideRelID(i)))
 		}
 		slideIDs.WriteString(`</p:sldIdLst>`)
 	}
 
 	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + openTag +
-		`<p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst>` +
+		masters +
+		notesMaster +
+		extractTag(noDecl, "p:handoutMasterIdLst") +
 		slideIDs.String() +
-		`<p:sldSz cx="` + strconv.FormatInt(cx, 10) + `" cy="` + strconv.FormatInt(cy, 10) + `" type="screen16x9"/>` +
-		`<p:notesSz cx="6858000" cy="9144000"/>` +
+		`<p:sldSz cx="` + strconv.FormatInt(cx, 10) + `" cy="` + strconv.FormatInt(cy, 10) + `"` + slideSizeType(cx, cy) + `/>` +
+		notesSize +
+		extractTag(noDecl, "p:embeddedFontLst") +
 		defaultTextStyle +
 		`</p:presentation>`
 }
 
-func extractTag(xmlText string, tag string) string {
-	start := strings.Index(xmlText, "<"+tag)
-	if start < 0 {
+// slideSizeType 按宽高比给出 p:sldSz 的 type，不是常见比例时省略（即自定义尺寸）。
+func slideSizeType(cx, cy int64) string {
+	if cy <= 0 {
 		return ""
 	}
+	ratio := float64(cx) / float64(cy)
+	for _, t := range []struct {
+		ratio float64
+		name  string
+	}{{16.0 / 9, "screen16x9"}, {16.0 / 10, "screen16x10"}, {4.0 / 3, "screen4x3"}} {
+		if math.Abs(ratio-t.ratio) < 0.01 {
+			return ` type="` + t.name + `"`
+		}
+	}
+	return ""
+}
+
+func extractTag(xmlText string, tag string) string {
+	start := -1
+	for from := 0; ; {
+		i := strings.Index(xmlText[from:], "<"+tag)
+		if i < 0 {
+			return ""
+		}
+		i += from
+		next := i + 1 + len(tag)
+		if next < len(xmlText) && strings.IndexByte(" \t\r\n/>", xmlText[next]) >= 0 {
+			start = i
+			break
+		}
+		from = next
+	}
+	if open := strings.Index(xmlText[start:], ">"); open > 0 && xmlText[start+open-1] == '/' {
+		return xmlText[start : start+open+1]
+	}
 	endToken := "</" + tag + ">"
 	end := strings.Index(xmlText[start:], endToken)
 	if end < 0 {
@@ -347,9 +617,16 @@ func extractTag(xmlText string, tag string) string {
 	return xmlText[start:end]
 }
 
-func slideRelsXML() string {
+func slideRelsXML(layoutTarget string, slideNo int, hasNotes bool, extra []relationship) string {
+	var rels strings.Builder
+	if hasNotes {
+		rels.WriteString(fmt.Sprintf(`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="../notesSlides/notesSlide%d.xml"/>`, slideNo))
+	}
+	for _, rel := range extra {
+		rels.WriteString(relationshipXML(rel))
+	}
 	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
-<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout7.xml"/></Relationships>`
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="` + escapeXMLText(layoutTarget) + `"/>` + rels.String() + `</Relationships>`
 }
 
 func truncationBadgeXML(totalW, totalH, pad int64) string {
@@ -360,14 +637,25 @@ func truncationBadgeXML(totalW, totalH, pad int64) string {
 	return fmt.Sprintf(`<p:sp><p:nvSpPr><p:cNvPr id="99" name="Truncation Badge"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr><p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="roundRect"><a:avLst/></a:prstGeom><a:solidFill><a:srgbClr val="FFF3CD"/></a:solidFill><a:ln w="12700"><a:solidFill><a:srgbClr val="DC2626"/></a:solidFill></a:ln></p:spPr><p:txBody><a:bodyPr wrap="square"/><a:lstStyle/><a:p><a:pPr algn="ctr"/><a:r><a:rPr lang="zh-CN" sz="1200" b="1"><a:solidFill><a:srgbClr val="B91C1C"/></a:solidFill></a:rPr><a:t>【本页内容有截断】</a:t></a:r><a:endParaRPr lang="zh-CN"/></a:p></p:txBody></p:sp>`, x, y, badgeW, badgeH)
 }
 
-func appPropsXML(slideCount int) string {
+func pageLabelXML(page, count int, totalW, totalH, pad int64) string {
+	text := fmt.Sprintf("(%d/%d)", page, count)
+	if page > 1 {
+		text = fmt.Sprintf("(cont. %d/%d)", page, count)
+	}
+	labelW := toEMU(2.0)
+	x := totalW - pad - labelW
+	y := totalH - pad
+	return fmt.Sprintf(`<p:sp><p:nvSpPr><p:cNvPr id="98" name="Page Label"/><p:cNvSpPr txBox="1"/><p:nvPr/></p:nvSpPr><p:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom><a:noFill/></p:spPr><p:txBody><a:bodyPr wrap="none" lIns="0" tIns="0" rIns="0" bIns="0" anchor="ctr"/><a:lstStyle/><a:p><a:pPr algn="r"/><a:r><a:rPr lang="en-US" sz="1000"><a:solidFill><a:srgbClr val="6B7280"/></a:solidFill></a:rPr><a:t>%s</a:t></a:r><a:endParaRPr lang="en-US"/></a:p></p:txBody></p:sp>`, x, y, labelW, pad, text)
+}
+
+func appPropsXML(slideCount, notesCount int) string {
 	var titles strings.Builder
 	titles.WriteString(`<vt:lpstr>Office Theme</vt:lpstr>`)
 	for i := 1; i <= slideCount; i++ {
 		titles.WriteString(fmt.Sprintf(`<vt:lpstr>幻灯片 %d</vt:lpstr>`, i))
 	}
 	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
-<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes"><TotalTime>1</TotalTime><Words>0</Words><Application>syl-md2ppt</Application><PresentationFormat>On-screen Show (16:9)</PresentationFormat><Paragraphs>0</Paragraphs><Slides>%d</Slides><Notes>0</Notes><HiddenSlides>0</HiddenSlides><MMClips>0</MMClips><ScaleCrop>false</ScaleCrop><HeadingPairs><vt:vector size="4" baseType="variant"><vt:variant><vt:lpstr>Theme</vt:lpstr></vt:variant><vt:variant><vt:i4>1</vt:i4></vt:variant><vt:variant><vt:lpstr>Slide Titles</vt:lpstr></vt:variant><vt:variant><vt:i4>%d</vt:i4></vt:variant></vt:vector></HeadingPairs><TitlesOfParts><vt:vector size="%d" baseType="lpstr">%s</vt:vector></TitlesOfParts><Manager></Manager><Company></Company><LinksUpToDate>false</LinksUpToDate><SharedDoc>false</SharedDoc><HyperlinkBase></HyperlinkBase><HyperlinksChanged>false</HyperlinksChanged><AppVersion>16.0000</AppVersion></Properties>`, slideCount, slideCount, slideCount+1, titles.String())
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes"><TotalTime>1</TotalTime><Words>0</Words><Application>syl-md2ppt</Application><PresentationFormat>On-screen Show (16:9)</PresentationFormat><Paragraphs>0</Paragraphs><Slides>%d</Slides><Notes>%d</Notes><HiddenSlides>0</HiddenSlides><MMClips>0</MMClips><ScaleCrop>false</ScaleCrop><HeadingPairs><vt:vector size="4" baseType="variant"><vt:variant><vt:lpstr>Theme</vt:lpstr></vt:variant><vt:variant><vt:i4>1</vt:i4></vt:variant><vt:variant><vt:lpstr>Slide Titles</vt:lpstr></vt:variant><vt:variant><vt:i4>%d</vt:i4></vt:variant></vt:vector></HeadingPairs><TitlesOfParts><vt:vector size="%d" baseType="lpstr">%s</vt:vector></TitlesOfParts><Manager></Manager><Company></Company><LinksUpToDate>false</LinksUpToDate><SharedDoc>false</SharedDoc><HyperlinkBase></HyperlinkBase><HyperlinksChanged>false</HyperlinksChanged><AppVersion>16.0000</AppVersion></Properties>`, slideCount, notesCount, slideCount, slideCount+1, titles.String())
 }
 
 func corePropsXML(now time.Time) string {
@@ -3,7 +3,9 @@ package pptx
 import (
 	"archive/zip"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -204,12 +206,10 @@ func TestWritePPTX_TruncationBadgeRendered(t *testing.T) {
 		SlideHeightIn: 7.5,
 		Slides: []render.Slide{{
 			FontSize:           12,
-			ENNumCol:           1,
-			CNNumCol:           2,
 			HasTruncationBadge: true,
 			Columns: []render.Column{
-				{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: "EN"}}}}},
-				{Lang: "CN", Blocks: []render.Block{{Runs: []render.Run{{Text: "CN"}}}}},
+				{Lang: "EN", NumCol: 1, Blocks: []render.Block{{Runs: []render.Run{{Text: "EN"}}}}},
+				{Lang: "CN", NumCol: 2, Blocks: []render.Block{{Runs: []render.Run{{Text: "CN"}}}}},
 			},
 		}},
 	}
@@ -265,7 +265,7 @@ func TestWritePPTX_FormulaKeepsRawAndHighlighted(t *testing.T) {
 					Lang: "EN",
 					Blocks: []render.Block{{
 						Runs: []render.Run{
-							{Text: "10^9", Formula: true},
+							render.NewFormulaRun("10^9"),
 						},
 					}},
 				},
@@ -311,6 +311,434 @@ func TestWritePPTX_FormulaKeepsRawAndHighlighted(t *testing.T) {
 	}
 }
 
+func TestWritePPTX_FormulaAsEquation(t *testing.T) {
+	tmp := t.TempDir()
+	out := filepath.Join(tmp, "equation.pptx")
+
+	deck := Deck{
+		SlideWidthIn:  13.333,
+		SlideHeightIn: 7.5,
+		Slides: []render.Slide{{
+			FontSize: 20,
+			Columns: []render.Column{
+				{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{
+					{Text: "area "},
+					render.NewFormulaRun(`\frac{\pi r^2}{2}`),
+				}}}},
+				{Lang: "CN", Blocks: []render.Block{{Runs: []render.Run{render.NewFormulaRun(`\unknown{x}`)}}}},
+			},
+		}},
+	}
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	slide := readZipFile(t, &zr.Reader, "ppt/slides/slide1.xml")
+	if strings.Count(slide, "<mc:AlternateContent") != 1 {
+		t.Fatalf("expected only the EN column to carry an equation, got: %s", slide)
+	}
+	choice := slide[strings.Index(slide, "<mc:Choice"):strings.Index(slide, "</mc:Choice>")]
+	for _, want := range []string{`Requires="a14"`, "<a14:m><m:oMath", "<m:f><m:num>", "<m:sSup>", "<m:t>π</m:t>", "Cambria Math"} {
+		if !strings.Contains(choice, want) {
+			t.Fatalf("equation missing %q: %s", want, choice)
+		}
+	}
+	fallback := slide[strings.Index(slide, "<mc:Fallback>"):strings.Index(slide, "</mc:Fallback>")]
+	if !strings.Contains(fallback, `<a:t>$\frac{\pi r^2}{2}$</a:t>`) || strings.Contains(fallback, "a14:m") {
+		t.Fatalf("fallback should keep highlighted text: %s", fallback)
+	}
+	if !strings.Contains(slide, `<a:t>$\unknown{x}$</a:t>`) {
+		t.Fatalf("unconvertible formula should stay as text: %s", slide)
+	}
+}
+
+func TestWritePPTX_Images(t *testing.T) {
+	tmp := t.TempDir()
+	pngPath := filepath.Join(tmp, "chart.png")
+	svgPath := filepath.Join(tmp, "logo.svg")
+	if err := os.WriteFile(pngPath, []byte("\x89PNG fake"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(svgPath, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"/>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chart := render.Block{Kind: render.BlockImage, Image: &render.Image{Path: pngPath, Alt: "Chart", Format: "png", Frame: render.Frame{DisplayW: 144, DisplayH: 72, ReservePt: 72, LeftIn: 0.1, TopIn: 0.05}}}
+	logo := render.Block{Kind: render.BlockImage, Image: &render.Image{Path: svgPath, Format: "svg", Frame: render.Frame{DisplayW: 72, DisplayH: 72, ReservePt: 96}}}
+	deck := Deck{SlideWidthIn: 13.333, SlideHeightIn: 7.5, PaddingIn: 0.3}
+	for i := 0; i < 2; i++ {
+		deck.Slides = append(deck.Slides, render.Slide{
+			FontSize: 20,
+			Columns: []render.Column{
+				{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: "before"}}}, chart}},
+				{Lang: "CN", Blocks: []render.Block{logo}},
+			},
+		})
+	}
+	out := filepath.Join(tmp, "images.pptx")
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	if got := readZipFile(t, &zr.Reader, "ppt/media/image1.png"); got != "\x89PNG fake" {
+		t.Fatalf("png should be copied as-is, got %q", got)
+	}
+	if zipHasFile(&zr.Reader, "ppt/media/image4.png") {
+		t.Fatalf("the same image should be stored once")
+	}
+	rels := readZipFile(t, &zr.Reader, "ppt/slides/_rels/slide2.xml.rels")
+	for _, want := range []string{`Id="rId3" Type="` + relTypeImage + `" Target="../media/image1.png"`, `Id="rId4" Type="` + relTypeImage + `" Target="../media/image3.png"`, `Id="rId5" Type="` + relTypeImage + `" Target="../media/image2.svg"`} {
+		if !strings.Contains(rels, want) {
+			t.Fatalf("slide rels missing %s: %s", want, rels)
+		}
+	}
+	slide := readZipFile(t, &zr.Reader, "ppt/slides/slide1.xml")
+	if !strings.Contains(slide, `descr="Chart"/>`) || !strings.Contains(slide, `<a:blip r:embed="rId3"/>`) || !strings.Contains(slide, `<asvg:svgBlip xmlns:asvg="http://schemas.microsoft.com/office/drawing/2016/SVG/main" r:embed="rId5"/>`) {
+		t.Fatalf("expected pictures in slide xml: %s", slide)
+	}
+	// 图片位于英文栏：栏左边距 0.3 英寸加上文本框内的偏移。
+	if !strings.Contains(slide, fmt.Sprintf(`<a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/>`, toEMU(0.4), toEMU(0.35), toEMU(2), toEMU(1))) {
+		t.Fatalf("picture position or size is off: %s", slide)
+	}
+	if !strings.Contains(slide, `<a:lnSpc><a:spcPts val="7200"/></a:lnSpc>`) {
+		t.Fatalf("expected a placeholder paragraph reserving the image height: %s", slide)
+	}
+	ct := readZipFile(t, &zr.Reader, "[Content_Types].xml")
+	if !strings.Contains(ct, `Extension="svg" ContentType="image/svg+xml"`) || strings.Count(ct, `Extension="png"`) != 1 {
+		t.Fatalf("content types should declare image extensions once: %s", ct)
+	}
+}
+
+func TestWritePPTX_MissingImageFails(t *testing.T) {
+	deck := Deck{SlideWidthIn: 13.333, SlideHeightIn: 7.5, Slides: []render.Slide{{
+		FontSize: 20,
+		Columns:  []render.Column{{Lang: "EN", Blocks: []render.Block{{Kind: render.BlockImage, Image: &render.Image{Path: "/nope/a.png", Format: "png"}}}}},
+	}}}
+	if err := Write(filepath.Join(t.TempDir(), "x.pptx"), deck); err == nil || !strings.Contains(err.Error(), "/nope/a.png") {
+		t.Fatalf("expected an error naming the image, got %v", err)
+	}
+}
+
+func TestWritePPTX_Table(t *testing.T) {
+	tbl := &render.Table{
+		Align: []string{"", "right"},
+		Rows: []render.TableRow{
+			{Cells: [][]render.Run{{{Text: "Name", Bold: true}}, {{Text: "Score", Bold: true}}}, HeightPt: 36},
+			{Cells: [][]render.Run{{{Text: "a", Italic: true}}, {render.NewFormulaRun(`\sqrt{2}`)}}, HeightPt: 36},
+			{Cells: [][]render.Run{{{Text: "b"}}, {{Text: "3"}}}, HeightPt: 36},
+		},
+		Frame: render.Frame{DisplayW: 288, DisplayH: 108, ReservePt: 120, LeftIn: 0.1, TopIn: 0.05},
+	}
+	deck := Deck{
+		SlideWidthIn:  13.333,
+		SlideHeightIn: 7.5,
+		PaddingIn:     0.3,
+		Styles:        StylePalette{TableHeaderFill: "112233", TableHeaderColor: "FFEEDD", TableBandFill: "F0F0F0"},
+		Slides: []render.Slide{{
+			FontSize: 20,
+			Columns: []render.Column{
+				{Lang: "EN", Blocks: []render.Block{{Kind: render.BlockTable, Table: tbl}}},
+				{Lang: "CN", Blocks: []render.Block{{Runs: []render.Run{{Text: "测试"}}}}},
+			},
+		}},
+	}
+	out := filepath.Join(t.TempDir(), "table.pptx")
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	slide := readZipFile(t, &zr.Reader, "ppt/slides/slide1.xml")
+	fallback := slide[strings.Index(slide, "<mc:Fallback>"):]
+	for _, want := range []string{
+		`<p:graphicFrame>`,
+		fmt.Sprintf(`<p:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></p:xfrm>`, toEMU(0.4), toEMU(0.35), toEMU(4), toEMU(1.5)),
+		`<a:tblPr firstRow="1" bandRow="1"/>`,
+		fmt.Sprintf(`<a:gridCol w="%d"/>`, toEMU(2)),
+		`<a:srgbClr val="FFEEDD"/></a:solidFill></a:rPr><a:t>Name</a:t>`,
+		`<a:srgbClr val="112233"/></a:solidFill></a:tcPr>`,
+		`<a:srgbClr val="F0F0F0"/></a:solidFill></a:tcPr>`,
+		`<a:pPr algn="r"/>`,
+		`i="1"`,
+		`<a14:m><m:oMath`,
+		`<a:spcPts val="12000"/>`,
+	} {
+		if !strings.Contains(slide, want) {
+			t.Fatalf("slide missing %s: %s", want, slide)
+		}
+	}
+	if !strings.Contains(fallback, `<a:t>$\sqrt{2}$</a:t>`) {
+		t.Fatalf("table fallback should keep the formula text: %s", fallback)
+	}
+}
+
+func TestWritePPTX_HyperlinksAndCode(t *testing.T) {
+	deck := Deck{
+		SlideWidthIn:  13.333,
+		SlideHeightIn: 7.5,
+		Styles:        StylePalette{CodeFont: "Fira Code", CodeFill: "EEEEEE"},
+		Slides: []render.Slide{{
+			FontSize: 20,
+			Columns: []render.Column{
+				{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{
+					{Text: "docs", Link: "https://example.com/?a=1&b=2"},
+					{Text: " and "},
+					{Text: "again", Link: "https://example.com/?a=1&b=2"},
+					{Text: "go build", Code: true},
+					{Text: "old", Strike: true},
+					{Text: "new", Underline: true},
+				}}}},
+				{Lang: "CN", Blocks: []render.Block{{Runs: []render.Run{{Text: "链接", Link: "https://example.cn"}}}}},
+			},
+			Notes: []render.Note{{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: "ref", Link: "https://notes.example"}}}}}},
+		}},
+	}
+	out := filepath.Join(t.TempDir(), "links.pptx")
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	rels := readZipFile(t, &zr.Reader, "ppt/slides/_rels/slide1.xml.rels")
+	if strings.Count(rels, relTypeHyperlink) != 2 ||
+		!strings.Contains(rels, `Id="rId3" Type="`+relTypeHyperlink+`" Target="https://example.com/?a=1&amp;b=2" TargetMode="External"`) ||
+		!strings.Contains(rels, `Id="rId4" Type="`+relTypeHyperlink+`" Target="https://example.cn" TargetMode="External"`) {
+		t.Fatalf("expected one external relationship per distinct link: %s", rels)
+	}
+	slide := readZipFile(t, &zr.Reader, "ppt/slides/slide1.xml")
+	if strings.Count(slide, `<a:hlinkClick r:id="rId3"/></a:rPr><a:t>`) != 2 {
+		t.Fatalf("both runs should point at the same link: %s", slide)
+	}
+	if !strings.Contains(slide, `<a:highlight><a:srgbClr val="EEEEEE"/></a:highlight><a:latin typeface="Fira Code"/><a:cs typeface="Fira Code"/></a:rPr><a:t>go build</a:t>`) {
+		t.Fatalf("code run should use the monospace font and shading: %s", slide)
+	}
+	if !strings.Contains(slide, `strike="sngStrike"><a:solidFill>`) || !strings.Contains(slide, `u="sng"><a:solidFill>`) {
+		t.Fatalf("strike and underline runs should be marked: %s", slide)
+	}
+	noteRels := readZipFile(t, &zr.Reader, "ppt/notesSlides/_rels/notesSlide1.xml.rels")
+	if !strings.Contains(noteRels, `Id="rId3" Type="`+relTypeHyperlink+`" Target="https://notes.example"`) {
+		t.Fatalf("notes links need their own relationships: %s", noteRels)
+	}
+}
+
+func TestWritePPTX_ContinuationLabel(t *testing.T) {
+	tmp := t.TempDir()
+	out := filepath.Join(tmp, "cont.pptx")
+
+	deck := Deck{SlideWidthIn: 13.333, SlideHeightIn: 7.5}
+	for i := 1; i <= 2; i++ {
+		deck.Slides = append(deck.Slides, render.Slide{
+			FontSize:  20,
+			Page:      i,
+			PageCount: 2,
+			Columns:   []render.Column{{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: "A"}}}}}, {Lang: "CN", Blocks: []render.Block{{Runs: []render.Run{{Text: "B"}}}}}},
+		})
+	}
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	first := readZipFile(t, &zr.Reader, "ppt/slides/slide1.xml")
+	second := readZipFile(t, &zr.Reader, "ppt/slides/slide2.xml")
+	if !strings.Contains(first, "<a:t>(1/2)</a:t>") {
+		t.Fatalf("expected first page label, got: %s", first)
+	}
+	if !strings.Contains(second, "<a:t>(cont. 2/2)</a:t>") {
+		t.Fatalf("expected continuation label, got: %s", second)
+	}
+}
+
+func TestWritePPTX_SpeakerNotes(t *testing.T) {
+	tmp := t.TempDir()
+	out := filepath.Join(tmp, "notes.pptx")
+
+	column := []render.Column{{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: "A"}}}}}}
+	deck := Deck{
+		SlideWidthIn:  13.333,
+		SlideHeightIn: 7.5,
+		Slides: []render.Slide{
+			{FontSize: 20, Columns: column, Notes: []render.Note{
+				{Lang: "EN", Tag: "en-US", Blocks: []render.Block{{Runs: []render.Run{{Text: "Say hello"}}}}},
+				{Lang: "CN", Tag: "zh-CN", Blocks: []render.Block{{Runs: []render.Run{{Text: "打个招呼"}}}}},
+			}},
+			{FontSize: 20, Columns: column},
+		},
+	}
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	notes := readZipFile(t, &zr.Reader, "ppt/notesSlides/notesSlide1.xml")
+	if !strings.Contains(notes, "Say hello") || !strings.Contains(notes, `lang="zh-CN"`) || !strings.Contains(notes, "打个招呼") {
+		t.Fatalf("expected bilingual notes, got: %s", notes)
+	}
+	if strings.Index(notes, "Say hello") > strings.Index(notes, "打个招呼") {
+		t.Fatalf("expected EN notes before CN notes")
+	}
+	notesRels := readZipFile(t, &zr.Reader, "ppt/notesSlides/_rels/notesSlide1.xml.rels")
+	if !strings.Contains(notesRels, "../notesMasters/notesMaster1.xml") || !strings.Contains(notesRels, "../slides/slide1.xml") {
+		t.Fatalf("unexpected notes rels: %s", notesRels)
+	}
+	slideRels := readZipFile(t, &zr.Reader, "ppt/slides/_rels/slide1.xml.rels")
+	if !strings.Contains(slideRels, "../notesSlides/notesSlide1.xml") {
+		t.Fatalf("slide should link its notes page: %s", slideRels)
+	}
+	if rels := readZipFile(t, &zr.Reader, "ppt/slides/_rels/slide2.xml.rels"); strings.Contains(rels, "notesSlide") {
+		t.Fatalf("slide without notes should not link a notes page: %s", rels)
+	}
+	readZipFile(t, &zr.Reader, "ppt/notesMasters/notesMaster1.xml")
+	readZipFile(t, &zr.Reader, "ppt/theme/theme2.xml")
+
+	types := readZipFile(t, &zr.Reader, "[Content_Types].xml")
+	for _, want := range []string{"/ppt/notesMasters/notesMaster1.xml", "/ppt/notesSlides/notesSlide1.xml", "/ppt/theme/theme2.xml"} {
+		if !strings.Contains(types, want) {
+			t.Fatalf("missing content type override for %s", want)
+		}
+	}
+	if strings.Contains(types, "/ppt/notesSlides/notesSlide2.xml") {
+		t.Fatalf("unexpected override for slide without notes")
+	}
+	presentation := readZipFile(t, &zr.Reader, "ppt/presentation.xml")
+	if !strings.Contains(presentation, `<p:notesMasterIdLst><p:notesMasterId r:id="rId9"/></p:notesMasterIdLst>`) {
+		t.Fatalf("expected notes master id list, got: %s", presentation)
+	}
+	presRels := readZipFile(t, &zr.Reader, "ppt/_rels/presentation.xml.rels")
+	if !strings.Contains(presRels, `Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesMaster"`) {
+		t.Fatalf("expected notes master relationship, got: %s", presRels)
+	}
+}
+
+func TestWritePPTX_OutlineLevelsAndBullets(t *testing.T) {
+	tmp := t.TempDir()
+	out := filepath.Join(tmp, "outline.pptx")
+
+	text := func(s string) []render.Run { return []render.Run{{Text: s}} }
+	deck := Deck{Slides: []render.Slide{{
+		FontSize: 20,
+		Columns: []render.Column{{Lang: "EN", Blocks: []render.Block{
+			{Kind: render.BlockHeading, Level: 1, Runs: []render.Run{{Text: "Title", Bold: true}}},
+			{Kind: render.BlockBullet, Runs: text("top")},
+			{Kind: render.BlockBullet, Depth: 1, Runs: text("nested")},
+			{Kind: render.BlockNumbered, Depth: 1, Number: 3, Runs: text("third")},
+			{Kind: render.BlockNumbered, Depth: 1, Number: 4, Runs: text("fourth")},
+			{Kind: render.BlockParagraph, Runs: text("plain")},
+		}}},
+	}}}
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	slide := readZipFile(t, &zr.Reader, "ppt/slides/slide1.xml")
+	for _, want := range []string{
+		`sz="3000" b="1"`,
+		`<a:pPr marL="254000" lvl="0" indent="-254000"><a:buFont typeface="Arial"/><a:buChar char="•"/></a:pPr>`,
+		`lvl="1" indent="-254000"><a:buFont typeface="Arial"/><a:buChar char="–"/>`,
+		`<a:buAutoNum type="arabicPeriod" startAt="3"/>`,
+	} {
+		if !strings.Contains(slide, want) {
+			t.Fatalf("expected %s in slide xml: %s", want, slide)
+		}
+	}
+	if strings.Count(slide, `startAt="3"`) != 2 {
+		t.Fatalf("consecutive numbered items should share the same start: %s", slide)
+	}
+	if !strings.Contains(slide, `<a:p><a:r><a:rPr lang="en-US" sz="2000"><a:solidFill><a:srgbClr val="1F2937"/></a:solidFill></a:rPr><a:t>plain</a:t>`) {
+		t.Fatalf("plain paragraph should not get paragraph properties: %s", slide)
+	}
+}
+
+func TestWritePPTX_ColumnPerLanguage(t *testing.T) {
+	tmp := t.TempDir()
+	out := filepath.Join(tmp, "langs.pptx")
+
+	deck := Deck{
+		SlideWidthIn:  13.333,
+		SlideHeightIn: 7.5,
+		Slides: []render.Slide{{
+			FontSize: 20,
+			Columns: []render.Column{
+				{Lang: "EN", Tag: "en-US", Blocks: []render.Block{{Runs: []render.Run{{Text: "Hello"}}}}},
+				{Lang: "JA", Tag: "ja-JP", Blocks: []render.Block{{Runs: []render.Run{{Text: "こんにちは"}}}}},
+				{Lang: "KO", Tag: "ko-KR", Blocks: []render.Block{{Runs: []render.Run{{Text: "안녕하세요"}}}}},
+			},
+		}},
+	}
+	if err := Write(out, deck); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+
+	slide := readZipFile(t, &zr.Reader, "ppt/slides/slide1.xml")
+	for _, want := range []string{`name="TextBox EN"`, `name="TextBox JA"`, `name="TextBox KO"`, `lang="ja-JP"`, `lang="ko-KR"`} {
+		if !strings.Contains(slide, want) {
+			t.Fatalf("expected %s in slide xml", want)
+		}
+	}
+	if strings.Contains(slide, `lang="zh-CN"`) {
+		t.Fatalf("unexpected zh-CN tag in slide xml")
+	}
+}
+
+func readZipFile(t *testing.T, zr *zip.Reader, name string) string {
+	t.Helper()
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return string(b)
+	}
+	t.Fatalf("%s not found", name)
+	return ""
+}
+
 func zipHasFile(zr *zip.Reader, name string) bool {
 	for _, f := range zr.File {
 		if f.Name == name {
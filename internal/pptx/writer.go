@@ -0,0 +1,184 @@
+package pptx
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"syl-md2ppt/internal/render"
+)
+
+// 依赖页数的部件，等所有页面写完后在 Close 里生成。
+var finalParts = map[string]bool{
+	"[Content_Types].xml":             true,
+	"ppt/presentation.xml":            true,
+	"ppt/_rels/presentation.xml.rels": true,
+	"docProps/core.xml":               true,
+	"docProps/app.xml":                true,
+}
+
+type zipPart struct {
+	name string
+	data string
+}
+
+// Writer 把 PPT 边生成边写进 zip：NewWriter 写出模板部件，AddSlide 写出一页的 XML、关系、
+// 备注和图片，Close 补上 presentation.xml、关系和内容类型。已写出的内容不留在内存里。
+type Writer struct {
+	zw         *zip.Writer
+	deck       Deck
+	tpl        *template
+	media      *mediaSet
+	notesTheme []byte
+	modified   time.Time
+	slides     int
+	notes      []int
+	closed     bool
+	err        error
+}
+
+// NewWriter 按 deck 的版面、配色和模板开始写一个 PPT，deck.Slides 不用填。
+// Close 不会关闭 w。
+func NewWriter(w io.Writer, deck Deck) (*Writer, error) {
+	applyDeckDefaults(&deck)
+	deck.Slides, deck.Prerendered = nil, nil
+	tpl, err := loadTemplate(deck.TemplatePath, deck.TemplateLayout)
+	if err != nil {
+		return nil, err
+	}
+	pw := &Writer{zw: zip.NewWriter(w), deck: deck, tpl: tpl, notesTheme: tpl.masterTheme(), modified: deck.Modified.UTC()}
+	if deck.Modified.IsZero() {
+		pw.modified = time.Now().UTC()
+	}
+
+	names := make([]string, 0, len(tpl.files))
+	for name := range tpl.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pw.media = newMediaSet(names, pw.writePart)
+	for _, name := range names {
+		if finalParts[name] {
+			continue
+		}
+		if err := pw.writePart(name, tpl.files[name]); err != nil {
+			return nil, err
+		}
+		delete(tpl.files, name)
+	}
+	return pw, nil
+}
+
+// AddSlide 生成并写出下一页。
+func (w *Writer) AddSlide(s render.Slide) error {
+	return w.AddRenderedSlide(s, SlidePart{})
+}
+
+// AddRenderedSlide 写出下一页；part.XML 不为空时直接使用（比如来自缓存），不再重新生成。
+func (w *Writer) AddRenderedSlide(s render.Slide, part SlidePart) error {
+	if w.closed {
+		return fmt.Errorf("PPT 已经写完，不能再加页面")
+	}
+	if w.err != nil {
+		return w.err
+	}
+	w.slides++
+	n := w.slides
+	// rId1 是版式，rId2 留给备注页，图片从 rId3 开始。
+	pics, picRels, err := w.media.slidePictures(s, 3)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	links := newHyperlinks(3 + len(picRels))
+	xml := part.XML
+	if xml != "" {
+		// 按原来的顺序登记链接，关系编号和现成的 XML 对得上。
+		for _, url := range part.Links {
+			links.id(url)
+		}
+	} else {
+		xml = slideXML(s, w.deck, pics, links)
+	}
+	if err := w.writePart(fmt.Sprintf("ppt/slides/slide%d.xml", n), []byte(xml)); err != nil {
+		return err
+	}
+	rels := slideRelsXML(w.tpl.layoutTarget, n, len(s.Notes) > 0, append(picRels, links.rels...))
+	if err := w.writePart(fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", n), []byte(rels)); err != nil {
+		return err
+	}
+	if len(s.Notes) == 0 {
+		return nil
+	}
+	w.notes = append(w.notes, n)
+	// 备注页的 rId1、rId2 是备注母版和幻灯片。
+	noteLinks := newHyperlinks(3)
+	if err := w.writePart(fmt.Sprintf("ppt/notesSlides/notesSlide%d.xml", n), []byte(notesSlideXML(s.Notes, w.deck.Styles, noteLinks))); err != nil {
+		return err
+	}
+	return w.writePart(fmt.Sprintf("ppt/notesSlides/_rels/notesSlide%d.xml.rels", n), []byte(notesSlideRelsXML(n, noteLinks.rels)))
+}
+
+// Close 写出依赖页数的部件并结束 zip。一页都没有时报错。
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	if w.slides == 0 {
+		w.err = fmt.Errorf("没有可写入的页面，PPT 生成不了")
+		return w.err
+	}
+	tpl, deck := w.tpl, w.deck
+	hasNotes := len(w.notes) > 0
+	parts := []zipPart{
+		{"docProps/core.xml", corePropsXML(w.modified)},
+		{"docProps/app.xml", appPropsXML(w.slides, len(w.notes))},
+		{"ppt/presentation.xml", presentationXML(string(tpl.files["ppt/presentation.xml"]), tpl, w.slides, hasNotes, toEMU(deck.SlideWidthIn), toEMU(deck.SlideHeightIn))},
+		{"ppt/_rels/presentation.xml.rels", presentationRelsXML(tpl, w.slides, hasNotes)},
+	}
+	if hasNotes {
+		parts = append(parts, []zipPart{
+			{"ppt/notesMasters/notesMaster1.xml", notesMasterXML()},
+			{"ppt/notesMasters/_rels/notesMaster1.xml.rels", notesMasterRelsXML(tpl.notesTheme)},
+			// 备注母版需要自己的主题部件，直接复用幻灯片主题的内容。
+			{"ppt/theme/" + tpl.notesTheme, string(w.notesTheme)},
+		}...)
+	}
+	parts = append(parts, zipPart{"[Content_Types].xml", w.media.contentTypes(contentTypesXML(string(tpl.files["[Content_Types].xml"]), w.slides, w.notes, tpl.notesTheme))})
+	for _, p := range parts {
+		if err := w.writePart(p.name, []byte(p.data)); err != nil {
+			return err
+		}
+	}
+	if err := w.zw.Close(); err != nil {
+		w.err = fmt.Errorf("写入 PPT 文件收尾失败：%w", err)
+	}
+	return w.err
+}
+
+func (w *Writer) writePart(name string, data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	// zip 的时间从 1980 年开始，更早的按 1980 年记。
+	mod := w.modified
+	if mod.Year() < 1980 {
+		mod = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	fw, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: mod})
+	if err != nil {
+		w.err = fmt.Errorf("写入 PPT 结构失败（%s）：%w", name, err)
+		return w.err
+	}
+	if _, err := fw.Write(data); err != nil {
+		w.err = fmt.Errorf("写入 PPT 内容失败（%s）：%w", name, err)
+		return w.err
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"syl-md2ppt/internal/render"
+)
+
+func TestWriter_StreamsSlides(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Deck{SlideWidthIn: 13.333, SlideHeightIn: 7.5})
+	if err != nil {
+		t.Fatalf("NewWriter returned error: %v", err)
+	}
+	templateBytes := buf.Len()
+	if templateBytes == 0 {
+		t.Fatalf("template parts should be written right away")
+	}
+	for _, text := range []string{"first", "second", "third"} {
+		slide := render.Slide{FontSize: 20, Columns: []render.Column{{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: text}}}}}}}
+		if text == "second" {
+			slide.Notes = []render.Note{{Lang: "EN", Blocks: []render.Block{{Runs: []render.Run{{Text: "say hi"}}}}}}
+		}
+		if err := w.AddSlide(slide); err != nil {
+			t.Fatalf("AddSlide returned error: %v", err)
+		}
+	}
+	if buf.Len() <= templateBytes {
+		t.Fatalf("slides should be streamed before Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := w.AddSlide(render.Slide{}); err == nil {
+		t.Fatalf("adding a slide after Close should fail")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	pres := readZipFile(t, zr, "ppt/presentation.xml")
+	if strings.Count(pres, "<p:sldId ") != 3 || !strings.Contains(pres, "<p:notesMasterIdLst>") {
+		t.Fatalf("presentation should list 3 slides and the notes master: %s", pres)
+	}
+	if !strings.Contains(readZipFile(t, zr, "ppt/slides/slide3.xml"), "third") {
+		t.Fatalf("slides should keep their order")
+	}
+	ct := readZipFile(t, zr, "[Content_Types].xml")
+	if !strings.Contains(ct, "/ppt/slides/slide3.xml") || !strings.Contains(ct, "/ppt/notesSlides/notesSlide2.xml") || strings.Contains(ct, "notesSlide1.xml") {
+		t.Fatalf("content types should be finalized from the streamed slides: %s", ct)
+	}
+	seen := make(map[string]bool)
+	for _, f := range zr.File {
+		if seen[f.Name] {
+			t.Fatalf("%s written twice", f.Name)
+		}
+		seen[f.Name] = true
+	}
+}
+
+func TestWriter_CloseWithoutSlidesFails(t *testing.T) {
+	w, err := NewWriter(&bytes.Buffer{}, Deck{})
+	if err != nil {
+		t.Fatalf("NewWriter returned error: %v", err)
+	}
+	if err := w.Close(); err == nil {
+		t.Fatalf("an empty deck should be rejected")
+	}
+}
@@ -0,0 +1,69 @@
+package render
+
+// 列表每深一级缩进的宽度，以及项目符号悬挂的宽度（单位 em，按正文字号）。
+const (
+	listIndentEm  = 1.5
+	bulletHangEm  = 1.0
+	maxBlockDepth = 8
+)
+
+// FontScale 返回块字号相对正文字号的倍数：标题按级别放大。
+func (b Block) FontScale() float64 {
+	if b.Kind != BlockHeading {
+		return 1
+	}
+	switch b.Level {
+	case 1:
+		return 1.5
+	case 2:
+		return 1.3
+	case 3:
+		return 1.15
+	}
+	return 1
+}
+
+// IndentEm 返回正文左缩进（em）；列表项的符号悬挂在缩进里。
+func (b Block) IndentEm() float64 {
+	depth := float64(b.depth())
+	switch b.Kind {
+	case BlockBullet, BlockNumbered:
+		return depth*listIndentEm + bulletHangEm
+	case BlockParagraph, BlockImage:
+		return depth * listIndentEm
+	}
+	return 0
+}
+
+// HangingEm 返回首行向左悬挂的宽度（em），用来放项目符号。
+func (b Block) HangingEm() float64 {
+	if b.Kind == BlockBullet || b.Kind == BlockNumbered {
+		return bulletHangEm
+	}
+	return 0
+}
+
+// OutlineLevel 返回写入 a:pPr lvl 的层级（0-8）。
+func (b Block) OutlineLevel() int {
+	return b.depth()
+}
+
+func (b Block) depth() int {
+	if b.Depth < 0 {
+		return 0
+	}
+	if b.Depth > maxBlockDepth {
+		return maxBlockDepth
+	}
+	return b.Depth
+}
+
+// blockGeometry 返回块按自身字号折行的宽度（em），以及每行占正文行高的倍数。
+func blockGeometry(block Block, widthEm float64) (float64, float64) {
+	scale := block.FontScale()
+	w := (widthEm - block.IndentEm()) / scale
+	if w < 4 {
+		w = 4
+	}
+	return w, scale
+}
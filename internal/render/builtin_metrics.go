@@ -0,0 +1,105 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
+)
+
+// 内置宽度表：ASCII 32–126，单位 1/1000 em。
+// Arial 与 Helvetica 字宽一致，取自 Helvetica AFM；Calibri 为近似值。
+// 斜体与对应正体字宽相同，直接复用。
+
+type builtinFace struct {
+	widths *[95]uint16
+}
+
+func (f builtinFace) advance(r rune) (float64, bool) {
+	if r < 32 || r > 126 {
+		return 0, false
+	}
+	return float64(f.widths[r-32]) / 1000, true
+}
+
+// 未内置的字体按 Arial 估算，偏宽一些，宁可早换行也不溢出。
+func builtinFaces(family string) [4]glyphSource {
+	regular, bold := &arialWidths, &arialBoldWidths
+	if isCalibri(family) {
+		regular, bold = &calibriWidths, &calibriBoldWidths
+	}
+	return [4]glyphSource{
+		faceRegular:    builtinFace{widths: regular},
+		faceBold:       builtinFace{widths: bold},
+		faceItalic:     builtinFace{widths: regular},
+		faceBoldItalic: builtinFace{widths: bold},
+	}
+}
+
+func isCalibri(family string) bool {
+	switch strings.ToLower(strings.TrimSpace(family)) {
+	case "calibri", "calibri light":
+		return true
+	}
+	return false
+}
+
+func hasBuiltinWidths(family string) bool {
+	switch strings.ToLower(strings.TrimSpace(family)) {
+	case "arial", "helvetica":
+		return true
+	}
+	return isCalibri(family)
+}
+
+// FontWarnings 在 font_family 没有内置宽度表、又没配 font_files.regular 时提醒：字宽按 Arial 估算，
+// 折行和溢出判断可能和实际显示有出入。
+func FontWarnings(cfg *config.Config) []diag.Diagnostic {
+	typo := cfg.Layout.Typography
+	if strings.TrimSpace(typo.FontFiles.Regular) != "" || hasBuiltinWidths(typo.FontFamily) {
+		return nil
+	}
+	return []diag.Diagnostic{{
+		Code:     diag.CodeFontFallback,
+		Severity: diag.SeverityWarning,
+		Message: fmt.Sprintf("字体 %s 没有内置字宽表，暂按 Arial 估算，折行和溢出判断可能不准；"+
+			"可以用 layout.typography.font_files 指定字体文件", typo.FontFamily),
+	}}
+}
+
+var arialWidths = [95]uint16{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var arialBoldWidths = [95]uint16{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+var calibriWidths = [95]uint16{
+	226, 326, 401, 498, 507, 715, 682, 221, 303, 303, 498, 498, 250, 306, 252, 386,
+	507, 507, 507, 507, 507, 507, 507, 507, 507, 507, 268, 268, 498, 498, 498, 463,
+	894, 579, 544, 533, 615, 488, 459, 631, 623, 252, 319, 520, 420, 855, 646, 662,
+	517, 673, 543, 459, 487, 642, 567, 890, 519, 487, 468, 307, 386, 307, 498, 498,
+	291, 479, 525, 423, 525, 498, 305, 471, 525, 229, 239, 455, 229, 799, 525, 527,
+	525, 525, 349, 391, 335, 525, 452, 715, 433, 453, 395, 314, 460, 314, 498,
+}
+
+var calibriBoldWidths = [95]uint16{
+	226, 326, 438, 498, 507, 715, 705, 221, 303, 303, 498, 498, 250, 306, 252, 386,
+	507, 507, 507, 507, 507, 507, 507, 507, 507, 507, 268, 268, 498, 498, 498, 463,
+	899, 606, 561, 529, 630, 488, 459, 637, 631, 267, 331, 547, 423, 874, 659, 676,
+	532, 686, 563, 473, 495, 653, 591, 906, 551, 520, 478, 307, 386, 307, 498, 498,
+	291, 494, 537, 418, 537, 503, 316, 474, 537, 246, 255, 480, 246, 813, 537, 538,
+	537, 537, 355, 399, 347, 537, 473, 745, 459, 474, 397, 314, 460, 314, 498,
+}
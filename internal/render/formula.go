@@ -0,0 +1,58 @@
+package render
+
+import (
+	"syl-md2ppt/internal/diag"
+	"syl-md2ppt/internal/omml"
+)
+
+// NewFormulaRun 返回一个公式 Run，并把公式转成 OMML 存在上面，排版和写出都不用再转。
+func NewFormulaRun(latex string) Run {
+	r := Run{Text: latex, Formula: true}
+	if omath, err := omml.Convert(latex, omml.RunProps); err != nil {
+		r.MathErr = err.Error()
+	} else {
+		r.Math = omath
+	}
+	return r
+}
+
+// withText 换掉 Run 的文字；公式只剩一部分时不再当公式对象写出。
+func (r Run) withText(text string) Run {
+	if text != r.Text {
+		r.Text, r.Math, r.MathErr = text, "", ""
+	}
+	return r
+}
+
+// formulaWarnings 找出转不成公式对象的公式；写出时这些公式按高亮文本显示。
+func formulaWarnings(slides []Slide) []diag.Diagnostic {
+	warnings := make([]diag.Diagnostic, 0)
+	for s, slide := range slides {
+		for c, col := range slide.Columns {
+			for _, block := range col.Blocks {
+				for _, r := range blockRuns(block) {
+					if r.Formula && r.MathErr != "" {
+						w := newWarning(diag.KindFormula, col.Lang, c, "公式 $"+r.Text+"$ 没法转成 PPT 公式（"+r.MathErr+"），按文本显示")
+						w.Slide = s + 1
+						warnings = append(warnings, w)
+					}
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// blockRuns 返回块里的全部文字，表格按单元格依次展开。
+func blockRuns(block Block) []Run {
+	if block.Table == nil {
+		return block.Runs
+	}
+	runs := make([]Run, 0)
+	for _, row := range block.Table.Rows {
+		for _, cell := range row.Cells {
+			runs = append(runs, cell...)
+		}
+	}
+	return runs
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"math"
+
+	"syl-md2ppt/internal/config"
+)
+
+// 文本框上边默认内边距 0.05 英寸。
+const textInsetTopIn = 0.05
+
+// Frame 返回图片、表格块的排版结果，其他块返回 nil。
+func (b Block) Frame() *Frame {
+	switch {
+	case b.Kind == BlockImage && b.Image != nil:
+		return &b.Image.Frame
+	case b.Kind == BlockTable && b.Table != nil:
+		return &b.Table.Frame
+	}
+	return nil
+}
+
+// sizeBlocks 按当前字号和栏宽算出图片、表格的显示大小和占用行数，返回副本。
+// 它们最高不超过一整栏，超出的表格在拆页时按行切开。
+func sizeBlocks(blocks []Block, cfg *config.Config, m *Metrics, font int, widthEm float64) []Block {
+	out := blocks
+	copied := false
+	lineH := lineHeightPt(cfg, font)
+	maxH := float64(maxLines(cfg, font)) * lineH
+	for i, block := range blocks {
+		if block.Frame() == nil {
+			continue
+		}
+		if !copied {
+			out = append([]Block(nil), blocks...)
+			copied = true
+		}
+		availPt := (widthEm - block.IndentEm()) * float64(font)
+		var f *Frame
+		switch block.Kind {
+		case BlockImage:
+			img := *block.Image
+			fitImage(&img, availPt, maxH)
+			out[i].Image, f = &img, &img.Frame
+		case BlockTable:
+			tbl := m.measureTable(*block.Table, availPt, font, lineH)
+			out[i].Table, f = &tbl, &tbl.Frame
+		}
+		f.Lines = int(math.Ceil(f.DisplayH/lineH - 1e-9))
+		if f.Lines < 1 {
+			f.Lines = 1
+		}
+		f.ReservePt = float64(f.Lines) * lineH
+	}
+	return out
+}
+
+// placeFrames 在最终字号和分栏下确定图片、表格在文本框里的位置。
+func placeFrames(slides []Slide, cfg *config.Config, m *Metrics, widths []float64) {
+	for s := range slides {
+		font := slides[s].FontSize
+		lineH := lineHeightPt(cfg, font)
+		perCol := maxLines(cfg, font)
+		for c := range slides[s].Columns {
+			col := &slides[s].Columns[c]
+			numCol := max(1, col.NumCol)
+			width := columnWidthEm(cfg, font, widths[c], numCol)
+			col.Blocks = sizeBlocks(col.Blocks, cfg, m, font, width)
+			subW := width * float64(font) / 72
+			innerGap := cfg.Layout.Columns.Gap * 0.5
+			if innerGap <= 0 {
+				innerGap = 0.08
+			}
+			used := 0
+			for _, block := range col.Blocks {
+				need := m.blockLines(block, width)
+				if f := block.Frame(); f != nil {
+					sub, line := used/perCol, used%perCol
+					// 当前栏剩下的位置放不下时，整体挪到下一栏。
+					if line > 0 && line+need > perCol && sub+1 < numCol {
+						sub, line = sub+1, 0
+						used = sub * perCol
+					}
+					f.TopIn = textInsetTopIn + float64(line)*lineH/72
+					f.LeftIn = textInsetIn + float64(sub)*(subW+innerGap) + block.IndentEm()*float64(font)/72
+				}
+				used += need
+			}
+		}
+	}
+}
+
+func lineHeightPt(cfg *config.Config, font int) float64 {
+	lineSpacing := cfg.Layout.Typography.LineSpacing
+	if lineSpacing <= 0 {
+		lineSpacing = 1.2
+	}
+	return float64(font) * lineSpacing
+}
+
+// withoutFrames 去掉图片和表格；备注页只放文字。
+func withoutFrames(blocks []Block) []Block {
+	out := blocks[:0:0]
+	for _, block := range blocks {
+		if block.Kind != BlockImage && block.Kind != BlockTable {
+			out = append(out, block)
+		}
+	}
+	return out
+}
@@ -0,0 +1,180 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"syl-md2ppt/internal/diag"
+)
+
+var imageLineRe = regexp.MustCompile(`^!\[([^\]]*)\]\(\s*(<[^>]*>|[^\s)]+)(?:\s+"[^"]*")?\s*\)$`)
+
+// parseImageLine 识别独占一行的 ![alt](path)，相对路径以卡片所在目录为准。
+func parseImageLine(body, baseDir string) (*Image, bool) {
+	m := imageLineRe.FindStringSubmatch(strings.TrimSpace(body))
+	if m == nil {
+		return nil, false
+	}
+	p := strings.TrimSuffix(strings.TrimPrefix(m[2], "<"), ">")
+	if !filepath.IsAbs(p) && !strings.Contains(p, "://") && baseDir != "" {
+		p = filepath.Join(baseDir, filepath.FromSlash(p))
+	}
+	return &Image{Path: p, Alt: strings.TrimSpace(m[1])}, true
+}
+
+// ImagePaths 返回卡片里引用的本地图片路径（不管文件在不在），用来判断缓存的排版结果是否还有效。
+func ImagePaths(src Source) []string {
+	baseDir := ""
+	if src.Path != "" {
+		baseDir = filepath.Dir(src.Path)
+	}
+	out := make([]string, 0)
+	for _, line := range strings.Split(src.Raw, "\n") {
+		_, body := splitIndent(strings.TrimRight(line, " \t\r"))
+		if img, ok := parseImageLine(body, baseDir); ok && !strings.Contains(img.Path, "://") {
+			out = append(out, img.Path)
+		}
+	}
+	return out
+}
+
+// loadImages 读取图片格式和原始尺寸；读不了的图片去掉，并给出告警。
+func loadImages(cols []Column) []diag.Diagnostic {
+	warnings := make([]diag.Diagnostic, 0)
+	for c := range cols {
+		blocks := make([]Block, 0, len(cols[c].Blocks))
+		for _, block := range cols[c].Blocks {
+			if block.Kind != BlockImage {
+				blocks = append(blocks, block)
+				continue
+			}
+			img := *block.Image
+			if err := readImageSize(&img); err != nil {
+				warnings = append(warnings, newWarning(diag.KindImage, cols[c].Lang, c, "图片 "+img.Path+" 没法用（"+err.Error()+"），已跳过"))
+				continue
+			}
+			block.Image = &img
+			blocks = append(blocks, block)
+		}
+		cols[c].Blocks = blocks
+	}
+	return warnings
+}
+
+func readImageSize(img *Image) error {
+	if strings.Contains(img.Path, "://") {
+		return fmt.Errorf("不支持网络图片")
+	}
+	data, err := os.ReadFile(img.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("文件不存在")
+		}
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(img.Path), ".svg") {
+		w, h, err := svgSize(data)
+		if err != nil {
+			return err
+		}
+		img.Format, img.WidthPt, img.HeightPt = "svg", w, h
+		return nil
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("只支持 PNG、JPEG 和 SVG")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return fmt.Errorf("图片尺寸为 0")
+	}
+	// 按 96 DPI 换算成磅。
+	img.Format = format
+	img.WidthPt = float64(cfg.Width) * 0.75
+	img.HeightPt = float64(cfg.Height) * 0.75
+	return nil
+}
+
+// svgSize 读取根元素的 width/height，缺失时用 viewBox。
+func svgSize(data []byte) (float64, float64, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, fmt.Errorf("SVG 读不懂")
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "svg" {
+			return 0, 0, fmt.Errorf("SVG 读不懂")
+		}
+		var w, h float64
+		var viewBox []float64
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "width":
+				w = svgLengthPt(attr.Value)
+			case "height":
+				h = svgLengthPt(attr.Value)
+			case "viewBox":
+				for _, f := range strings.FieldsFunc(attr.Value, func(r rune) bool { return r == ',' || r == ' ' }) {
+					v, _ := strconv.ParseFloat(f, 64)
+					viewBox = append(viewBox, v)
+				}
+			}
+		}
+		if len(viewBox) == 4 && viewBox[2] > 0 && viewBox[3] > 0 {
+			switch {
+			case w <= 0 && h <= 0:
+				w, h = viewBox[2]*0.75, viewBox[3]*0.75
+			case w <= 0:
+				w = h * viewBox[2] / viewBox[3]
+			case h <= 0:
+				h = w * viewBox[3] / viewBox[2]
+			}
+		}
+		if w <= 0 || h <= 0 {
+			return 0, 0, fmt.Errorf("SVG 缺少尺寸")
+		}
+		return w, h, nil
+	}
+}
+
+// svgLengthPt 把 SVG 长度换算成磅；百分比等无法确定的长度返回 0。
+func svgLengthPt(v string) float64 {
+	v = strings.TrimSpace(v)
+	units := map[string]float64{"px": 0.75, "pt": 1, "in": 72, "cm": 72 / 2.54, "mm": 72 / 25.4, "pc": 12}
+	factor := 0.75
+	for unit, f := range units {
+		if strings.HasSuffix(v, unit) {
+			v, factor = strings.TrimSuffix(v, unit), f
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n * factor
+}
+
+// fitImage 让图片不超过原始大小和可用宽度，最高不超过 maxH。
+func fitImage(img *Image, availPt, maxH float64) {
+	img.DisplayW = math.Min(img.WidthPt, math.Max(availPt, 1))
+	img.DisplayH = img.DisplayW * img.HeightPt / img.WidthPt
+	if img.DisplayH > maxH {
+		img.DisplayW *= maxH / img.DisplayH
+		img.DisplayH = maxH
+	}
+}
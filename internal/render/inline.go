@@ -0,0 +1,332 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// inlinePiece 是行内解析的中间结果：一段文字、一串强调符号或已经成形的 run。
+type inlinePiece struct {
+	runs  []Run // 文字、代码、公式、链接
+	delim byte  // 强调符号：* _ ~ +，0 表示不是符号
+	count int   // 剩余没配上的符号个数
+	orig  int
+	open  bool
+	close bool
+	dead  bool // 夹在一对强调符号中间、已不能再配对
+	// 匹配上的强调作用到这段上。
+	bold, italic, strike, underline bool
+}
+
+// parseInline 按 CommonMark 的分隔符规则解析行内格式：
+// **粗体**/__粗体__、*斜体*/_斜体_、~~删除线~~、++下划线++、`代码`、公式和 [链接](url)。
+// 反斜杠转义标点；配不上对的符号按原文保留，并在 issues 里说明。
+func parseInline(line string, formulaDelimiter string) ([]Run, []string) {
+	if line == "" {
+		return nil, nil
+	}
+	pieces, issues := tokenizeInline(line, formulaDelimiter)
+	matchDelimiters(pieces)
+
+	runs := make([]Run, 0, len(pieces))
+	add := func(r Run, p inlinePiece) {
+		r.Bold = r.Bold || p.bold
+		r.Italic = r.Italic || p.italic
+		r.Strike = r.Strike || p.strike
+		r.Underline = r.Underline || p.underline
+		if n := len(runs); n > 0 && sameStyle(runs[n-1], r) {
+			runs[n-1].Text += r.Text
+			return
+		}
+		runs = append(runs, r)
+	}
+	for _, p := range pieces {
+		if p.delim == 0 {
+			for _, r := range p.runs {
+				add(r, p)
+			}
+			continue
+		}
+		if p.count == 0 {
+			continue
+		}
+		// 只能结束的 ++ 多半是 C++、i++ 这类写法，不算问题。
+		if p.open || (p.close && p.delim != '+' && p.delim != '~') {
+			issues = append(issues, "“"+strings.Repeat(string(p.delim), p.orig)+"”没有配对，按原文显示")
+		}
+		add(Run{Text: strings.Repeat(string(p.delim), p.count)}, p)
+	}
+	return runs, issues
+}
+
+func sameStyle(a, b Run) bool {
+	return a.Bold == b.Bold && a.Italic == b.Italic && a.Strike == b.Strike && a.Underline == b.Underline &&
+		a.Link == b.Link && !a.Code && !b.Code && !a.Formula && !b.Formula
+}
+
+// tokenizeInline 切出文字、代码、公式、链接和强调符号串。
+func tokenizeInline(line, formulaDelimiter string) ([]inlinePiece, []string) {
+	pieces := make([]inlinePiece, 0)
+	issues := make([]string, 0)
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			pieces = append(pieces, inlinePiece{runs: []Run{{Text: text.String()}}})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(line); {
+		ch := line[i]
+		switch {
+		case ch == '\\' && i+1 < len(line) && isASCIIPunct(line[i+1]):
+			text.WriteByte(line[i+1])
+			i += 2
+			continue
+		case ch == '`':
+			if code, n := parseCodeSpan(line[i:]); n > 0 {
+				flush()
+				pieces = append(pieces, inlinePiece{runs: []Run{{Text: code, Code: true}}})
+				i += n
+				continue
+			}
+			// 没有闭合的反引号按原文保留。
+			ticks := len(line[i:]) - len(strings.TrimLeft(line[i:], "`"))
+			text.WriteString(line[i : i+ticks])
+			i += ticks
+			continue
+		case formulaDelimiter != "" && strings.HasPrefix(line[i:], formulaDelimiter):
+			if formula, n := parseFormula(line, i, formulaDelimiter); n > 0 {
+				flush()
+				pieces = append(pieces, inlinePiece{runs: []Run{NewFormulaRun(formula)}})
+				i += n
+				continue
+			}
+			// $5 这样后面跟数字的多半是价格，不算问题。
+			if opensFormula(line, i, formulaDelimiter) && !startsWithDigit(line[i+len(formulaDelimiter):]) {
+				issues = append(issues, "公式符号“"+formulaDelimiter+"”没有配对，按原文显示（字面意思请写成 \\"+formulaDelimiter+"）")
+			}
+			text.WriteString(formulaDelimiter)
+			i += len(formulaDelimiter)
+			continue
+		case ch == '[':
+			if label, url, n := parseLink(line[i:]); n > 0 {
+				flush()
+				inner, innerIssues := parseInline(label, formulaDelimiter)
+				issues = append(issues, innerIssues...)
+				if len(inner) == 0 {
+					inner = []Run{{Text: url}}
+				}
+				for k := range inner {
+					inner[k].Link = url
+				}
+				pieces = append(pieces, inlinePiece{runs: inner})
+				i += n
+				continue
+			}
+		case ch == '*' || ch == '_' || ch == '~' || ch == '+':
+			n := 1
+			for i+n < len(line) && line[i+n] == ch {
+				n++
+			}
+			// ~~ 和 ++ 只认正好两个。
+			if (ch == '~' || ch == '+') && n != 2 {
+				text.WriteString(line[i : i+n])
+				i += n
+				continue
+			}
+			flush()
+			open, close := flanking(line, i, i+n, ch)
+			pieces = append(pieces, inlinePiece{delim: ch, count: n, orig: n, open: open, close: close})
+			i += n
+			continue
+		}
+		text.WriteByte(ch)
+		i++
+	}
+	flush()
+	return pieces, issues
+}
+
+// flanking 按 CommonMark 的左右侧规则判断符号串能否开始、结束强调；
+// 下划线在单词中间不算强调（snake_case）。
+func flanking(line string, start, end int, ch byte) (bool, bool) {
+	before, after := ' ', ' '
+	if start > 0 {
+		before, _ = utf8.DecodeLastRuneInString(line[:start])
+	}
+	if end < len(line) {
+		after, _ = utf8.DecodeRuneInString(line[end:])
+	}
+	left := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	right := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+	if ch == '_' {
+		return left && (!right || isPunct(before)), right && (!left || isPunct(after))
+	}
+	return left, right
+}
+
+// matchDelimiters 从左到右为每个结束符找最近的开始符，并把样式加到两者之间的内容上。
+func matchDelimiters(pieces []inlinePiece) {
+	for c := range pieces {
+		closer := &pieces[c]
+		if closer.delim == 0 || !closer.close || closer.dead {
+			continue
+		}
+		for closer.count > 0 {
+			o := -1
+			for k := c - 1; k >= 0; k-- {
+				p := &pieces[k]
+				if p.delim != closer.delim || !p.open || p.dead || p.count == 0 {
+					continue
+				}
+				// 规则 3：既能开始又能结束的符号串，长度之和是 3 的倍数时不配对。
+				if (p.close || closer.open) && (p.orig+closer.orig)%3 == 0 && (p.orig%3 != 0 || closer.orig%3 != 0) && (closer.delim == '*' || closer.delim == '_') {
+					continue
+				}
+				o = k
+				break
+			}
+			if o < 0 {
+				break
+			}
+			opener := &pieces[o]
+			n := 1
+			if opener.count >= 2 && closer.count >= 2 {
+				n = 2
+			}
+			for k := o + 1; k < c; k++ {
+				p := &pieces[k]
+				switch {
+				case closer.delim == '~':
+					p.strike = true
+				case closer.delim == '+':
+					p.underline = true
+				case n == 2:
+					p.bold = true
+				default:
+					p.italic = true
+				}
+				// 中间没配上的符号不再参与后面的匹配。
+				if p.delim != 0 {
+					p.dead = true
+				}
+			}
+			opener.count -= n
+			closer.count -= n
+		}
+	}
+}
+
+// parseFormula 找 start 处公式的结束符。单字符的 $ 按 pandoc 的规则：
+// 开始符后面不能是空白，结束符前面不能是空白、后面不能紧跟数字，这样 $5 和 $10 不会被当成公式。
+func parseFormula(line string, start int, delim string) (string, int) {
+	if !opensFormula(line, start, delim) {
+		return "", 0
+	}
+	from := start + len(delim)
+	for i := from; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(line[i:], delim) || i == from {
+			continue
+		}
+		if len(delim) == 1 {
+			if unicode.IsSpace(rune(line[i-1])) {
+				continue
+			}
+			if startsWithDigit(line[i+1:]) {
+				continue
+			}
+		}
+		return line[from:i], i + len(delim) - start
+	}
+	return "", 0
+}
+
+func opensFormula(line string, start int, delim string) bool {
+	next := start + len(delim)
+	if next >= len(line) {
+		return false
+	}
+	return len(delim) > 1 || !unicode.IsSpace(rune(line[next]))
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+func isASCIIPunct(b byte) bool {
+	return b < utf8.RuneSelf && unicode.IsPunct(rune(b)) || strings.IndexByte("$+<=>^`|~", b) >= 0
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// parseCodeSpan 识别 `code`：开闭反引号个数相同，内容按原样保留。
+func parseCodeSpan(s string) (string, int) {
+	ticks := len(s) - len(strings.TrimLeft(s, "`"))
+	fence := s[:ticks]
+	for i := ticks; i < len(s); {
+		j := strings.Index(s[i:], fence)
+		if j < 0 {
+			return "", 0
+		}
+		start := i + j
+		end := start + ticks
+		if end < len(s) && s[end] == '`' {
+			// 反引号更长，不是闭合符。
+			for end < len(s) && s[end] == '`' {
+				end++
+			}
+			i = end
+			continue
+		}
+		code := s[ticks:start]
+		if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+			code = code[1 : len(code)-1]
+		}
+		return code, end
+	}
+	return "", 0
+}
+
+// parseLink 识别 [text](url "title")，返回链接文字、地址和消耗的字节数。
+func parseLink(s string) (string, string, int) {
+	depth := 0
+	closeText := -1
+	for i := 0; i < len(s) && closeText < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeText = i
+			}
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", 0
+	}
+	end := strings.IndexByte(s[closeText+2:], ')')
+	if end < 0 {
+		return "", "", 0
+	}
+	end += closeText + 2
+	dest := strings.TrimSpace(s[closeText+2 : end])
+	if k := strings.IndexAny(dest, " \t"); k >= 0 {
+		dest = dest[:k] // 去掉 "title"
+	}
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+	if dest == "" {
+		return "", "", 0
+	}
+	return s[1:closeText], dest, end + 1
+}
@@ -3,22 +3,93 @@ package render
 import (
 	"fmt"
 	"math"
+	"path/filepath"
 	"strings"
-	"unicode/utf8"
 
 	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
 )
 
-func BuildSlide(enRaw, cnRaw string, cfg *config.Config) (Slide, []Warning) {
+func BuildSlide(sources []Source, cfg *config.Config) ([]Slide, []diag.Diagnostic) {
 	opts := ParseOptions{
 		FormulaDelimiter: cfg.Styles.InlineFormula.Delimiter,
 		StarPrefix:       cfg.Styles.Markers.Star.Prefix,
 		DotPrefix:        cfg.Styles.Markers.Dot.Prefix,
 		WarnPrefix:       cfg.Styles.Markers.Warn.Prefix,
 	}
-	enBlocks := ParseMarkdown(enRaw, opts)
-	cnBlocks := ParseMarkdown(cnRaw, opts)
+	bodies := make([]Source, len(sources))
+	notes := make([]Note, 0)
+	for i, src := range sources {
+		body, note := SplitNotes(src.Raw, cfg.Notes.Separators)
+		bodies[i] = src
+		bodies[i].Raw = body
+		if !wantNotes(cfg, src.Lang) {
+			continue
+		}
+		if blocks := withoutFrames(ParseMarkdown(note, opts)); len(blocks) > 0 {
+			notes = append(notes, Note{Lang: src.Lang, Tag: src.Tag, Blocks: blocks})
+		}
+	}
+
+	slides, warnings := layoutSlides(bodies, cfg, opts)
+	warnings = append(warnings, formulaWarnings(slides)...)
+	if len(notes) > 0 {
+		// 续页沿用同一份备注，方便讲到哪一页都能看到。
+		for i := range slides {
+			slides[i].Notes = notes
+		}
+	}
+	return slides, warnings
+}
 
+// newWarning 生成第 column 栏（语言 lang）的排版告警。
+func newWarning(kind, lang string, column int, message string) diag.Diagnostic {
+	return diag.Diagnostic{
+		Code:     diag.LangCode(kind, lang),
+		Severity: diag.SeverityWarning,
+		Message:  message,
+		Column:   column,
+	}
+}
+
+func wantNotes(cfg *config.Config, lang string) bool {
+	want := cfg.Notes.Lang
+	return want == "" || strings.EqualFold(want, config.NotesAllLanguages) || strings.EqualFold(want, lang)
+}
+
+func layoutSlides(sources []Source, cfg *config.Config, opts ParseOptions) ([]Slide, []diag.Diagnostic) {
+	ratios := columnRatios(cfg, len(sources))
+	cols := make([]Column, len(sources))
+	widths := make([]float64, len(sources))
+	warnings := make([]diag.Diagnostic, 0)
+	for i, src := range sources {
+		srcOpts := opts
+		if src.Path != "" {
+			srcOpts.BaseDir = filepath.Dir(src.Path)
+		}
+		blocks, issues := parseMarkdown(src.Raw, srcOpts)
+		for _, issue := range issues {
+			warnings = append(warnings, newWarning(diag.KindLint, src.Lang, i, issue))
+		}
+		cols[i] = Column{
+			Lang:   src.Lang,
+			Tag:    src.Tag,
+			Ratio:  ratios[i],
+			NumCol: 1,
+			Blocks: blocks,
+		}
+		widths[i] = columnWidthIn(cfg, ratios[i], len(sources))
+	}
+	m := metricsFor(cfg)
+
+	warnings = append(warnings, loadImages(cols)...)
+	slides, fitWarnings := fitSlides(cols, widths, cfg, m)
+	placeFrames(slides, cfg, m, widths)
+	return slides, append(warnings, fitWarnings...)
+}
+
+// fitSlides 选字号和分栏，放不下时按 overflow 配置截断、保留或拆页。
+func fitSlides(cols []Column, widths []float64, cfg *config.Config, m *Metrics) ([]Slide, []diag.Diagnostic) {
 	font := cfg.Layout.Typography.BaseSize
 	if font <= 0 {
 		font = 20
@@ -28,79 +99,124 @@ func BuildSlide(enRaw, cnRaw string, cfg *config.Config) (Slide, []Warning) {
 		minFont = 12
 	}
 
-	enNumCol := 1
-	cnNumCol := 1
+	allFit := func() bool {
+		for i := range cols {
+			if !fits(cols[i].Blocks, cfg, m, font, widths[i], cols[i].NumCol) {
+				return false
+			}
+		}
+		return true
+	}
 	for font > minFont {
-		if fits(enBlocks, cfg, font, true, enNumCol) && fits(cnBlocks, cfg, font, false, cnNumCol) {
+		if allFit() {
 			break
 		}
 		font--
 	}
 
-	enOverflow := overflowLines(enBlocks, cfg, font, true, 1)
-	cnOverflow := overflowLines(cnBlocks, cfg, font, false, 1)
-	// 两侧独立判断：英文最多加一栏，中文最多加一栏。
-	if enOverflow > 0 {
-		enNumCol = 2
-	}
-	if cnOverflow > 0 {
-		cnNumCol = 2
+	// 各栏独立判断：每种语言最多加一栏。表格没法跨栏排，有表格的栏不分栏。
+	for i := range cols {
+		if !hasTable(cols[i].Blocks) && overflowLines(cols[i].Blocks, cfg, m, font, widths[i], 1) > 0 {
+			cols[i].NumCol = 2
+		}
 	}
 
-	warnings := make([]Warning, 0)
-	if !fits(enBlocks, cfg, font, true, enNumCol) {
-		var truncated bool
-		enBlocks, truncated = truncateToFit(enBlocks, cfg, font, true, enNumCol)
-		if truncated {
-			warnings = append(warnings, Warning{Code: "truncate_en", Message: "英文内容有点多，部分截断"})
+	fitted := make([]bool, len(cols))
+	for i := range cols {
+		fitted[i] = fits(cols[i].Blocks, cfg, m, font, widths[i], cols[i].NumCol)
+	}
+	if !allFit() {
+		switch cfg.Layout.Overflow {
+		case config.OverflowSplit:
+			return splitSlides(cols, widths, cfg, m, font), nil
+		case config.OverflowShrink:
+			// 不截断：最小字号放不下时继续缩小直到放下，并提醒字号低于最小字号。
+			for font > 1 && !allFit() {
+				font--
+			}
+			warnings := make([]diag.Diagnostic, 0, len(cols))
+			for i, ok := range fitted {
+				if ok {
+					continue
+				}
+				msg := fmt.Sprintf("%s 内容有点多，字号缩到 %dpt 才放下，低于最小字号 %dpt", cols[i].Lang, font, minFont)
+				if !fits(cols[i].Blocks, cfg, m, font, widths[i], cols[i].NumCol) {
+					msg = cols[i].Lang + " 内容有点多，超出页面"
+				}
+				warnings = append(warnings, newWarning(diag.KindOverflow, cols[i].Lang, i, msg))
+			}
+			return []Slide{newSlide(cols, font, false)}, warnings
 		}
 	}
-	if !fits(cnBlocks, cfg, font, false, cnNumCol) {
+
+	warnings := make([]diag.Diagnostic, 0)
+	for i, ok := range fitted {
+		if ok {
+			continue
+		}
 		var truncated bool
-		cnBlocks, truncated = truncateToFit(cnBlocks, cfg, font, false, cnNumCol)
+		cols[i].Blocks, truncated = truncateToFit(cols[i].Blocks, cfg, m, font, widths[i], cols[i].NumCol)
 		if truncated {
-			warnings = append(warnings, Warning{Code: "truncate_cn", Message: "中文内容有点多，部分截断"})
+			warnings = append(warnings, newWarning(diag.KindTruncate, cols[i].Lang, i, cols[i].Lang+" 内容有点多，部分截断"))
 		}
 	}
 
-	slide := Slide{
+	return []Slide{newSlide(cols, font, len(warnings) > 0)}, warnings
+}
+
+func newSlide(cols []Column, font int, badge bool) Slide {
+	return Slide{
 		FontSize:           font,
-		ENNumCol:           enNumCol,
-		CNNumCol:           cnNumCol,
-		HasTruncationBadge: len(warnings) > 0,
-		Columns: []Column{
-			{Lang: "EN", Blocks: enBlocks},
-			{Lang: "CN", Blocks: cnBlocks},
-		},
-	}
-	return slide, warnings
+		HasTruncationBadge: badge,
+		Page:               1,
+		PageCount:          1,
+		Columns:            cols,
+	}
 }
 
-func fits(blocks []Block, cfg *config.Config, font int, isLeft bool, numCol int) bool {
+// columnRatios 返回各语言栏占可用宽度的比例；两栏时沿用 left_ratio，其余平分。
+func columnRatios(cfg *config.Config, n int) []float64 {
+	out := make([]float64, n)
+	if n == 0 {
+		return out
+	}
+	if n == 2 {
+		left := cfg.Layout.Columns.LeftRatio
+		if left <= 0 || left >= 1 {
+			left = 0.5
+		}
+		out[0], out[1] = left, 1-left
+		return out
+	}
+	for i := range out {
+		out[i] = 1 / float64(n)
+	}
+	return out
+}
+
+func fits(blocks []Block, cfg *config.Config, m *Metrics, font int, widthIn float64, numCol int) bool {
 	max := maxLines(cfg, font) * max(1, numCol)
-	used := usedLines(blocks, cfg, font, isLeft, numCol)
+	used := usedLines(blocks, cfg, m, font, widthIn, numCol)
 	return used <= max
 }
 
-func truncateToFit(blocks []Block, cfg *config.Config, font int, isLeft bool, numCol int) ([]Block, bool) {
+func truncateToFit(blocks []Block, cfg *config.Config, m *Metrics, font int, widthIn float64, numCol int) ([]Block, bool) {
 	max := maxLines(cfg, font) * max(1, numCol)
 	if max <= 0 {
 		return blocks, false
 	}
-	chars := charsPerLine(cfg, font, isLeft, numCol)
+	width := columnWidthEm(cfg, font, widthIn, numCol)
+	blocks = sizeBlocks(blocks, cfg, m, font, width)
 	out := make([]Block, 0, len(blocks))
 	used := 0
 	truncated := false
 
 	for _, block := range blocks {
-		text := flattenRuns(block.Runs)
-		if text == "" {
+		need := m.blockLines(block, width)
+		if need == 0 {
+			out = append(out, block)
 			continue
 		}
-		need := int(math.Ceil(float64(utf8.RuneCountInString(text)) / float64(chars)))
-		if need < 1 {
-			need = 1
-		}
 
 		if used+need <= max {
 			out = append(out, block)
@@ -114,13 +230,26 @@ func truncateToFit(blocks []Block, cfg *config.Config, font int, isLeft bool, nu
 			break
 		}
 
-		allowed := remaining * chars
-		clipped := clipRunText(block.Runs, allowed)
+		if block.Kind == BlockTable {
+			// 表格按行截断，至少留下表头和一行数据。
+			if part := splitTable(block, remaining)[0]; len(part.Table.Rows) > 1 && part.Table.Lines <= remaining {
+				out = append(out, part)
+			}
+			truncated = true
+			break
+		}
+
+		w, scale := blockGeometry(block, width)
+		var clipped []Run
+		if lines := int(float64(remaining) / scale); lines > 0 {
+			clipped, _ = m.clipToLines(block.Runs, w, lines, " ...")
+		}
 		if len(clipped) > 0 {
 			last := clipped[len(clipped)-1]
-			last.Text = strings.TrimSpace(last.Text) + " ..."
-			clipped[len(clipped)-1] = last
-			out = append(out, Block{Marker: block.Marker, Runs: clipped})
+			clipped[len(clipped)-1] = last.withText(strings.TrimSpace(last.Text) + " ...")
+			part := block
+			part.Runs = clipped
+			out = append(out, part)
 		}
 		truncated = true
 		break
@@ -154,47 +283,56 @@ func maxLines(cfg *config.Config, font int) int {
 	return max
 }
 
-func usedLines(blocks []Block, cfg *config.Config, font int, isLeft bool, numCol int) int {
-	chars := charsPerLine(cfg, font, isLeft, numCol)
+func usedLines(blocks []Block, cfg *config.Config, m *Metrics, font int, widthIn float64, numCol int) int {
+	width := columnWidthEm(cfg, font, widthIn, numCol)
+	blocks = sizeBlocks(blocks, cfg, m, font, width)
 	total := 0
 	for _, block := range blocks {
-		text := flattenRuns(block.Runs)
-		if text == "" {
-			continue
-		}
-		lines := int(math.Ceil(float64(utf8.RuneCountInString(text)) / float64(chars)))
-		if lines < 1 {
-			lines = 1
-		}
-		total += lines
+		total += m.blockLines(block, width)
 	}
 	return total
 }
 
-func charsPerLine(cfg *config.Config, font int, isLeft bool, numCol int) int {
+func (m *Metrics) blockLines(block Block, widthEm float64) int {
+	if f := block.Frame(); f != nil {
+		return f.Lines
+	}
+	if block.Kind == BlockImage || block.Kind == BlockTable {
+		return 0
+	}
+	w, scale := blockGeometry(block, widthEm)
+	n := m.lineCount(block.Runs, w)
+	if scale == 1 {
+		return n
+	}
+	return int(math.Ceil(float64(n)*scale - 1e-9))
+}
+
+// 文本框默认左右各留 0.1 英寸内边距。
+const textInsetIn = 0.1
+
+// columnWidthIn 返回某一语言栏的宽度（英寸），已扣除文本框内边距。
+func columnWidthIn(cfg *config.Config, ratio float64, n int) float64 {
 	slideWidth := cfg.Layout.Slide.Width
 	if slideWidth <= 0 {
 		slideWidth = 13.333
 	}
 	padding := cfg.Layout.Columns.Padding
 	gap := cfg.Layout.Columns.Gap
-	leftRatio := cfg.Layout.Columns.LeftRatio
-	if leftRatio <= 0 || leftRatio >= 1 {
-		leftRatio = 0.5
-	}
-	usable := slideWidth - (2 * padding) - gap
+	usable := slideWidth - (2 * padding) - gap*float64(max(0, n-1))
 	if usable <= 0 {
 		usable = 10
 	}
-	colWidth := usable * leftRatio
-	if !isLeft {
-		colWidth = usable * (1 - leftRatio)
-	}
+	return usable*ratio - 2*textInsetIn
+}
+
+func columnWidthEm(cfg *config.Config, font int, widthIn float64, numCol int) float64 {
+	colWidth := widthIn
 	if numCol < 1 {
 		numCol = 1
 	}
 	if numCol > 1 {
-		innerGap := gap * 0.5
+		innerGap := cfg.Layout.Columns.Gap * 0.5
 		if innerGap <= 0 {
 			innerGap = 0.08
 		}
@@ -203,11 +341,11 @@ func charsPerLine(cfg *config.Config, font int, isLeft bool, numCol int) int {
 	if colWidth <= 0 {
 		colWidth = 2.5
 	}
-	chars := int(math.Floor((colWidth * 144.0) / float64(font)))
-	if chars < 12 {
-		chars = 12
+	width := colWidth * 72.0 / float64(font)
+	if width < 6 {
+		width = 6
 	}
-	return chars
+	return width
 }
 
 func flattenRuns(runs []Run) string {
@@ -234,9 +372,7 @@ func clipRunText(runs []Run, maxRunes int) []Run {
 			remaining -= len(runes)
 			continue
 		}
-		clipped := run
-		clipped.Text = string(runes[:remaining])
-		out = append(out, clipped)
+		out = append(out, run.withText(string(runes[:remaining])))
 		remaining = 0
 	}
 	if len(out) == 0 {
@@ -245,8 +381,8 @@ func clipRunText(runs []Run, maxRunes int) []Run {
 	return out
 }
 
-func overflowLines(blocks []Block, cfg *config.Config, font int, isLeft bool, numCol int) int {
-	used := usedLines(blocks, cfg, font, isLeft, numCol)
+func overflowLines(blocks []Block, cfg *config.Config, m *Metrics, font int, widthIn float64, numCol int) int {
+	used := usedLines(blocks, cfg, m, font, widthIn, numCol)
 	capacity := maxLines(cfg, font) * max(1, numCol)
 	if used <= capacity {
 		return 0
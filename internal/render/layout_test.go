@@ -1,18 +1,28 @@
 package render
 
 import (
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/omml"
 )
 
 func TestBuildSlideTwoColumns(t *testing.T) {
 	cfg := minimalConfig()
-	slide, warnings := BuildSlide("hello EN", "你好 CN", cfg)
+	slides, warnings := BuildSlide(bilingual("hello EN", "你好 CN"), cfg)
 	if len(warnings) != 0 {
 		t.Fatalf("expected no warnings, got %d", len(warnings))
 	}
+	if len(slides) != 1 {
+		t.Fatalf("expected 1 slide, got %d", len(slides))
+	}
+	slide := slides[0]
 	if len(slide.Columns) != 2 {
 		t.Fatalf("expected 2 columns, got %d", len(slide.Columns))
 	}
@@ -25,8 +35,9 @@ func TestBuildSlideShrinkAndTruncate(t *testing.T) {
 	cfg := minimalConfig()
 	cfg.Layout.Typography.BaseSize = 24
 	cfg.Layout.Typography.MinSize = 10
-	heavy := strings.Repeat("long text ", 400)
-	slide, warnings := BuildSlide(heavy, heavy, cfg)
+	heavy := strings.Repeat("long text ", 800)
+	slides, warnings := BuildSlide(bilingual(heavy, heavy), cfg)
+	slide := slides[0]
 	if slide.FontSize > cfg.Layout.Typography.BaseSize {
 		t.Fatalf("font size should not exceed base")
 	}
@@ -45,12 +56,13 @@ func TestBuildSlide_CNCanAddOneExtraColumnIndependently(t *testing.T) {
 	cfg := minimalConfig()
 	en := "short english"
 	cn := strings.Repeat("这是比较长的中文内容。", 520)
-	slide, _ := BuildSlide(en, cn, cfg)
-	if slide.ENNumCol != 1 {
-		t.Fatalf("expected EN to keep single column, got EN=%d", slide.ENNumCol)
+	slides, _ := BuildSlide(bilingual(en, cn), cfg)
+	slide := slides[0]
+	if slide.Columns[0].NumCol != 1 {
+		t.Fatalf("expected EN to keep single column, got EN=%d", slide.Columns[0].NumCol)
 	}
-	if slide.CNNumCol != 2 {
-		t.Fatalf("expected CN side to receive one extra column, got EN=%d CN=%d", slide.ENNumCol, slide.CNNumCol)
+	if slide.Columns[1].NumCol != 2 {
+		t.Fatalf("expected CN side to receive one extra column, got EN=%d CN=%d", slide.Columns[0].NumCol, slide.Columns[1].NumCol)
 	}
 }
 
@@ -58,9 +70,222 @@ func TestBuildSlide_ENAndCNCanEachAddOneExtraColumn(t *testing.T) {
 	cfg := minimalConfig()
 	en := strings.Repeat("long english content. ", 520)
 	cn := strings.Repeat("这是比较长的中文内容。", 520)
-	slide, _ := BuildSlide(en, cn, cfg)
-	if slide.ENNumCol != 2 || slide.CNNumCol != 2 {
-		t.Fatalf("expected EN=2 and CN=2, got EN=%d CN=%d", slide.ENNumCol, slide.CNNumCol)
+	slides, _ := BuildSlide(bilingual(en, cn), cfg)
+	slide := slides[0]
+	if slide.Columns[0].NumCol != 2 || slide.Columns[1].NumCol != 2 {
+		t.Fatalf("expected EN=2 and CN=2, got EN=%d CN=%d", slide.Columns[0].NumCol, slide.Columns[1].NumCol)
+	}
+}
+
+func TestBuildSlide_SplitKeepsAllContent(t *testing.T) {
+	cfg := minimalConfig()
+	cfg.Layout.Overflow = config.OverflowSplit
+	var en, cn []string
+	for i := 0; i < 200; i++ {
+		en = append(en, "english paragraph with a handful of words")
+		cn = append(cn, "这是一段中文段落，用来测试续页")
+	}
+	slides, warnings := BuildSlide(bilingual(strings.Join(en, "\n"), strings.Join(cn, "\n")), cfg)
+	if len(warnings) != 0 {
+		t.Fatalf("split mode should not warn, got %#v", warnings)
+	}
+	if len(slides) < 2 {
+		t.Fatalf("expected continuation slides, got %d", len(slides))
+	}
+	enTotal, cnTotal := 0, 0
+	for i, s := range slides {
+		if s.Page != i+1 || s.PageCount != len(slides) {
+			t.Fatalf("unexpected page numbering on slide %d: %d/%d", i, s.Page, s.PageCount)
+		}
+		if s.HasTruncationBadge {
+			t.Fatalf("split slide should not carry truncation badge")
+		}
+		if len(s.Columns[0].Blocks) != len(s.Columns[1].Blocks) {
+			t.Fatalf("expected aligned EN/CN blocks on slide %d, got EN=%d CN=%d", i, len(s.Columns[0].Blocks), len(s.Columns[1].Blocks))
+		}
+		enTotal += len(s.Columns[0].Blocks)
+		cnTotal += len(s.Columns[1].Blocks)
+	}
+	if enTotal != 200 || cnTotal != 200 {
+		t.Fatalf("expected all blocks kept, got EN=%d CN=%d", enTotal, cnTotal)
+	}
+}
+
+func TestBuildSlide_SplitOversizedBlock(t *testing.T) {
+	cfg := minimalConfig()
+	cfg.Layout.Overflow = config.OverflowSplit
+	en := strings.Repeat("word ", 3000)
+	slides, _ := BuildSlide(bilingual(en, "短"), cfg)
+	if len(slides) < 2 {
+		t.Fatalf("expected oversized block to be split, got %d slide(s)", len(slides))
+	}
+	var b strings.Builder
+	for _, s := range slides {
+		for _, block := range s.Columns[0].Blocks {
+			b.WriteString(flattenRuns(block.Runs))
+		}
+	}
+	if b.String() != strings.TrimRight(en, " ") {
+		t.Fatalf("split pages lost text: got %d bytes, want %d", b.Len(), len(strings.TrimRight(en, " ")))
+	}
+	if len(slides[0].Columns[1].Blocks) != 1 {
+		t.Fatalf("expected CN content on first page")
+	}
+}
+
+func TestBuildSlide_SplitKeepsImages(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "chart.png"), 400, 200)
+	cfg := minimalConfig()
+	cfg.Layout.Overflow = config.OverflowSplit
+	var en []string
+	for i := 0; i < 60; i++ {
+		en = append(en, "english paragraph with a handful of words")
+		if i%20 == 10 {
+			en = append(en, "![chart](chart.png)")
+		}
+	}
+	sources := bilingual(strings.Join(en, "\n"), "短")
+	sources[0].Path = filepath.Join(dir, "card.md")
+	slides, _ := BuildSlide(sources, cfg)
+	images := 0
+	for _, s := range slides {
+		for _, block := range s.Columns[0].Blocks {
+			if block.Kind == BlockImage {
+				images++
+			}
+		}
+	}
+	if len(slides) < 2 || images != 3 {
+		t.Fatalf("split pages should keep every image, got %d image(s) on %d slide(s)", images, len(slides))
+	}
+
+	// 没量出大小的块不占行，也要留在页面上。
+	blocks := []Block{{Runs: []Run{{Text: "a"}}}, {Kind: BlockImage}, {Runs: []Run{{Text: "b"}}}}
+	var kept int
+	for _, page := range paginate(metricsFor(cfg), blocks, 1, 40) {
+		kept += len(page)
+	}
+	if kept != len(blocks) {
+		t.Fatalf("paginate dropped zero-line blocks: kept %d of %d", kept, len(blocks))
+	}
+}
+
+func TestBuildSlide_ShrinkKeepsContentAndWarns(t *testing.T) {
+	cfg := minimalConfig()
+	cfg.Layout.Overflow = config.OverflowShrink
+	heavy := strings.Repeat("long text ", 2000)
+	slides, warnings := BuildSlide(bilingual(heavy, "短"), cfg)
+	if len(slides) != 1 {
+		t.Fatalf("expected a single slide, got %d", len(slides))
+	}
+	if slides[0].HasTruncationBadge {
+		t.Fatalf("shrink mode should not add truncation badge")
+	}
+	if len(warnings) != 1 || warnings[0].Code != "overflow_en" || !strings.Contains(warnings[0].Message, "低于最小字号") {
+		t.Fatalf("expected overflow_en warning, got %#v", warnings)
+	}
+	font := slides[0].FontSize
+	if font >= cfg.Layout.Typography.MinSize {
+		t.Fatalf("shrink mode should go below min_size when needed, got %dpt", font)
+	}
+	col := slides[0].Columns[0]
+	if !fits(col.Blocks, cfg, metricsFor(cfg), font, columnWidthIn(cfg, 0.5, 2), col.NumCol) {
+		t.Fatalf("shrunk text should fit on the slide at %dpt", font)
+	}
+	if got := flattenRuns(slides[0].Columns[0].Blocks[0].Runs); got != strings.TrimRight(heavy, " ") {
+		t.Fatalf("shrink mode should keep all text")
+	}
+}
+
+func TestBuildSlide_ThreeLanguageColumns(t *testing.T) {
+	cfg := minimalConfig()
+	slides, warnings := BuildSlide([]Source{
+		{Lang: "EN", Tag: "en-US", Raw: "hello"},
+		{Lang: "JA", Tag: "ja-JP", Raw: "こんにちは"},
+		{Lang: "KO", Tag: "ko-KR", Raw: "안녕하세요"},
+	}, cfg)
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got %#v", warnings)
+	}
+	cols := slides[0].Columns
+	if len(cols) != 3 {
+		t.Fatalf("expected 3 columns, got %d", len(cols))
+	}
+	for i, c := range cols {
+		if c.Ratio < 0.33 || c.Ratio > 0.34 {
+			t.Fatalf("column %d should get a third of the width, got %v", i, c.Ratio)
+		}
+		if c.NumCol != 1 {
+			t.Fatalf("column %d should have one text column, got %d", i, c.NumCol)
+		}
+	}
+	if cols[2].Tag != "ko-KR" {
+		t.Fatalf("expected language tag to be carried, got %q", cols[2].Tag)
+	}
+}
+
+func TestBuildSlide_TruncateWarningPointsAtColumn(t *testing.T) {
+	cfg := minimalConfig()
+	heavy := strings.Repeat("很长的中文内容", 2000)
+	_, warnings := BuildSlide([]Source{
+		{Lang: "EN", Raw: "short"},
+		{Lang: "JA", Raw: "short"},
+		{Lang: "CN", Raw: heavy},
+	}, cfg)
+	if len(warnings) != 1 || warnings[0].Code != "truncate_cn" || warnings[0].Column != 2 {
+		t.Fatalf("expected truncate_cn on column 2, got %#v", warnings)
+	}
+}
+
+func TestBuildSlide_MovesNotesOutOfBody(t *testing.T) {
+	cfg := minimalConfig()
+	cfg.Notes.Separators = []string{"<!-- notes -->", "Note:"}
+	cfg.Notes.Lang = config.NotesAllLanguages
+	slides, _ := BuildSlide(bilingual("Body EN\n<!-- notes -->\nSay hello", "正文\n## Note:\n打个招呼"), cfg)
+
+	slide := slides[0]
+	for _, col := range slide.Columns {
+		for _, b := range col.Blocks {
+			for _, r := range b.Runs {
+				if strings.Contains(r.Text, "hello") || strings.Contains(r.Text, "招呼") || strings.Contains(r.Text, "Note") {
+					t.Fatalf("notes should be removed from slide body, got %q", r.Text)
+				}
+			}
+		}
+	}
+	if len(slide.Notes) != 2 || slide.Notes[0].Lang != "EN" || slide.Notes[1].Lang != "CN" {
+		t.Fatalf("expected EN then CN notes, got %#v", slide.Notes)
+	}
+	if slide.Notes[1].Blocks[0].Runs[0].Text != "打个招呼" {
+		t.Fatalf("unexpected CN note: %#v", slide.Notes[1])
+	}
+
+	cfg.Notes.Lang = "cn"
+	slides, _ = BuildSlide(bilingual("Body\n<!-- notes -->\nSay hello", "正文\n<!-- NOTES -->\n打个招呼"), cfg)
+	if len(slides[0].Notes) != 1 || slides[0].Notes[0].Lang != "CN" {
+		t.Fatalf("expected only CN notes, got %#v", slides[0].Notes)
+	}
+}
+
+func TestBlockLinesAccountForHeadingAndIndent(t *testing.T) {
+	m := metricsFor(minimalConfig())
+	text := []Run{{Text: strings.Repeat("word ", 30)}}
+	plain := m.blockLines(Block{Runs: text}, 40)
+	heading := m.blockLines(Block{Kind: BlockHeading, Level: 1, Runs: text}, 40)
+	nested := m.blockLines(Block{Kind: BlockBullet, Depth: 3, Runs: text}, 40)
+	if heading <= plain {
+		t.Fatalf("heading should take more lines than body text: heading=%d plain=%d", heading, plain)
+	}
+	if nested <= plain {
+		t.Fatalf("indented item should take more lines than body text: nested=%d plain=%d", nested, plain)
+	}
+}
+
+func bilingual(en, cn string) []Source {
+	return []Source{
+		{Lang: "EN", Tag: "en-US", Raw: en},
+		{Lang: "CN", Tag: "zh-CN", Raw: cn},
 	}
 }
 
@@ -81,3 +306,124 @@ func minimalConfig() *config.Config {
 	cfg.Styles.InlineFormula.Delimiter = "$"
 	return cfg
 }
+
+func TestBuildSlide_WarnsOnUnconvertibleFormula(t *testing.T) {
+	cfg := minimalConfig()
+	_, warnings := BuildSlide(bilingual("ok $\\sqrt{x}$", "坏 $\\foo{x}$"), cfg)
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %#v", warnings)
+	}
+	w := warnings[0]
+	if w.Code != "formula_cn" || w.Column != 1 || w.Slide != 1 || !strings.Contains(w.Message, `$\foo{x}$`) {
+		t.Fatalf("unexpected warning: %#v", w)
+	}
+}
+
+func TestNewFormulaRun_CarriesConversion(t *testing.T) {
+	ok := NewFormulaRun(`\sqrt{x}`)
+	if !strings.Contains(ok.Math, "<m:rad>") || !strings.Contains(ok.Math, omml.RunProps) || ok.MathErr != "" {
+		t.Fatalf("expected converted OMML with the rPr placeholder, got %#v", ok)
+	}
+	if bad := NewFormulaRun(`\foo{x}`); bad.Math != "" || bad.MathErr == "" {
+		t.Fatalf("expected the conversion error on the run, got %#v", bad)
+	}
+	if part := ok.withText(`\sqr`); part.Math != "" || !part.Formula {
+		t.Fatalf("a clipped formula should be written as text, got %#v", part)
+	}
+	if same := ok.withText(ok.Text); same.Math != ok.Math {
+		t.Fatal("keeping the text should keep the conversion")
+	}
+}
+
+func TestBuildSlide_ReservesSpaceForImages(t *testing.T) {
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "wide.png"), 800, 400)
+	cfg := minimalConfig()
+	sources := []Source{
+		{Lang: "EN", Tag: "en-US", Raw: "above\n![wide](wide.png)\nbelow\n![gone](missing.png)", Path: filepath.Join(dir, "card.md")},
+		{Lang: "CN", Tag: "zh-CN", Raw: "文字"},
+	}
+	slides, warnings := BuildSlide(sources, cfg)
+	if len(warnings) != 1 || warnings[0].Code != "image_en" || warnings[0].Column != 0 || !strings.Contains(warnings[0].Message, "missing.png") {
+		t.Fatalf("expected a warning for the missing image, got %#v", warnings)
+	}
+	blocks := slides[0].Columns[0].Blocks
+	if len(blocks) != 3 || blocks[1].Kind != BlockImage {
+		t.Fatalf("missing image should be dropped, got %#v", blocks)
+	}
+	img := blocks[1].Image
+	font := slides[0].FontSize
+	lineH := float64(font) * cfg.Layout.Typography.LineSpacing
+	widthPt := columnWidthIn(cfg, 0.5, 2) * 72
+	if img.DisplayW > widthPt+0.01 || img.DisplayW > 600.01 || img.DisplayH*2 != img.DisplayW {
+		t.Fatalf("image should keep aspect ratio within the column, got %vx%v (column %v)", img.DisplayW, img.DisplayH, widthPt)
+	}
+	if img.Lines != int(math.Ceil(img.DisplayH/lineH)) {
+		t.Fatalf("image lines %d do not cover height %v", img.Lines, img.DisplayH)
+	}
+	if want := textInsetTopIn + lineH/72; math.Abs(img.TopIn-want) > 1e-9 {
+		t.Fatalf("image should sit below the first line, want top %v got %v", want, img.TopIn)
+	}
+}
+
+func writePNG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildSlide_TableHeightAndSplit(t *testing.T) {
+	cfg := minimalConfig()
+	cfg.Layout.Overflow = config.OverflowSplit
+	rows := "| A | B |\n|---|---|\n"
+	for i := 0; i < 40; i++ {
+		rows += "| cell | value |\n"
+	}
+	slides, _ := BuildSlide(bilingual(rows, "中文"), cfg)
+	if len(slides) < 2 {
+		t.Fatalf("a table taller than the page should be split, got %d slides", len(slides))
+	}
+	total := 0
+	for _, s := range slides {
+		tbl := s.Columns[0].Blocks[0].Table
+		if tbl == nil || flattenRuns(tbl.Rows[0].Cells[0]) != "A" {
+			t.Fatalf("every page should repeat the header row")
+		}
+		total += len(tbl.Rows) - 1
+		lineH := float64(s.FontSize) * cfg.Layout.Typography.LineSpacing
+		if tbl.Lines > maxLines(cfg, s.FontSize) || float64(tbl.Lines)*lineH < tbl.DisplayH-1e-6 {
+			t.Fatalf("table lines %d do not match height %v", tbl.Lines, tbl.DisplayH)
+		}
+	}
+	if total != 40 {
+		t.Fatalf("expected all 40 rows across pages, got %d", total)
+	}
+}
+
+// BenchmarkBuildSlide 是一张需要反复试字号的卡片的排版耗时；parallel 是多个 goroutine 同时排版，
+// 和 serial 对比可以看出并行的收益。
+func BenchmarkBuildSlide(b *testing.B) {
+	cfg := minimalConfig()
+	src := bilingual(
+		strings.Repeat("**Term** explains a *long* sentence with `code` and more words.\n", 40),
+		strings.Repeat("这是一段比较长的中文说明，带 **重点** 和更多文字。\n", 40),
+	)
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			BuildSlide(src, cfg)
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				BuildSlide(src, cfg)
+			}
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"syl-md2ppt/internal/config"
+)
+
+// 字体里查不到的字符按以下宽度估算（单位 em）。
+const (
+	fallbackWideEm   = 1.0
+	fallbackNarrowEm = 0.55
+)
+
+type faceKind int
+
+const (
+	faceRegular faceKind = iota
+	faceBold
+	faceItalic
+	faceBoldItalic
+)
+
+type glyphSource interface {
+	advance(r rune) (float64, bool)
+}
+
+// Metrics 按粗体/斜体分别测量字宽。
+type Metrics struct {
+	faces [4]glyphSource
+}
+
+var metricsCache sync.Map
+
+// LoadMetrics 读取配置的字体文件；没有配置时使用 font_family 对应的内置宽度表。
+func LoadMetrics(cfg *config.Config) (*Metrics, error) {
+	typo := cfg.Layout.Typography
+	files := typo.FontFiles
+	key := strings.Join([]string{strings.ToLower(typo.FontFamily), files.Regular, files.Bold, files.Italic, files.BoldItalic}, "|")
+	if cached, ok := metricsCache.Load(key); ok {
+		return cached.(*Metrics), nil
+	}
+
+	m := &Metrics{faces: builtinFaces(typo.FontFamily)}
+	paths := [4]string{files.Regular, files.Bold, files.Italic, files.BoldItalic}
+	var loaded [4]glyphSource
+	for i, path := range paths {
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取字体文件失败（%s）：%w", path, err)
+		}
+		face, err := parseSFNT(raw)
+		if err != nil {
+			return nil, fmt.Errorf("字体文件读不懂（%s）：%w", path, err)
+		}
+		loaded[i] = face
+	}
+	// 只配了常规字体时，其余字形沿用已配置的文件，避免和内置表混用。
+	if loaded[faceRegular] != nil {
+		if loaded[faceBold] == nil {
+			loaded[faceBold] = loaded[faceRegular]
+		}
+		if loaded[faceItalic] == nil {
+			loaded[faceItalic] = loaded[faceRegular]
+		}
+		if loaded[faceBoldItalic] == nil {
+			loaded[faceBoldItalic] = loaded[faceBold]
+		}
+	}
+	for i, face := range loaded {
+		if face != nil {
+			m.faces[i] = face
+		}
+	}
+
+	actual, _ := metricsCache.LoadOrStore(key, m)
+	return actual.(*Metrics), nil
+}
+
+func metricsFor(cfg *config.Config) *Metrics {
+	m, err := LoadMetrics(cfg)
+	if err != nil {
+		return &Metrics{faces: builtinFaces(cfg.Layout.Typography.FontFamily)}
+	}
+	return m
+}
+
+func (m *Metrics) runeWidth(r rune, bold, italic bool) float64 {
+	kind := faceRegular
+	switch {
+	case bold && italic:
+		kind = faceBoldItalic
+	case bold:
+		kind = faceBold
+	case italic:
+		kind = faceItalic
+	}
+	if w, ok := m.faces[kind].advance(r); ok {
+		return w
+	}
+	if isWideRune(r) {
+		return fallbackWideEm
+	}
+	return fallbackNarrowEm
+}
+
+func isWideRune(r rune) bool {
+	switch {
+	case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r), unicode.Is(unicode.Hangul, r):
+		return true
+	case r >= 0x3000 && r <= 0x303F: // CJK 标点
+		return true
+	case r >= 0xFF01 && r <= 0xFF60, r >= 0xFFE0 && r <= 0xFFE6: // 全角字符
+		return true
+	case r >= 0x2E80 && r <= 0x2FDF, r >= 0x3190 && r <= 0x31FF:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package render
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"syl-md2ppt/internal/config"
+	"syl-md2ppt/internal/diag"
+)
+
+type fixedFace struct {
+	width float64
+}
+
+func (f fixedFace) advance(r rune) (float64, bool) {
+	if r > 126 {
+		return 0, false
+	}
+	return f.width, true
+}
+
+func fixedMetrics(regular, bold float64) *Metrics {
+	return &Metrics{faces: [4]glyphSource{
+		faceRegular:    fixedFace{regular},
+		faceBold:       fixedFace{bold},
+		faceItalic:     fixedFace{regular},
+		faceBoldItalic: fixedFace{bold},
+	}}
+}
+
+func TestLineCountWrapsLatinByWords(t *testing.T) {
+	m := fixedMetrics(0.5, 1)
+	// 每个单词 1em，空格 0.5em：一行放得下两个单词。
+	if got := m.lineCount([]Run{{Text: "ab cd ef"}}, 2.6); got != 2 {
+		t.Fatalf("expected 2 lines, got %d", got)
+	}
+	if got := m.lineCount([]Run{{Text: "ab cd ef"}}, 4); got != 1 {
+		t.Fatalf("expected 1 line, got %d", got)
+	}
+	// 超长单词只能硬断。
+	if got := m.lineCount([]Run{{Text: "abcdefghij"}}, 2); got != 3 {
+		t.Fatalf("expected long word to break into 3 lines, got %d", got)
+	}
+}
+
+func TestLineCountWrapsCJKByCharacters(t *testing.T) {
+	m := fixedMetrics(0.5, 1)
+	if got := m.lineCount([]Run{{Text: "中文测试换行"}}, 2.5); got != 3 {
+		t.Fatalf("expected 3 lines for CJK text, got %d", got)
+	}
+}
+
+func TestLineCountUsesBoldFace(t *testing.T) {
+	m := fixedMetrics(0.5, 1)
+	plain := m.lineCount([]Run{{Text: "abcd abcd"}}, 4.5)
+	bold := m.lineCount([]Run{{Text: "abcd abcd", Bold: true}}, 4.5)
+	if plain != 1 || bold != 2 {
+		t.Fatalf("expected plain=1 bold=2, got plain=%d bold=%d", plain, bold)
+	}
+}
+
+func TestBuiltinMetricsDifferByFamily(t *testing.T) {
+	cfg := minimalConfig()
+	calibri := metricsFor(cfg)
+	cfg = minimalConfig()
+	cfg.Layout.Typography.FontFamily = "Arial"
+	arial := metricsFor(cfg)
+	if calibri.runeWidth('a', false, false) >= arial.runeWidth('a', false, false) {
+		t.Fatalf("expected Calibri to be narrower than Arial")
+	}
+	if arial.runeWidth('a', true, false) <= arial.runeWidth('i', true, false) {
+		t.Fatalf("expected proportional widths")
+	}
+	if w := arial.runeWidth('中', false, false); w != fallbackWideEm {
+		t.Fatalf("expected CJK fallback width, got %v", w)
+	}
+}
+
+func TestLoadMetricsFromFontFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.ttf")
+	if err := os.WriteFile(path, buildTestFont(), 0o644); err != nil {
+		t.Fatalf("write font: %v", err)
+	}
+	cfg := minimalConfig()
+	cfg.Layout.Typography.FontFiles = config.FontFilesConfig{Regular: path}
+	m, err := LoadMetrics(cfg)
+	if err != nil {
+		t.Fatalf("LoadMetrics returned error: %v", err)
+	}
+	if w := m.runeWidth('A', false, false); math.Abs(w-1) > 1e-9 {
+		t.Fatalf("expected A=1em, got %v", w)
+	}
+	if w := m.runeWidth('B', true, false); math.Abs(w-0.25) > 1e-9 {
+		t.Fatalf("expected bold to reuse regular font file, got %v", w)
+	}
+	if w := m.runeWidth('z', false, false); w != fallbackNarrowEm {
+		t.Fatalf("expected fallback width for missing glyph, got %v", w)
+	}
+}
+
+func TestLoadMetricsRejectsBadFontFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.ttf")
+	if err := os.WriteFile(path, []byte("not a font"), 0o644); err != nil {
+		t.Fatalf("write font: %v", err)
+	}
+	cfg := minimalConfig()
+	cfg.Layout.Typography.FontFiles = config.FontFilesConfig{Regular: path}
+	if _, err := LoadMetrics(cfg); err == nil {
+		t.Fatalf("expected error for invalid font file")
+	}
+}
+
+// buildTestFont 生成一个只有 A、B 两个字形的最小 TrueType 字体（unitsPerEm=1000）。
+func buildTestFont() []byte {
+	be := binary.BigEndian
+	head := make([]byte, 54)
+	be.PutUint16(head[18:], 1000)
+	hhea := make([]byte, 36)
+	be.PutUint16(hhea[34:], 3)
+	hmtx := make([]byte, 12)
+	for i, adv := range []uint16{500, 1000, 250} {
+		be.PutUint16(hmtx[i*4:], adv)
+	}
+
+	// cmap format 4：A-B 映射到字形 1-2，外加结束段 0xFFFF。
+	sub := make([]byte, 14+2*2*4+2)
+	be.PutUint16(sub[0:], 4)
+	be.PutUint16(sub[2:], uint16(len(sub)))
+	be.PutUint16(sub[6:], 4)
+	be.PutUint16(sub[14:], 'B')
+	be.PutUint16(sub[16:], 0xFFFF)
+	be.PutUint16(sub[20:], 'A')
+	be.PutUint16(sub[22:], 0xFFFF)
+	be.PutUint16(sub[24:], uint16(1-'A'+0x10000))
+	be.PutUint16(sub[26:], 1)
+	cmap := make([]byte, 12)
+	be.PutUint16(cmap[2:], 1)
+	be.PutUint16(cmap[4:], 3)
+	be.PutUint16(cmap[6:], 1)
+	be.PutUint32(cmap[8:], 12)
+	cmap = append(cmap, sub...)
+
+	tables := []struct {
+		tag  string
+		data []byte
+	}{{"cmap", cmap}, {"head", head}, {"hhea", hhea}, {"hmtx", hmtx}}
+	out := make([]byte, 12+16*len(tables))
+	be.PutUint32(out[0:], 0x00010000)
+	be.PutUint16(out[4:], uint16(len(tables)))
+	for i, tbl := range tables {
+		rec := 12 + 16*i
+		copy(out[rec:], tbl.tag)
+		be.PutUint32(out[rec+8:], uint32(len(out)))
+		be.PutUint32(out[rec+12:], uint32(len(tbl.data)))
+		out = append(out, tbl.data...)
+	}
+	return out
+}
+
+func TestLineCountMeasuresVisibleLinkText(t *testing.T) {
+	m := &Metrics{faces: builtinFaces("Calibri")}
+	plain := ParseMarkdown("read the guide now", ParseOptions{})[0].Runs
+	linked := ParseMarkdown("read [the guide](https://example.com/a/very/long/path/that/should/not/count) now", ParseOptions{})[0].Runs
+	for _, width := range []float64{6, 8, 12} {
+		if a, b := m.lineCount(plain, width), m.lineCount(linked, width); a != b {
+			t.Fatalf("width %v: link syntax should not be measured, got %d vs %d", width, a, b)
+		}
+	}
+}
+
+func TestFontWarnings(t *testing.T) {
+	cfg := minimalConfig()
+	if ws := FontWarnings(cfg); len(ws) != 0 {
+		t.Fatalf("Calibri has built-in widths, got %+v", ws)
+	}
+	cfg.Layout.Typography.FontFamily = "Source Han Sans"
+	ws := FontWarnings(cfg)
+	if len(ws) != 1 || ws[0].Code != diag.CodeFontFallback || !strings.Contains(ws[0].Message, "Source Han Sans") {
+		t.Fatalf("expected a font_fallback warning, got %+v", ws)
+	}
+	cfg.Layout.Typography.FontFiles.Regular = "fonts/SourceHanSans.otf"
+	if ws := FontWarnings(cfg); len(ws) != 0 {
+		t.Fatalf("a configured font file should silence the warning, got %+v", ws)
+	}
+}
@@ -0,0 +1,33 @@
+package render
+
+import "strings"
+
+// SplitNotes 在第一处分隔行把卡片拆成正文和演讲者备注。
+// 分隔行需独占一行，忽略大小写；写成标题（如 "## Note:"）也能识别。
+func SplitNotes(raw string, separators []string) (string, string) {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if isNotesSeparator(line, separators) {
+			return strings.Join(lines[:i], "\n"), strings.Join(lines[i+1:], "\n")
+		}
+	}
+	return raw, ""
+}
+
+func isNotesSeparator(line string, separators []string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+	heading := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+	for _, sep := range separators {
+		sep = strings.TrimSpace(sep)
+		if sep == "" {
+			continue
+		}
+		if strings.EqualFold(trimmed, sep) || strings.EqualFold(heading, sep) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,26 +1,180 @@
 package render
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 )
 
 func ParseMarkdown(raw string, opts ParseOptions) []Block {
+	blocks, _ := parseMarkdown(raw, opts)
+	return blocks
+}
+
+// parseMarkdown 同 ParseMarkdown，另外返回行内格式的问题（如没配对的 **），每条带行号。
+func parseMarkdown(raw string, opts ParseOptions) ([]Block, []string) {
 	opts = normalizeOptions(opts)
 	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
 	blocks := make([]Block, 0, len(lines))
-	for _, line := range lines {
-		trimmed := strings.TrimRight(line, " \t")
+	issues := make([]string, 0)
+	var lists listStack
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimRight(lines[i], " \t")
 		if strings.TrimSpace(trimmed) == "" {
 			continue
 		}
-		marker, text := parseMarker(trimmed, opts)
-		runs := parseInline(text, opts.FormulaDelimiter)
+		indent, body := splitIndent(trimmed)
+		if img, ok := parseImageLine(body, opts.BaseDir); ok {
+			blocks = append(blocks, Block{Kind: BlockImage, Depth: lists.continuation(indent), Image: img})
+			continue
+		}
+		if tbl, n, tblIssues := parseTable(lines[i:], i+1, opts); n > 0 {
+			issues = append(issues, tblIssues...)
+			blocks = append(blocks, Block{Kind: BlockTable, Depth: lists.continuation(indent), Table: tbl})
+			i += n - 1
+			continue
+		}
+		block, text := parseBlockLine(body)
+		switch block.Kind {
+		case BlockBullet, BlockNumbered:
+			extra := block.Depth
+			block.Depth, block.Number = lists.push(indent, block.Kind, block.Number)
+			block.Depth += extra
+		case BlockHeading:
+			lists = nil
+		default:
+			block.Depth = lists.continuation(indent)
+		}
+
+		marker, text := parseMarker(text, opts)
+		runs, lineIssues := parseInline(text, opts.FormulaDelimiter)
+		issues = append(issues, atLine(i+1, lineIssues)...)
 		if len(runs) == 0 {
 			runs = []Run{{Text: text}}
 		}
-		blocks = append(blocks, Block{Marker: marker, Runs: runs})
+		if block.Kind == BlockHeading {
+			for i := range runs {
+				runs[i].Bold = true
+			}
+		}
+		block.Marker = marker
+		block.Runs = runs
+		blocks = append(blocks, block)
 	}
-	return blocks
+	return blocks, issues
+}
+
+func atLine(no int, issues []string) []string {
+	out := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		out = append(out, fmt.Sprintf("第 %d 行：%s", no, issue))
+	}
+	return out
+}
+
+type listLevel struct {
+	indent int
+	kind   BlockKind
+	next   int
+}
+
+// listStack 记录当前打开的各级列表，按缩进判断嵌套深度。
+type listStack []listLevel
+
+// push 登记一个列表项，返回它的深度和序号。
+func (s *listStack) push(indent int, kind BlockKind, number int) (int, int) {
+	for len(*s) > 0 && indent < (*s)[len(*s)-1].indent {
+		*s = (*s)[:len(*s)-1]
+	}
+	if len(*s) == 0 || indent > (*s)[len(*s)-1].indent {
+		*s = append(*s, listLevel{indent: indent, kind: kind, next: number})
+	}
+	top := &(*s)[len(*s)-1]
+	if top.kind != kind {
+		top.kind, top.next = kind, number
+	}
+	if kind != BlockNumbered {
+		return len(*s) - 1, 0
+	}
+	n := top.next
+	top.next++
+	return len(*s) - 1, n
+}
+
+// continuation 返回缩进段落挂在第几层列表下；顶格段落结束所有列表。
+func (s *listStack) continuation(indent int) int {
+	if indent == 0 {
+		*s = nil
+		return 0
+	}
+	depth := 0
+	for _, l := range *s {
+		if l.indent < indent {
+			depth++
+		}
+	}
+	return depth
+}
+
+// splitIndent 返回行首缩进宽度（制表符按 4 个空格算）和去掉缩进后的内容。
+func splitIndent(line string) (int, string) {
+	width := 0
+	for i, ch := range line {
+		switch ch {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width, line[i:]
+		}
+	}
+	return width, ""
+}
+
+// parseBlockLine 识别标题和列表前缀，返回块类型和剩余文本。
+func parseBlockLine(body string) (Block, string) {
+	if strings.HasPrefix(body, "#") {
+		level := len(body) - len(strings.TrimLeft(body, "#"))
+		if level <= 6 {
+			return Block{Kind: BlockHeading, Level: level}, strings.TrimSpace(body[level:])
+		}
+	}
+
+	kind, number, rest, ok := listPrefix(body)
+	if !ok {
+		return Block{Kind: BlockParagraph}, strings.TrimSpace(body)
+	}
+	block := Block{Kind: kind, Number: number}
+	// "- - x" 这种连写的符号按更深一级处理。
+	for {
+		k, n, r, more := listPrefix(rest)
+		if !more {
+			break
+		}
+		block.Kind, block.Number, rest = k, n, r
+		block.Depth++
+	}
+	return block, strings.TrimSpace(rest)
+}
+
+func listPrefix(s string) (BlockKind, int, string, bool) {
+	s = strings.TrimLeft(s, " \t")
+	if len(s) >= 2 && (s[0] == '-' || s[0] == '*' || s[0] == '+') && (s[1] == ' ' || s[1] == '\t') {
+		return BlockBullet, 0, s[2:], true
+	}
+	digits := 0
+	for digits < len(s) && digits < 9 && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits+1 >= len(s) {
+		return BlockParagraph, 0, s, false
+	}
+	if (s[digits] == '.' || s[digits] == ')') && (s[digits+1] == ' ' || s[digits+1] == '\t') {
+		n, _ := strconv.Atoi(s[:digits])
+		return BlockNumbered, n, s[digits+2:], true
+	}
+	return BlockParagraph, 0, s, false
 }
 
 func normalizeOptions(opts ParseOptions) ParseOptions {
@@ -41,7 +195,6 @@ func normalizeOptions(opts ParseOptions) ParseOptions {
 
 func parseMarker(line string, opts ParseOptions) (MarkerType, string) {
 	work := strings.TrimSpace(line)
-	work = stripListPrefix(work)
 
 	if strings.HasPrefix(work, opts.StarPrefix) {
 		return MarkerStar, strings.TrimSpace(strings.TrimPrefix(work, opts.StarPrefix))
@@ -52,70 +205,5 @@ func parseMarker(line string, opts ParseOptions) (MarkerType, string) {
 	if strings.HasPrefix(work, opts.WarnPrefix) {
 		return MarkerWarn, strings.TrimSpace(strings.TrimPrefix(work, opts.WarnPrefix))
 	}
-
-	if strings.HasPrefix(work, "#") {
-		return MarkerNormal, strings.TrimSpace(strings.TrimLeft(work, "#"))
-	}
 	return MarkerNormal, work
 }
-
-func stripListPrefix(line string) string {
-	work := strings.TrimSpace(line)
-	for {
-		if strings.HasPrefix(work, "* ") || strings.HasPrefix(work, "- ") || strings.HasPrefix(work, "+ ") {
-			work = strings.TrimSpace(work[2:])
-			continue
-		}
-		return work
-	}
-}
-
-func parseInline(line string, formulaDelimiter string) []Run {
-	if line == "" {
-		return nil
-	}
-	var runs []Run
-	var b strings.Builder
-
-	bold := false
-	italic := false
-	formula := false
-
-	flush := func() {
-		if b.Len() == 0 {
-			return
-		}
-		runs = append(runs, Run{
-			Text:    b.String(),
-			Bold:    bold,
-			Italic:  italic,
-			Formula: formula,
-		})
-		b.Reset()
-	}
-
-	for i := 0; i < len(line); {
-		if strings.HasPrefix(line[i:], "**") {
-			flush()
-			bold = !bold
-			i += 2
-			continue
-		}
-		if strings.HasPrefix(line[i:], formulaDelimiter) {
-			flush()
-			formula = !formula
-			i += len(formulaDelimiter)
-			continue
-		}
-		if line[i] == '*' {
-			flush()
-			italic = !italic
-			i++
-			continue
-		}
-		b.WriteByte(line[i])
-		i++
-	}
-	flush()
-	return runs
-}
@@ -1,6 +1,10 @@
 package render
 
-import "testing"
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestParseInlineStyles(t *testing.T) {
 	blocks := ParseMarkdown("plain **bold** *it* $x+y$", ParseOptions{})
@@ -51,3 +55,204 @@ func TestParseMarkerLine(t *testing.T) {
 		t.Fatalf("expected MarkerWarn, got %v", blocks[2].Marker)
 	}
 }
+
+func TestParseOutlineStructure(t *testing.T) {
+	raw := "# Title\n## Sub\nintro\n- one\n  - nested\n    1. deep\n    2. deeper\n- two\n3. three\n4) four\n- - double\n- ★ hero"
+	blocks := ParseMarkdown(raw, ParseOptions{})
+	want := []struct {
+		kind   BlockKind
+		level  int
+		depth  int
+		number int
+		text   string
+	}{
+		{BlockHeading, 1, 0, 0, "Title"},
+		{BlockHeading, 2, 0, 0, "Sub"},
+		{BlockParagraph, 0, 0, 0, "intro"},
+		{BlockBullet, 0, 0, 0, "one"},
+		{BlockBullet, 0, 1, 0, "nested"},
+		{BlockNumbered, 0, 2, 1, "deep"},
+		{BlockNumbered, 0, 2, 2, "deeper"},
+		{BlockBullet, 0, 0, 0, "two"},
+		{BlockNumbered, 0, 0, 3, "three"},
+		{BlockNumbered, 0, 0, 4, "four"},
+		{BlockBullet, 0, 1, 0, "double"},
+		{BlockBullet, 0, 0, 0, "hero"},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("expected %d blocks, got %d: %#v", len(want), len(blocks), blocks)
+	}
+	for i, w := range want {
+		b := blocks[i]
+		if b.Kind != w.kind || b.Level != w.level || b.Depth != w.depth || b.Number != w.number || flattenRuns(b.Runs) != w.text {
+			t.Fatalf("block %d: want %+v, got kind=%v level=%d depth=%d number=%d text=%q", i, w, b.Kind, b.Level, b.Depth, b.Number, flattenRuns(b.Runs))
+		}
+	}
+	if !blocks[0].Runs[0].Bold {
+		t.Fatalf("heading runs should be bold")
+	}
+	if blocks[len(blocks)-1].Marker != MarkerStar {
+		t.Fatalf("marker after bullet should still be detected")
+	}
+}
+
+func TestParseIndentedParagraphContinuesList(t *testing.T) {
+	blocks := ParseMarkdown("- item\n  more text\nback to top", ParseOptions{})
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(blocks))
+	}
+	if blocks[1].Kind != BlockParagraph || blocks[1].Depth != 1 {
+		t.Fatalf("indented paragraph should sit under the item, got %#v", blocks[1])
+	}
+	if blocks[2].Depth != 0 {
+		t.Fatalf("top-level paragraph should reset depth, got %d", blocks[2].Depth)
+	}
+}
+
+func TestParseImageLine(t *testing.T) {
+	blocks := ParseMarkdown("![Chart](img/chart.png)\n![](</abs/a b.svg> \"title\")\nsee ![x](y.png) inline", ParseOptions{BaseDir: "/cards/EN"})
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(blocks))
+	}
+	if blocks[0].Kind != BlockImage || blocks[0].Image.Path != filepath.Join("/cards/EN", "img", "chart.png") || blocks[0].Image.Alt != "Chart" {
+		t.Fatalf("relative image should resolve against card dir, got %#v", blocks[0].Image)
+	}
+	if blocks[1].Kind != BlockImage || blocks[1].Image.Path != "/abs/a b.svg" {
+		t.Fatalf("absolute image path should be kept, got %#v", blocks[1].Image)
+	}
+	if blocks[2].Kind != BlockParagraph {
+		t.Fatalf("inline image syntax should stay text, got %v", blocks[2].Kind)
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	raw := "Compare:\n| Name | **Score** | Note |\n|:-----|:----:|---:|\n| a | $x^2$ | *ok* |\n| b \\| c | 2 |\nafter"
+	blocks := ParseMarkdown(raw, ParseOptions{})
+	if len(blocks) != 3 || blocks[1].Kind != BlockTable {
+		t.Fatalf("expected paragraph, table, paragraph; got %#v", blocks)
+	}
+	tbl := blocks[1].Table
+	if len(tbl.Rows) != 3 || len(tbl.Rows[2].Cells) != 3 {
+		t.Fatalf("expected 3 rows padded to 3 cells, got %#v", tbl.Rows)
+	}
+	if got := []string{tbl.Align[0], tbl.Align[1], tbl.Align[2]}; got[0] != "left" || got[1] != "center" || got[2] != "right" {
+		t.Fatalf("unexpected alignment %v", got)
+	}
+	if !tbl.Rows[0].Cells[0][0].Bold {
+		t.Fatalf("header cells should be bold")
+	}
+	if r := tbl.Rows[1].Cells[1][0]; !r.Formula || r.Text != "x^2" {
+		t.Fatalf("formula in cell not parsed: %#v", r)
+	}
+	if r := tbl.Rows[1].Cells[2][0]; !r.Italic {
+		t.Fatalf("italic in cell not parsed: %#v", r)
+	}
+	if got := flattenRuns(tbl.Rows[2].Cells[0]); got != "b | c" {
+		t.Fatalf("escaped pipe should stay in the cell, got %q", got)
+	}
+	if ParseMarkdown("a | b\nplain", ParseOptions{})[0].Kind != BlockParagraph {
+		t.Fatalf("a pipe without a delimiter row is not a table")
+	}
+}
+
+func TestParseLinksAndCode(t *testing.T) {
+	blocks := ParseMarkdown("see [the **docs**](https://example.com/a_b \"Docs\") and `x * y` or ``a ` b``", ParseOptions{})
+	runs := blocks[0].Runs
+	var link, boldLink, code, tick *Run
+	for i := range runs {
+		r := &runs[i]
+		switch {
+		case r.Link != "" && r.Bold:
+			boldLink = r
+		case r.Link != "":
+			link = r
+		case r.Code && r.Text == "x * y":
+			code = r
+		case r.Code && r.Text == "a ` b":
+			tick = r
+		}
+	}
+	if link == nil || link.Text != "the " || link.Link != "https://example.com/a_b" {
+		t.Fatalf("link text should keep only visible text, got %#v", runs)
+	}
+	if boldLink == nil || boldLink.Text != "docs" {
+		t.Fatalf("formatting inside link text should be kept, got %#v", runs)
+	}
+	if code == nil || code.Italic || tick == nil {
+		t.Fatalf("code spans should be literal, got %#v", runs)
+	}
+	if got := flattenRuns(runs); got != "see the docs and x * y or a ` b" {
+		t.Fatalf("unexpected visible text %q", got)
+	}
+}
+
+func TestParseInlineDelimiterRules(t *testing.T) {
+	cases := []struct {
+		in     string
+		text   string
+		styled string
+	}{
+		{"5 * 3 = 15", "5 * 3 = 15", ""},
+		{"costs $5 and $10 now", "costs $5 and $10 now", ""},
+		{`\*not italic\* and \$x\$`, "*not italic* and $x$", ""},
+		{"call snake_case_name here", "call snake_case_name here", ""},
+		{"__bold__ and _it_", "bold and it", "bold"},
+		{"***both***", "both", "both"},
+	}
+	for _, c := range cases {
+		runs, issues := parseInline(c.in, "$")
+		if got := flattenRuns(runs); got != c.text {
+			t.Fatalf("%q: visible text %q, want %q", c.in, got, c.text)
+		}
+		for _, r := range runs {
+			if r.Formula || (c.styled == "" && (r.Bold || r.Italic)) {
+				t.Fatalf("%q: unexpected styling %#v", c.in, runs)
+			}
+			if r.Text == c.styled && !r.Bold {
+				t.Fatalf("%q: %q should be bold, got %#v", c.in, c.styled, runs)
+			}
+		}
+		if len(issues) != 0 {
+			t.Fatalf("%q: unexpected issues %v", c.in, issues)
+		}
+	}
+
+	runs, _ := parseInline("***both***", "$")
+	if len(runs) != 1 || !runs[0].Italic {
+		t.Fatalf("triple delimiters should be bold italic, got %#v", runs)
+	}
+}
+
+func TestParseStrikeAndUnderline(t *testing.T) {
+	runs, issues := parseInline("~~old~~ ++new++ a+b c++", "$")
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	var strike, under bool
+	for _, r := range runs {
+		strike = strike || (r.Strike && r.Text == "old")
+		under = under || (r.Underline && r.Text == "new")
+	}
+	if !strike || !under {
+		t.Fatalf("strike/underline not detected: %#v", runs)
+	}
+	if got := flattenRuns(runs); got != "old new a+b c++" {
+		t.Fatalf("unexpected visible text %q", got)
+	}
+}
+
+func TestParseUnbalancedDelimitersAreReported(t *testing.T) {
+	blocks, issues := parseMarkdown("first\n**bold and *it* rest", ParseOptions{})
+	runs := blocks[1].Runs
+	if got := flattenRuns(runs); got != "**bold and it rest" {
+		t.Fatalf("unmatched ** should stay literal, got %q", got)
+	}
+	for _, r := range runs {
+		if r.Bold || (r.Italic && r.Text != "it") {
+			t.Fatalf("unmatched ** should not change styling, got %#v", runs)
+		}
+	}
+	if len(issues) != 1 || !strings.HasPrefix(issues[0], "第 2 行：") || !strings.Contains(issues[0], "**") {
+		t.Fatalf("expected one lint issue on line 2, got %v", issues)
+	}
+}
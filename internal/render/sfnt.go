@@ -0,0 +1,208 @@
+package render
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// sfntFace 只解析测量需要的几张表：head、hhea、hmtx、cmap。
+type sfntFace struct {
+	unitsPerEm int
+	advances   []uint16
+	segments   []cmapSegment
+}
+
+type cmapSegment struct {
+	start      rune
+	end        rune
+	glyphStart int
+	delta      int
+	// format 4 的 idRangeOffset 段，按字形数组取值。
+	glyphs []uint16
+}
+
+func parseSFNT(data []byte) (*sfntFace, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("字体文件太短")
+	}
+	switch binary.BigEndian.Uint32(data) {
+	case 0x00010000, 0x4F54544F, 0x74727565: // TrueType、OTTO、true
+	default:
+		return nil, fmt.Errorf("不是 TrueType/OpenType 字体")
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, fmt.Errorf("字体表目录不完整")
+		}
+		tag := string(data[rec : rec+4])
+		off := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if off < 0 || length < 0 || off+length > len(data) {
+			return nil, fmt.Errorf("字体表 %s 越界", tag)
+		}
+		tables[tag] = data[off : off+length]
+	}
+
+	head, hhea, hmtx, cmap := tables["head"], tables["hhea"], tables["hmtx"], tables["cmap"]
+	if len(head) < 20 || len(hhea) < 36 || hmtx == nil || cmap == nil {
+		return nil, fmt.Errorf("字体缺少 head/hhea/hmtx/cmap 表")
+	}
+	face := &sfntFace{unitsPerEm: int(binary.BigEndian.Uint16(head[18:]))}
+	if face.unitsPerEm == 0 {
+		return nil, fmt.Errorf("字体 unitsPerEm 为 0")
+	}
+
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if numMetrics == 0 || numMetrics*4 > len(hmtx) {
+		return nil, fmt.Errorf("字体 hmtx 表不完整")
+	}
+	face.advances = make([]uint16, numMetrics)
+	for i := range face.advances {
+		face.advances[i] = binary.BigEndian.Uint16(hmtx[i*4:])
+	}
+
+	segments, err := parseCmap(cmap)
+	if err != nil {
+		return nil, err
+	}
+	face.segments = segments
+	return face, nil
+}
+
+func parseCmap(cmap []byte) ([]cmapSegment, error) {
+	if len(cmap) < 4 {
+		return nil, fmt.Errorf("字体 cmap 表不完整")
+	}
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	best, bestRank := -1, 0
+	for i := 0; i < n; i++ {
+		rec := 4 + 8*i
+		if rec+8 > len(cmap) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(cmap[rec:])
+		encoding := binary.BigEndian.Uint16(cmap[rec+2:])
+		off := int(binary.BigEndian.Uint32(cmap[rec+4:]))
+		if off+2 > len(cmap) {
+			continue
+		}
+		rank := 0
+		switch {
+		case platform == 3 && encoding == 10, platform == 0 && (encoding == 4 || encoding == 6):
+			rank = 3
+		case platform == 3 && encoding == 1, platform == 0:
+			rank = 2
+		}
+		format := binary.BigEndian.Uint16(cmap[off:])
+		if format != 4 && format != 12 {
+			rank = 0
+		}
+		if rank > bestRank {
+			best, bestRank = off, rank
+		}
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("字体没有可用的 Unicode cmap 子表")
+	}
+	sub := cmap[best:]
+	if binary.BigEndian.Uint16(sub) == 12 {
+		return parseCmap12(sub)
+	}
+	return parseCmap4(sub)
+}
+
+func parseCmap4(sub []byte) ([]cmapSegment, error) {
+	if len(sub) < 14 {
+		return nil, fmt.Errorf("cmap format 4 不完整")
+	}
+	segCount := int(binary.BigEndian.Uint16(sub[6:])) / 2
+	endOff := 14
+	startOff := endOff + 2*segCount + 2
+	deltaOff := startOff + 2*segCount
+	rangeOff := deltaOff + 2*segCount
+	if rangeOff+2*segCount > len(sub) {
+		return nil, fmt.Errorf("cmap format 4 不完整")
+	}
+	out := make([]cmapSegment, 0, segCount)
+	for i := 0; i < segCount; i++ {
+		end := rune(binary.BigEndian.Uint16(sub[endOff+2*i:]))
+		start := rune(binary.BigEndian.Uint16(sub[startOff+2*i:]))
+		delta := int(int16(binary.BigEndian.Uint16(sub[deltaOff+2*i:])))
+		ro := int(binary.BigEndian.Uint16(sub[rangeOff+2*i:]))
+		if start == 0xFFFF || end < start {
+			continue
+		}
+		seg := cmapSegment{start: start, end: end, delta: delta, glyphStart: -1}
+		if ro != 0 {
+			base := rangeOff + 2*i + ro
+			count := int(end-start) + 1
+			if base+2*count > len(sub) {
+				return nil, fmt.Errorf("cmap format 4 字形数组越界")
+			}
+			seg.glyphs = make([]uint16, count)
+			for j := range seg.glyphs {
+				seg.glyphs[j] = binary.BigEndian.Uint16(sub[base+2*j:])
+			}
+		}
+		out = append(out, seg)
+	}
+	return out, nil
+}
+
+func parseCmap12(sub []byte) ([]cmapSegment, error) {
+	if len(sub) < 16 {
+		return nil, fmt.Errorf("cmap format 12 不完整")
+	}
+	groups := int(binary.BigEndian.Uint32(sub[12:]))
+	if 16+12*groups > len(sub) {
+		return nil, fmt.Errorf("cmap format 12 不完整")
+	}
+	out := make([]cmapSegment, 0, groups)
+	for i := 0; i < groups; i++ {
+		rec := 16 + 12*i
+		out = append(out, cmapSegment{
+			start:      rune(binary.BigEndian.Uint32(sub[rec:])),
+			end:        rune(binary.BigEndian.Uint32(sub[rec+4:])),
+			glyphStart: int(binary.BigEndian.Uint32(sub[rec+8:])),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].start < out[j].start })
+	return out, nil
+}
+
+func (f *sfntFace) glyph(r rune) int {
+	i := sort.Search(len(f.segments), func(i int) bool { return f.segments[i].end >= r })
+	if i >= len(f.segments) || f.segments[i].start > r {
+		return 0
+	}
+	seg := f.segments[i]
+	switch {
+	case seg.glyphStart >= 0:
+		return seg.glyphStart + int(r-seg.start)
+	case seg.glyphs != nil:
+		g := int(seg.glyphs[r-seg.start])
+		if g == 0 {
+			return 0
+		}
+		return (g + seg.delta) & 0xFFFF
+	default:
+		return (int(r) + seg.delta) & 0xFFFF
+	}
+}
+
+// advance 返回字宽（单位 em）；字体里没有这个字时返回 false。
+func (f *sfntFace) advance(r rune) (float64, bool) {
+	g := f.glyph(r)
+	if g == 0 {
+		return 0, false
+	}
+	idx := g
+	if idx >= len(f.advances) {
+		idx = len(f.advances) - 1
+	}
+	return float64(f.advances[idx]) / float64(f.unitsPerEm), true
+}
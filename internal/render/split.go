@@ -0,0 +1,194 @@
+package render
+
+import "syl-md2ppt/internal/config"
+
+func splitSlides(cols []Column, widths []float64, cfg *config.Config, m *Metrics, font int) []Slide {
+	caps := make([]int, len(cols))
+	ems := make([]float64, len(cols))
+	cols = append([]Column(nil), cols...)
+	for i, col := range cols {
+		caps[i] = maxLines(cfg, font) * max(1, col.NumCol)
+		ems[i] = columnWidthEm(cfg, font, widths[i], col.NumCol)
+		cols[i].Blocks = sizeBlocks(col.Blocks, cfg, m, font, ems[i])
+	}
+
+	pages := make([][][]Block, len(cols))
+	if ranges, ok := paginateAligned(m, cols, caps, ems); ok {
+		for i, col := range cols {
+			for _, r := range ranges {
+				pages[i] = append(pages[i], col.Blocks[r[0]:r[1]])
+			}
+		}
+	} else {
+		for i, col := range cols {
+			pages[i] = paginate(m, col.Blocks, caps[i], ems[i])
+		}
+	}
+
+	total := 1
+	for _, p := range pages {
+		total = max(total, len(p))
+	}
+	slides := make([]Slide, 0, total)
+	for page := 0; page < total; page++ {
+		pageCols := make([]Column, len(cols))
+		for i, col := range cols {
+			pageCols[i] = col
+			pageCols[i].Blocks = nil
+			if page < len(pages[i]) {
+				pageCols[i].Blocks = pages[i][page]
+			}
+		}
+		slide := newSlide(pageCols, font, false)
+		slide.Page = page + 1
+		slide.PageCount = total
+		slides = append(slides, slide)
+	}
+	return slides
+}
+
+// paginateAligned 在各语言块数一致时按同一块下标切页，保证续页各栏内容对应。
+// 任一块单独超过一页时放弃对齐，返回 false。
+func paginateAligned(m *Metrics, cols []Column, caps []int, ems []float64) ([][2]int, bool) {
+	if len(cols) == 0 {
+		return nil, false
+	}
+	n := len(cols[0].Blocks)
+	for _, col := range cols[1:] {
+		if len(col.Blocks) != n {
+			return nil, false
+		}
+	}
+	pages := make([][2]int, 0)
+	start := 0
+	used := make([]int, len(cols))
+	need := make([]int, len(cols))
+	for b := 0; b < n; b++ {
+		overflow := false
+		for i, col := range cols {
+			need[i] = m.blockLines(col.Blocks[b], ems[i])
+			if need[i] > caps[i] {
+				return nil, false
+			}
+			if used[i]+need[i] > caps[i] {
+				overflow = true
+			}
+		}
+		if b > start && overflow {
+			pages = append(pages, [2]int{start, b})
+			start = b
+			for i := range used {
+				used[i] = 0
+			}
+		}
+		for i := range used {
+			used[i] += need[i]
+		}
+	}
+	if start < n {
+		pages = append(pages, [2]int{start, n})
+	}
+	return pages, true
+}
+
+func paginate(m *Metrics, blocks []Block, capacity int, width float64) [][]Block {
+	pages := make([][]Block, 0)
+	current := make([]Block, 0)
+	used := 0
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		pages = append(pages, current)
+		current = make([]Block, 0)
+		used = 0
+	}
+
+	for _, block := range blocks {
+		need := m.blockLines(block, width)
+		if need == 0 {
+			// 不占行的块（如没量出大小的图片）跟着当前页走，不能丢。
+			current = append(current, block)
+			continue
+		}
+		if used+need <= capacity {
+			current = append(current, block)
+			used += need
+			continue
+		}
+		if need <= capacity {
+			flush()
+			current = append(current, block)
+			used = need
+			continue
+		}
+		// 单块超过一整页时只能在块内切开，每段正好填满一页。
+		flush()
+		for _, piece := range m.splitBlock(block, width, capacity) {
+			need := m.blockLines(piece, width)
+			if used > 0 && used+need > capacity {
+				flush()
+			}
+			current = append(current, piece)
+			used += need
+			if used >= capacity {
+				flush()
+			}
+		}
+	}
+	flush()
+	return pages
+}
+
+func (m *Metrics) splitBlock(block Block, width float64, lines int) []Block {
+	if block.Kind == BlockTable {
+		return splitTable(block, lines)
+	}
+	out := make([]Block, 0)
+	w, scale := blockGeometry(block, width)
+	if lines = int(float64(lines) / scale); lines < 1 {
+		lines = 1
+	}
+	rest := block.Runs
+	for len(rest) > 0 {
+		_, n := m.clipToLines(rest, w, lines, "")
+		if n == 0 {
+			// 一个字都放不下时至少推进一个字，避免死循环。
+			n = 1
+		}
+		n = backToWordBoundary(rest, n)
+		piece := block
+		piece.Runs = clipRunText(rest, n)
+		out = append(out, piece)
+		rest = dropRunText(rest, n)
+	}
+	return out
+}
+
+// backToWordBoundary 尽量在空格处切开，避免把英文单词拆成两半。
+func backToWordBoundary(runs []Run, n int) int {
+	runes := []rune(flattenRuns(runs))
+	if n >= len(runes) {
+		return n
+	}
+	for i := n; i > n/2; i-- {
+		if runes[i-1] == ' ' {
+			return i
+		}
+	}
+	return n
+}
+
+func dropRunText(runs []Run, n int) []Run {
+	out := make([]Run, 0, len(runs))
+	for _, run := range runs {
+		runes := []rune(run.Text)
+		if n >= len(runes) {
+			n -= len(runes)
+			continue
+		}
+		out = append(out, run.withText(string(runes[n:])))
+		n = 0
+	}
+	return out
+}
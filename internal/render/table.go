@@ -0,0 +1,176 @@
+package render
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+var tableDelimCellRe = regexp.MustCompile(`^:?-+:?$`)
+
+// 表格单元格默认内边距：左右各 0.1 英寸，上下各 0.05 英寸。
+const (
+	cellInsetXPt = 7.2
+	cellInsetYPt = 3.6
+)
+
+// parseTable 识别从 lines[0] 开始的管道表格：表头行后面紧跟分隔行。
+// 返回表格、消耗的行数和单元格里的行内格式问题；不是表格时返回 0。firstLine 是 lines[0] 的行号。
+func parseTable(lines []string, firstLine int, opts ParseOptions) (*Table, int, []string) {
+	if len(lines) < 2 || !strings.Contains(lines[0], "|") {
+		return nil, 0, nil
+	}
+	header := splitTableRow(lines[0])
+	delim := splitTableRow(lines[1])
+	if len(delim) != len(header) {
+		return nil, 0, nil
+	}
+	align := make([]string, len(delim))
+	for i, cell := range delim {
+		if !tableDelimCellRe.MatchString(cell) {
+			return nil, 0, nil
+		}
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			align[i] = "center"
+		case right:
+			align[i] = "right"
+		case left:
+			align[i] = "left"
+		}
+	}
+
+	tbl := &Table{Align: align}
+	row, rowIssues := tableRow(header, len(header), opts, true)
+	tbl.Rows = append(tbl.Rows, row)
+	issues := atLine(firstLine, rowIssues)
+	n := 2
+	for ; n < len(lines); n++ {
+		line := strings.TrimSpace(lines[n])
+		if line == "" || !strings.Contains(line, "|") {
+			break
+		}
+		row, rowIssues := tableRow(splitTableRow(line), len(header), opts, false)
+		tbl.Rows = append(tbl.Rows, row)
+		issues = append(issues, atLine(firstLine+n, rowIssues)...)
+	}
+	return tbl, n, issues
+}
+
+// tableRow 把单元格补齐或截断到表头的列数；表头文字加粗。
+func tableRow(cells []string, n int, opts ParseOptions, header bool) (TableRow, []string) {
+	row := TableRow{Cells: make([][]Run, n)}
+	issues := make([]string, 0)
+	for i := 0; i < n && i < len(cells); i++ {
+		runs, cellIssues := parseInline(cells[i], opts.FormulaDelimiter)
+		issues = append(issues, cellIssues...)
+		if len(runs) == 0 && cells[i] != "" {
+			runs = []Run{{Text: cells[i]}}
+		}
+		if header {
+			for k := range runs {
+				runs[k].Bold = true
+			}
+		}
+		row.Cells[i] = runs
+	}
+	return row, issues
+}
+
+// splitTableRow 按未转义的 | 切分单元格，去掉首尾的竖线。
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	cells := make([]string, 0)
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+func hasTable(blocks []Block) bool {
+	for _, block := range blocks {
+		if block.Kind == BlockTable {
+			return true
+		}
+	}
+	return false
+}
+
+// measureTable 按列数平分宽度，按单元格折行估算每行高度。
+func (m *Metrics) measureTable(tbl Table, availPt float64, font int, lineH float64) Table {
+	rows := make([]TableRow, len(tbl.Rows))
+	cols := 1
+	if len(tbl.Rows) > 0 {
+		cols = max(1, len(tbl.Rows[0].Cells))
+	}
+	cellEm := (availPt/float64(cols) - 2*cellInsetXPt) / float64(font)
+	if cellEm < 1 {
+		cellEm = 1
+	}
+	tbl.DisplayW = math.Max(availPt, 1)
+	tbl.DisplayH = 0
+	for i, row := range tbl.Rows {
+		lines := 1
+		for _, cell := range row.Cells {
+			lines = max(lines, m.lineCount(cell, cellEm))
+		}
+		row.HeightPt = float64(lines)*lineH + 2*cellInsetYPt
+		row.LineCount = row.HeightPt / lineH
+		rows[i] = row
+		tbl.DisplayH += row.HeightPt
+	}
+	tbl.Rows = rows
+	return tbl
+}
+
+// splitTable 按行把表格切成每段不超过 lines 行的几段，每段都重复表头。
+// 单独一行就超过 lines 时，这一行自成一段。
+func splitTable(block Block, lines int) []Block {
+	tbl := block.Table
+	if tbl == nil || len(tbl.Rows) < 2 {
+		return []Block{block}
+	}
+	header := tbl.Rows[0]
+	out := make([]Block, 0)
+	piece := []TableRow{header}
+	used := header.LineCount
+	flush := func() {
+		part := *tbl
+		part.Rows = piece
+		part.DisplayH = 0
+		for _, row := range piece {
+			part.DisplayH += row.HeightPt
+		}
+		part.Lines = int(math.Ceil(used - 1e-9))
+		part.ReservePt = part.DisplayH / used * float64(part.Lines)
+		b := block
+		b.Table = &part
+		out = append(out, b)
+	}
+	for _, row := range tbl.Rows[1:] {
+		if len(piece) > 1 && used+row.LineCount > float64(lines)+1e-9 {
+			flush()
+			piece = []TableRow{header}
+			used = header.LineCount
+		}
+		piece = append(piece, row)
+		used += row.LineCount
+	}
+	flush()
+	return out
+}
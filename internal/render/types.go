@@ -10,15 +10,80 @@ const (
 )
 
 type Run struct {
-	Text    string
-	Bold    bool
-	Italic  bool
-	Formula bool
+	Text   string
+	Bold   bool
+	Italic bool
+	// Strike 是 ~~删除线~~，Underline 是 ++下划线++。
+	Strike    bool
+	Underline bool
+	Formula   bool
+	// Code 表示行内代码；Link 是超链接地址，Text 只保留可见文字。
+	Code bool
+	Link string
+	// Math 是公式转好的 OMML，rPr 处是 omml.RunProps 占位；转不成时为空，MathErr 是原因。
+	// 公式被截断、拆页后两者都为空，按文本显示。
+	Math    string
+	MathErr string
 }
 
+type BlockKind int
+
+const (
+	BlockParagraph BlockKind = iota
+	BlockHeading
+	BlockBullet
+	BlockNumbered
+	BlockImage
+	BlockTable
+)
+
 type Block struct {
 	Marker MarkerType
+	Kind   BlockKind
+	// Level 是标题级别（1-6）；Depth 是列表嵌套深度，从 0 开始。
+	Level int
+	Depth int
+	// Number 是有序列表项的序号。
+	Number int
 	Runs   []Run
+	// Image 只在 BlockImage 时有值，Table 只在 BlockTable 时有值。
+	Image *Image
+	Table *Table
+}
+
+// Frame 是浮在文本框上的对象（图片、表格）的排版结果：占用的行数、
+// 显示大小（磅）和相对文本框左上角的位置（英寸）。
+type Frame struct {
+	Lines     int
+	ReservePt float64
+	DisplayW  float64
+	DisplayH  float64
+	LeftIn    float64
+	TopIn     float64
+}
+
+// Image 是卡片里的一张图片；WidthPt/HeightPt 是原始尺寸。
+type Image struct {
+	Path     string
+	Alt      string
+	Format   string // png、jpeg 或 svg
+	WidthPt  float64
+	HeightPt float64
+	Frame
+}
+
+// Table 是一张管道表格，第一行是表头。
+type Table struct {
+	Align []string // 各列对齐：left、center、right，空为默认
+	Rows  []TableRow
+	Frame
+}
+
+type TableRow struct {
+	Cells [][]Run
+	// HeightPt 是估算的行高，LineCount 是折算成正文行数的高度。
+	HeightPt  float64
+	LineCount float64
 }
 
 type ParseOptions struct {
@@ -26,22 +91,39 @@ type ParseOptions struct {
 	StarPrefix       string
 	DotPrefix        string
 	WarnPrefix       string
+	// BaseDir 是卡片文件所在目录，图片的相对路径以它为准。
+	BaseDir string
 }
 
-type Warning struct {
-	Code    string
-	Message string
+// Source 是某一语言的卡片原文。
+type Source struct {
+	Lang string
+	Tag  string
+	Raw  string
+	// Path 是卡片文件路径，用来定位图片。
+	Path string
 }
 
 type Column struct {
 	Lang   string
+	Tag    string
+	Ratio  float64
+	NumCol int
+	Blocks []Block
+}
+
+// Note 是某一语言的演讲者备注。
+type Note struct {
+	Lang   string
+	Tag    string
 	Blocks []Block
 }
 
 type Slide struct {
 	FontSize           int
 	Columns            []Column
-	ENNumCol           int
-	CNNumCol           int
 	HasTruncationBadge bool
+	Page               int
+	PageCount          int
+	Notes              []Note
 }
@@ -0,0 +1,96 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 行内代码用等宽字体，按常见等宽字体的字宽估算。
+const monospaceEm = 0.6
+
+// lineCount 按实际字宽折行：拉丁文按单词换行，中日韩文字按字换行。
+func (m *Metrics) lineCount(runs []Run, widthEm float64) int {
+	lines := 0
+	lineW := 0.0
+	wordW := 0.0
+	started := false
+
+	placeWord := func() {
+		if wordW == 0 {
+			return
+		}
+		if !started {
+			lines, started = 1, true
+		}
+		if lineW+wordW <= widthEm {
+			lineW += wordW
+			wordW = 0
+			return
+		}
+		if lineW > 0 {
+			lines++
+			lineW = 0
+		}
+		// 单词比整行还宽时只能硬断。
+		for wordW > widthEm {
+			lines++
+			wordW -= widthEm
+		}
+		lineW = wordW
+		wordW = 0
+	}
+
+	for _, run := range runs {
+		for _, r := range run.Text {
+			w := m.runeWidth(r, run.Bold, run.Italic)
+			if run.Code && !isWideRune(r) {
+				w = monospaceEm
+			}
+			switch {
+			case unicode.IsSpace(r):
+				placeWord()
+				if !started {
+					continue
+				}
+				// 行尾空格悬挂，不触发换行。
+				if lineW+w <= widthEm {
+					lineW += w
+				}
+			case isWideRune(r):
+				placeWord()
+				wordW = w
+				placeWord()
+			default:
+				wordW += w
+			}
+		}
+	}
+	placeWord()
+	return lines
+}
+
+// clipToLines 返回能放进 lines 行的最长前缀（可附加后缀）及其字数。
+func (m *Metrics) clipToLines(runs []Run, widthEm float64, lines int, suffix string) ([]Run, int) {
+	total := 0
+	for _, r := range runs {
+		total += len([]rune(r.Text))
+	}
+	fitsN := func(n int) bool {
+		clipped := clipRunText(runs, n)
+		if suffix != "" && len(clipped) > 0 {
+			last := clipped[len(clipped)-1]
+			clipped[len(clipped)-1] = last.withText(strings.TrimSpace(last.Text) + suffix)
+		}
+		return m.lineCount(clipped, widthEm) <= lines
+	}
+	lo, hi := 0, total
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if fitsN(mid) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return clipRunText(runs, lo), lo
+}
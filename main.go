@@ -12,6 +12,6 @@ func main() {
 		if msg := cmd.FriendlyError(err); msg != "" {
 			fmt.Fprintln(os.Stderr, msg)
 		}
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }